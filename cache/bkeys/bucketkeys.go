@@ -2,7 +2,53 @@
 package bkeys
 
 const (
-	Products       = "products.id"
-	ProductHandles = "products.handle"
-	ProductTitles  = "products.title"
+	Meta                    = "meta"
+	Products                = "products.id"
+	ProductHandles          = "products.handle"
+	ProductTitles           = "products.title"
+	ProductsMeta            = "products.meta" // Before schema version 2.
+	ImageAlts               = "products.images.alt"
+	Variants                = "variants.id"
+	VariantSKUs             = "variants.sku"
+	VariantBarcodes         = "variants.barcode"
+	VariantInventoryItems   = "variants.inventoryItem"
+	Collections             = "collections.id"
+	CollectionHandles       = "collections.handle"
+	CollectionTitles        = "collections.title"
+	Locations               = "locations.id"
+	LocationNames           = "locations.name"
+	MetafieldDefinitions    = "metafieldDefinitions.id"
+	MetafieldDefinitionKeys = "metafieldDefinitions.key"
+	InventoryLevels         = "inventory.levels"
+	InventoryItems          = "inventory.items"
+	Push                    = "push"
+	PushJournal             = "push.journal"
+	PushHistory             = "push.history"
+)
+
+// Keys in the Meta bucket.
+const (
+	// SchemaVersion is the schema version of the cache.
+	SchemaVersion = "schemaVersion"
+	// Store is the name of the store of the cache.
+	Store = "store"
+	// ClonedAt is the start time of the last successful clone. It was kept in
+	// the ProductsMeta bucket before schema version 2.
+	ClonedAt = "clonedAt"
+)
+
+// Keys in the Push bucket and in the buckets of the PushHistory bucket.
+const (
+	// PushID is the ID of the push.
+	PushID = "id"
+	// PushPlan is the plan of the push.
+	PushPlan = "plan"
+	// PushSnapshot is the state of the products before the push.
+	PushSnapshot = "snapshot"
+	// PushUpdatedAt is the update time of the products after the push. It is
+	// only set in the buckets of the PushHistory bucket.
+	PushUpdatedAt = "updatedAt"
+	// PushJournalKey is the nested bucket of the journal in the buckets of the
+	// PushHistory bucket.
+	PushJournalKey = "journal"
 )
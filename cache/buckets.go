@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// recreateBucket deletes the bucket with the given name if it exists and
+// creates it again empty.
+func recreateBucket(tx *bolt.Tx, name string) (*bolt.Bucket, error) {
+	err := tx.DeleteBucket([]byte(name))
+	if err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+		return nil, err
+	}
+	return tx.CreateBucket([]byte(name))
+}
+
+// getJSON decodes the value of the key in bucket into v. ErrNotExist is
+// returned if the bucket or the key doesn't exist.
+func getJSON(bucket *bolt.Bucket, k []byte, v any) error {
+	if bucket == nil {
+		return ErrNotExist
+	}
+	b := bucket.Get(k)
+	if b == nil {
+		return ErrNotExist
+	}
+	return json.Unmarshal(b, v)
+}
+
+// getBySecondaryKey decodes the value in the primary bucket whose key is the
+// value of k in the secondary bucket into v.
+func getBySecondaryKey(primary *bolt.Bucket, secondary *bolt.Bucket, k []byte, v any) error {
+	if secondary == nil {
+		return ErrNotExist
+	}
+	primaryKey := secondary.Get(k)
+	if primaryKey == nil {
+		return ErrNotExist
+	}
+	return getJSON(primary, primaryKey, v)
+}
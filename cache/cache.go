@@ -3,6 +3,7 @@ package cache
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -20,41 +21,87 @@ var (
 )
 
 type Cache interface {
+	Meta() MetaCache
 	Products() ProductCache
+	Collections() CollectionCache
+	Locations() LocationCache
+	MetafieldDefinitions() MetafieldDefinitionCache
+	Journal() JournalCache
+	Stock() StockCache
 }
 
-// New returns a new cache.
-func New() (Cache, error) {
-	dbOpener, err := newDBOpener()
+// New returns the cache of the store with the given name. Every store has its
+// own cache so that the data of different stores never mixes.
+func New(store string) (Cache, error) {
+	dbOpener, err := newDBOpener(store)
 	if err != nil {
 		return nil, err
 	}
+	if err := adoptLegacyDB(os.Stderr, dbOpener.path); err != nil {
+		return nil, err
+	}
 	cache := &cache{
-		products: NewProductCache(dbOpener),
+		meta:        NewMetaCache(dbOpener),
+		products:    NewProductCache(dbOpener),
+		collections: NewCollectionCache(dbOpener),
+		locations:   NewLocationCache(dbOpener),
+		definitions: NewMetafieldDefinitionCache(dbOpener),
+		journal:     NewJournalCache(dbOpener),
+		stock:       NewStockCache(dbOpener),
 	}
 	return cache, nil
 }
 
 type cache struct {
-	products ProductCache
+	meta        MetaCache
+	products    ProductCache
+	collections CollectionCache
+	locations   LocationCache
+	definitions MetafieldDefinitionCache
+	journal     JournalCache
+	stock       StockCache
+}
+
+func (c *cache) Meta() MetaCache {
+	return c.meta
 }
 
 func (c *cache) Products() ProductCache {
 	return c.products
 }
 
-// Clear removes the cache directory.
-func Clear() error {
-	dir, err := directory()
+func (c *cache) Collections() CollectionCache {
+	return c.collections
+}
+
+func (c *cache) Locations() LocationCache {
+	return c.locations
+}
+
+func (c *cache) MetafieldDefinitions() MetafieldDefinitionCache {
+	return c.definitions
+}
+
+func (c *cache) Journal() JournalCache {
+	return c.journal
+}
+
+func (c *cache) Stock() StockCache {
+	return c.stock
+}
+
+// Clear removes the cache directory of the given store.
+func Clear(store string) error {
+	dir, err := directory(store)
 	if err != nil {
 		return err
 	}
 	return os.RemoveAll(dir)
 }
 
-// Size returns the size of the cache database in bytes.
-func Size() (int64, error) {
-	dir, err := directory()
+// Size returns the size of the cache database of the given store in bytes.
+func Size(store string) (int64, error) {
+	dir, err := directory(store)
 	if err != nil {
 		return 0, err
 	}
@@ -66,12 +113,15 @@ func Size() (int64, error) {
 	return stat.Size(), nil
 }
 
-// directory returns the path to the cache directory. If the directory does not
-// exist, then directory will create it.
-func directory() (string, error) {
+// directory returns the path to the cache directory of the given store. If the
+// directory does not exist, then directory will create it.
+func directory(store string) (string, error) {
+	if store == "" || store != filepath.Base(store) || store == "." || store == ".." {
+		return "", fmt.Errorf("invalid store name %q", store)
+	}
 	cacheRootDir, err := os.UserCacheDir()
 	if err != nil {
 		return "", err
 	}
-	return osutil.MakeUserDir(cacheRootDir, AppName)
+	return osutil.MakeUserDir(cacheRootDir, AppName, store)
 }
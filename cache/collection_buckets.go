@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/samherrmann/merchant/cache/bkeys"
+	bolt "go.etcd.io/bbolt"
+)
+
+func NewCollectionBuckets(tx *bolt.Tx) (*CollectionBuckets, error) {
+	return &CollectionBuckets{
+		tx:          tx,
+		collections: tx.Bucket([]byte(bkeys.Collections)),
+		handles:     tx.Bucket([]byte(bkeys.CollectionHandles)),
+		titles:      tx.Bucket([]byte(bkeys.CollectionTitles)),
+	}, nil
+}
+
+// CollectionBuckets is a collection of Bolt Buckets to store the custom and
+// smart collections of a store, indexed by handle and title.
+type CollectionBuckets struct {
+	tx          *bolt.Tx
+	collections *bolt.Bucket
+	handles     *bolt.Bucket
+	titles      *bolt.Bucket
+}
+
+// Set replaces all collections with the given collections.
+func (b *CollectionBuckets) Set(collections ...goshopify.Collection) error {
+	var err error
+	if b.collections, err = recreateBucket(b.tx, bkeys.Collections); err != nil {
+		return err
+	}
+	if b.handles, err = recreateBucket(b.tx, bkeys.CollectionHandles); err != nil {
+		return err
+	}
+	if b.titles, err = recreateBucket(b.tx, bkeys.CollectionTitles); err != nil {
+		return err
+	}
+	for _, c := range collections {
+		k := int64ToBytes(c.ID)
+		if c.Handle != "" {
+			if err := setOnce(b.handles, []byte(c.Handle), k); err != nil {
+				return fmt.Errorf("collection handle %q: %w", c.Handle, err)
+			}
+		}
+		// Titles are not unique, so a title is indexed to the last of its
+		// collections.
+		if c.Title != "" {
+			if err := b.titles.Put([]byte(c.Title), k); err != nil {
+				return err
+			}
+		}
+		v, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		if err := b.collections.Put(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *CollectionBuckets) GetByID(id int64) (*goshopify.Collection, error) {
+	c := &goshopify.Collection{}
+	if err := getJSON(b.collections, int64ToBytes(id), c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (b *CollectionBuckets) GetByHandle(handle string) (*goshopify.Collection, error) {
+	c := &goshopify.Collection{}
+	if err := getBySecondaryKey(b.collections, b.handles, []byte(handle), c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetByTitle returns a collection with the given title. If several collections
+// have the title, then the one that was set last is returned.
+func (b *CollectionBuckets) GetByTitle(title string) (*goshopify.Collection, error) {
+	c := &goshopify.Collection{}
+	if err := getBySecondaryKey(b.collections, b.titles, []byte(title), c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// List returns all collections ordered by ID.
+func (b *CollectionBuckets) List() ([]goshopify.Collection, error) {
+	collections := []goshopify.Collection{}
+	if b.collections == nil {
+		return collections, nil
+	}
+	err := b.collections.ForEach(func(k, v []byte) error {
+		c := goshopify.Collection{}
+		if err := json.Unmarshal(v, &c); err != nil {
+			return err
+		}
+		collections = append(collections, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(collections, func(i, j int) bool {
+		return collections[i].ID < collections[j].ID
+	})
+	return collections, nil
+}
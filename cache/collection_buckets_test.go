@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestCollectionBuckets(t *testing.T) {
+	db := newTestDB(t)
+	db.Update(func(tx *bolt.Tx) error {
+		buckets, err := NewCollectionBuckets(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := buckets.Set(
+			goshopify.Collection{ID: 12, Handle: "summer", Title: "Summer"},
+			goshopify.Collection{ID: 11, Handle: "sale", Title: "Sale"},
+			// Titles are not unique.
+			goshopify.Collection{ID: 13, Handle: "sale-2", Title: "Sale"},
+		); err != nil {
+			t.Fatal(err)
+		}
+		return nil
+	})
+	db.View(func(tx *bolt.Tx) error {
+		buckets, err := NewCollectionBuckets(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, err := buckets.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 3 || list[0].ID != 11 || list[1].ID != 12 || list[2].ID != 13 {
+			t.Fatalf("got collections %+v, want IDs 11, 12 and 13", list)
+		}
+		c, err := buckets.GetByHandle("summer")
+		if err != nil || c.ID != 12 {
+			t.Fatalf("got collection %+v and error %v by handle", c, err)
+		}
+		c, err = buckets.GetByTitle("Sale")
+		if err != nil || c.ID != 13 {
+			t.Fatalf("got collection %+v and error %v by title", c, err)
+		}
+		c, err = buckets.GetByID(12)
+		if err != nil || c.Title != "Summer" {
+			t.Fatalf("got collection %+v and error %v by ID", c, err)
+		}
+		if _, err := buckets.GetByHandle("winter"); !errors.Is(err, ErrNotExist) {
+			t.Fatalf("got error %v, want %v", err, ErrNotExist)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	bolt "go.etcd.io/bbolt"
+)
+
+// CollectionCache stores the custom and smart collections of a store.
+type CollectionCache interface {
+	Set(collections ...goshopify.Collection) error
+	GetByID(id int64) (*goshopify.Collection, error)
+	GetByHandle(handle string) (*goshopify.Collection, error)
+	GetByTitle(title string) (*goshopify.Collection, error)
+	List() ([]goshopify.Collection, error)
+}
+
+func NewCollectionCache(o DBOpener) CollectionCache {
+	return &collectionCache{dbOpener: o}
+}
+
+type collectionCache struct {
+	dbOpener DBOpener
+}
+
+func (cache *collectionCache) Set(collections ...goshopify.Collection) error {
+	return cache.update(func(b *CollectionBuckets) error {
+		return b.Set(collections...)
+	})
+}
+
+func (cache *collectionCache) GetByID(id int64) (c *goshopify.Collection, err error) {
+	err = cache.view(func(b *CollectionBuckets) error {
+		c, err = b.GetByID(id)
+		return err
+	})
+	return c, err
+}
+
+func (cache *collectionCache) GetByHandle(handle string) (c *goshopify.Collection, err error) {
+	err = cache.view(func(b *CollectionBuckets) error {
+		c, err = b.GetByHandle(handle)
+		return err
+	})
+	return c, err
+}
+
+func (cache *collectionCache) GetByTitle(title string) (c *goshopify.Collection, err error) {
+	err = cache.view(func(b *CollectionBuckets) error {
+		c, err = b.GetByTitle(title)
+		return err
+	})
+	return c, err
+}
+
+func (cache *collectionCache) List() (collections []goshopify.Collection, err error) {
+	err = cache.view(func(b *CollectionBuckets) error {
+		collections, err = b.List()
+		return err
+	})
+	return collections, err
+}
+
+func (cache *collectionCache) view(fn func(b *CollectionBuckets) error) error {
+	db, err := cache.dbOpener.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(func(tx *bolt.Tx) error {
+		buckets, err := NewCollectionBuckets(tx)
+		if err != nil {
+			return err
+		}
+		return fn(buckets)
+	})
+}
+
+func (cache *collectionCache) update(fn func(b *CollectionBuckets) error) error {
+	db, err := cache.dbOpener.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(func(tx *bolt.Tx) error {
+		buckets, err := NewCollectionBuckets(tx)
+		if err != nil {
+			return err
+		}
+		return fn(buckets)
+	})
+}
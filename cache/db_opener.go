@@ -10,20 +10,32 @@ type DBOpener interface {
 	Open() (*bolt.DB, error)
 }
 
-func newDBOpener() (*dbOpener, error) {
-	dir, err := directory()
+func newDBOpener(store string) (*dbOpener, error) {
+	dir, err := directory(store)
 	if err != nil {
 		return nil, err
 	}
 	return &dbOpener{
-		path: filepath.Join(dir, dbFilename),
+		store: store,
+		path:  filepath.Join(dir, dbFilename),
 	}, nil
 }
 
 type dbOpener struct {
-	path string
+	store string
+	path  string
 }
 
+// Open opens the database and migrates it to SchemaVersion if it has been
+// written by an older version of the application.
 func (p *dbOpener) Open() (*bolt.DB, error) {
-	return bolt.Open(p.path, 0600, nil)
+	db, err := bolt.Open(p.path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrate(db, p.store); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
 }
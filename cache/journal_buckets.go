@@ -0,0 +1,239 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/samherrmann/merchant/cache/bkeys"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Push is a push together with the record of its applied operations.
+type Push struct {
+	// ID identifies the push.
+	ID string
+	// Plan is the encoded operations of the push.
+	Plan []byte
+	// Snapshot is the encoded state of the affected products before the push.
+	Snapshot []byte
+	// UpdatedAt is the encoded update time of the affected products after the
+	// push, or nil if the push has not ended or was ended without it.
+	UpdatedAt []byte
+	// Completed maps the keys of the applied operations to the ID of the
+	// resource that they created, or zero if they didn't create a resource.
+	Completed map[string]int64
+}
+
+func NewJournalBuckets(tx *bolt.Tx) (*JournalBuckets, error) {
+	return &JournalBuckets{
+		tx:      tx,
+		push:    tx.Bucket([]byte(bkeys.Push)),
+		journal: tx.Bucket([]byte(bkeys.PushJournal)),
+		history: tx.Bucket([]byte(bkeys.PushHistory)),
+	}, nil
+}
+
+// JournalBuckets is a collection of Bolt Buckets to keep track of the
+// operations of a push that have been applied to the store. Once a push has
+// ended, it is kept in the history.
+type JournalBuckets struct {
+	tx      *bolt.Tx
+	push    *bolt.Bucket
+	journal *bolt.Bucket
+	history *bolt.Bucket
+}
+
+// Begin starts a new journal for a push. Begin returns ErrExist if a push has
+// already been started and not ended since, or if a push with the same ID
+// exists in the history.
+func (b *JournalBuckets) Begin(id string, plan []byte, snapshot []byte) error {
+	if _, err := b.Current(); err == nil {
+		return ErrExist
+	}
+	if b.history != nil && b.history.Bucket([]byte(id)) != nil {
+		return fmt.Errorf("push %v: %w", id, ErrExist)
+	}
+	var err error
+	b.push, err = b.tx.CreateBucketIfNotExists([]byte(bkeys.Push))
+	if err != nil {
+		return err
+	}
+	return putPush(b.push, id, plan, snapshot)
+}
+
+// Current returns the push that has been started but not ended. ErrNotExist
+// is returned if no such push exists.
+func (b *JournalBuckets) Current() (*Push, error) {
+	if b.push == nil || b.push.Get([]byte(bkeys.PushID)) == nil {
+		return nil, ErrNotExist
+	}
+	p := getPush(b.push)
+	completed, err := b.Completed()
+	if err != nil {
+		return nil, err
+	}
+	p.Completed = completed
+	return p, nil
+}
+
+// Record records that the operations with the keys of completed have been
+// applied. The keys are mapped to the ID of the resource that the operation
+// created, or zero.
+func (b *JournalBuckets) Record(completed map[string]int64) error {
+	var err error
+	b.journal, err = b.tx.CreateBucketIfNotExists([]byte(bkeys.PushJournal))
+	if err != nil {
+		return err
+	}
+	for key, id := range completed {
+		if err := b.journal.Put([]byte(key), int64ToBytes(id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Completed returns the keys of all recorded operations mapped to the ID of
+// the resource that they created.
+func (b *JournalBuckets) Completed() (map[string]int64, error) {
+	return readJournal(b.journal)
+}
+
+// End moves the current push together with its journal into the history.
+// updatedAt is the encoded update time of the affected products after the
+// push, and may be nil if it is unknown. Ending is a no-op if no push has been
+// started.
+func (b *JournalBuckets) End(updatedAt []byte) error {
+	p, err := b.Current()
+	if errors.Is(err, ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	b.history, err = b.tx.CreateBucketIfNotExists([]byte(bkeys.PushHistory))
+	if err != nil {
+		return err
+	}
+	bucket, err := b.history.CreateBucket([]byte(p.ID))
+	if err != nil {
+		return err
+	}
+	if err := putPush(bucket, p.ID, p.Plan, p.Snapshot); err != nil {
+		return err
+	}
+	if updatedAt != nil {
+		if err := bucket.Put([]byte(bkeys.PushUpdatedAt), updatedAt); err != nil {
+			return err
+		}
+	}
+	journal, err := bucket.CreateBucket([]byte(bkeys.PushJournalKey))
+	if err != nil {
+		return err
+	}
+	for k, id := range p.Completed {
+		if err := journal.Put([]byte(k), int64ToBytes(id)); err != nil {
+			return err
+		}
+	}
+	for _, k := range []string{bkeys.Push, bkeys.PushJournal} {
+		err := b.tx.DeleteBucket([]byte(k))
+		if err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return err
+		}
+	}
+	b.push = nil
+	b.journal = nil
+	return nil
+}
+
+// History returns the IDs of all ended pushes in ascending order.
+func (b *JournalBuckets) History() ([]string, error) {
+	ids := []string{}
+	if b.history == nil {
+		return ids, nil
+	}
+	err := b.history.ForEach(func(k, v []byte) error {
+		ids = append(ids, string(k))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Get returns the ended push with the given ID. ErrNotExist is returned if
+// the push is not in the history.
+func (b *JournalBuckets) Get(id string) (*Push, error) {
+	if b.history == nil {
+		return nil, ErrNotExist
+	}
+	bucket := b.history.Bucket([]byte(id))
+	if bucket == nil {
+		return nil, ErrNotExist
+	}
+	p := getPush(bucket)
+	completed, err := readJournal(bucket.Bucket([]byte(bkeys.PushJournalKey)))
+	if err != nil {
+		return nil, err
+	}
+	p.Completed = completed
+	return p, nil
+}
+
+// putPush writes the given push properties into bucket.
+func putPush(bucket *bolt.Bucket, id string, plan []byte, snapshot []byte) error {
+	values := map[string][]byte{
+		bkeys.PushID:       []byte(id),
+		bkeys.PushPlan:     plan,
+		bkeys.PushSnapshot: snapshot,
+	}
+	for k, v := range values {
+		if err := bucket.Put([]byte(k), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getPush reads the push properties written by putPush from bucket.
+func getPush(bucket *bolt.Bucket) *Push {
+	return &Push{
+		ID:        string(bucket.Get([]byte(bkeys.PushID))),
+		Plan:      copyBytes(bucket.Get([]byte(bkeys.PushPlan))),
+		Snapshot:  copyBytes(bucket.Get([]byte(bkeys.PushSnapshot))),
+		UpdatedAt: copyBytes(bucket.Get([]byte(bkeys.PushUpdatedAt))),
+	}
+}
+
+// readJournal returns the content of a journal bucket. The bucket may be nil.
+func readJournal(bucket *bolt.Bucket) (map[string]int64, error) {
+	completed := map[string]int64{}
+	if bucket == nil {
+		return completed, nil
+	}
+	err := bucket.ForEach(func(k, v []byte) error {
+		id, err := bytesToInt64(v)
+		if err != nil {
+			return err
+		}
+		completed[string(k)] = id
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return completed, nil
+}
+
+// copyBytes returns a copy of b. Values returned by Bolt are only valid for
+// the life of the transaction.
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
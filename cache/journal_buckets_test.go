@@ -0,0 +1,250 @@
+package cache
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestJournalBuckets_Begin(t *testing.T) {
+	t.Run("starts push", func(t *testing.T) {
+		db := newTestDB(t)
+		db.Update(func(tx *bolt.Tx) error {
+			buckets, err := NewJournalBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := buckets.Begin("1", []byte("plan"), []byte("snapshot")); err != nil {
+				t.Fatal(err)
+			}
+			return nil
+		})
+		db.View(func(tx *bolt.Tx) error {
+			buckets, err := NewJournalBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := buckets.Current()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := &Push{
+				ID:        "1",
+				Plan:      []byte("plan"),
+				Snapshot:  []byte("snapshot"),
+				Completed: map[string]int64{},
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %+v, want %+v", got, want)
+			}
+			return nil
+		})
+	})
+
+	t.Run("returns ErrExist if push has not ended", func(t *testing.T) {
+		db := newTestDB(t)
+		db.Update(func(tx *bolt.Tx) error {
+			buckets, err := NewJournalBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := buckets.Begin("1", nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			want := ErrExist
+			got := buckets.Begin("2", nil, nil)
+			if !errors.Is(got, want) {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+			return nil
+		})
+	})
+
+	t.Run("returns ErrExist if push is in history", func(t *testing.T) {
+		db := newTestDB(t)
+		db.Update(func(tx *bolt.Tx) error {
+			buckets, err := NewJournalBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := buckets.Begin("1", nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := buckets.End(nil); err != nil {
+				t.Fatal(err)
+			}
+			want := ErrExist
+			got := buckets.Begin("1", nil, nil)
+			if !errors.Is(got, want) {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+			return nil
+		})
+	})
+}
+
+func TestJournalBuckets_Current(t *testing.T) {
+	t.Run("returns ErrNotExist if no push has been started", func(t *testing.T) {
+		db := newTestDB(t)
+		db.View(func(tx *bolt.Tx) error {
+			buckets, err := NewJournalBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := ErrNotExist
+			_, got := buckets.Current()
+			if !errors.Is(got, want) {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+			return nil
+		})
+	})
+}
+
+func TestJournalBuckets_Completed(t *testing.T) {
+	t.Run("returns empty map if nothing is recorded", func(t *testing.T) {
+		db := newTestDB(t)
+		db.View(func(tx *bolt.Tx) error {
+			buckets, err := NewJournalBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := buckets.Completed()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 0 {
+				t.Fatalf("got %v, want empty map", got)
+			}
+			return nil
+		})
+	})
+
+	t.Run("returns recorded keys", func(t *testing.T) {
+		db := newTestDB(t)
+		db.Update(func(tx *bolt.Tx) error {
+			buckets, err := NewJournalBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := buckets.Record(map[string]int64{"a": 0}); err != nil {
+				t.Fatal(err)
+			}
+			if err := buckets.Record(map[string]int64{"b": 123}); err != nil {
+				t.Fatal(err)
+			}
+			return nil
+		})
+		db.View(func(tx *bolt.Tx) error {
+			buckets, err := NewJournalBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := buckets.Completed()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]int64{"a": 0, "b": 123}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+			return nil
+		})
+	})
+}
+
+func TestJournalBuckets_End(t *testing.T) {
+	t.Run("moves push into history", func(t *testing.T) {
+		db := newTestDB(t)
+		db.Update(func(tx *bolt.Tx) error {
+			buckets, err := NewJournalBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := buckets.Begin("1", []byte("plan"), []byte("snapshot")); err != nil {
+				t.Fatal(err)
+			}
+			if err := buckets.Record(map[string]int64{"a": 123}); err != nil {
+				t.Fatal(err)
+			}
+			return buckets.End([]byte("updatedAt"))
+		})
+		db.View(func(tx *bolt.Tx) error {
+			buckets, err := NewJournalBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := buckets.Current(); !errors.Is(err, ErrNotExist) {
+				t.Fatalf("got %v, want %v", err, ErrNotExist)
+			}
+			completed, err := buckets.Completed()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(completed) != 0 {
+				t.Fatalf("got %v, want empty map", completed)
+			}
+			got, err := buckets.Get("1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := &Push{
+				ID:        "1",
+				Plan:      []byte("plan"),
+				Snapshot:  []byte("snapshot"),
+				UpdatedAt: []byte("updatedAt"),
+				Completed: map[string]int64{"a": 123},
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %+v, want %+v", got, want)
+			}
+			return nil
+		})
+	})
+
+	t.Run("succeeds if no push has been started", func(t *testing.T) {
+		db := newTestDB(t)
+		db.Update(func(tx *bolt.Tx) error {
+			buckets, err := NewJournalBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := buckets.End(nil); err != nil {
+				t.Fatal(err)
+			}
+			return nil
+		})
+	})
+}
+
+func TestJournalBuckets_History(t *testing.T) {
+	db := newTestDB(t)
+	db.Update(func(tx *bolt.Tx) error {
+		buckets, err := NewJournalBuckets(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []string{"2", "1"} {
+			if err := buckets.Begin(id, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := buckets.End(nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, err := buckets.History()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"1", "2"}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		if _, err := buckets.Get("3"); !errors.Is(err, ErrNotExist) {
+			t.Fatalf("got %v, want %v", err, ErrNotExist)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	bolt "go.etcd.io/bbolt"
+)
+
+// JournalCache keeps track of the operations of a push that have been applied
+// to the store, so that an interrupted push can be resumed and a completed push
+// can be rolled back.
+type JournalCache interface {
+	Begin(id string, plan []byte, snapshot []byte) error
+	Current() (*Push, error)
+	Record(completed map[string]int64) error
+	Completed() (map[string]int64, error)
+	End(updatedAt []byte) error
+	History() ([]string, error)
+	Get(id string) (*Push, error)
+}
+
+func NewJournalCache(o DBOpener) JournalCache {
+	return &journalCache{dbOpener: o}
+}
+
+type journalCache struct {
+	dbOpener DBOpener
+}
+
+func (cache *journalCache) Begin(id string, plan []byte, snapshot []byte) error {
+	return cache.update(func(b *JournalBuckets) error {
+		return b.Begin(id, plan, snapshot)
+	})
+}
+
+func (cache *journalCache) Current() (p *Push, err error) {
+	err = cache.view(func(b *JournalBuckets) error {
+		p, err = b.Current()
+		return err
+	})
+	return p, err
+}
+
+func (cache *journalCache) Record(completed map[string]int64) error {
+	return cache.update(func(b *JournalBuckets) error {
+		return b.Record(completed)
+	})
+}
+
+func (cache *journalCache) Completed() (completed map[string]int64, err error) {
+	err = cache.view(func(b *JournalBuckets) error {
+		completed, err = b.Completed()
+		return err
+	})
+	return completed, err
+}
+
+func (cache *journalCache) End(updatedAt []byte) error {
+	return cache.update(func(b *JournalBuckets) error {
+		return b.End(updatedAt)
+	})
+}
+
+func (cache *journalCache) History() (ids []string, err error) {
+	err = cache.view(func(b *JournalBuckets) error {
+		ids, err = b.History()
+		return err
+	})
+	return ids, err
+}
+
+func (cache *journalCache) Get(id string) (p *Push, err error) {
+	err = cache.view(func(b *JournalBuckets) error {
+		p, err = b.Get(id)
+		return err
+	})
+	return p, err
+}
+
+func (cache *journalCache) view(fn func(b *JournalBuckets) error) error {
+	db, err := cache.dbOpener.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(func(tx *bolt.Tx) error {
+		buckets, err := NewJournalBuckets(tx)
+		if err != nil {
+			return err
+		}
+		return fn(buckets)
+	})
+}
+
+func (cache *journalCache) update(fn func(b *JournalBuckets) error) error {
+	db, err := cache.dbOpener.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(func(tx *bolt.Tx) error {
+		buckets, err := NewJournalBuckets(tx)
+		if err != nil {
+			return err
+		}
+		return fn(buckets)
+	})
+}
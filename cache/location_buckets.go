@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/samherrmann/merchant/cache/bkeys"
+	bolt "go.etcd.io/bbolt"
+)
+
+func NewLocationBuckets(tx *bolt.Tx) (*LocationBuckets, error) {
+	return &LocationBuckets{
+		tx:        tx,
+		locations: tx.Bucket([]byte(bkeys.Locations)),
+		names:     tx.Bucket([]byte(bkeys.LocationNames)),
+	}, nil
+}
+
+// LocationBuckets is a collection of Bolt Buckets to store the locations of a
+// store, indexed by name.
+type LocationBuckets struct {
+	tx        *bolt.Tx
+	locations *bolt.Bucket
+	names     *bolt.Bucket
+}
+
+// Set replaces all locations with the given locations.
+func (b *LocationBuckets) Set(locations ...goshopify.Location) error {
+	var err error
+	if b.locations, err = recreateBucket(b.tx, bkeys.Locations); err != nil {
+		return err
+	}
+	if b.names, err = recreateBucket(b.tx, bkeys.LocationNames); err != nil {
+		return err
+	}
+	for _, l := range locations {
+		k := int64ToBytes(l.ID)
+		if l.Name != "" {
+			if err := setOnce(b.names, []byte(l.Name), k); err != nil {
+				return fmt.Errorf("location name %q: %w", l.Name, err)
+			}
+		}
+		v, err := json.Marshal(l)
+		if err != nil {
+			return err
+		}
+		if err := b.locations.Put(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *LocationBuckets) GetByID(id int64) (*goshopify.Location, error) {
+	l := &goshopify.Location{}
+	if err := getJSON(b.locations, int64ToBytes(id), l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (b *LocationBuckets) GetByName(name string) (*goshopify.Location, error) {
+	l := &goshopify.Location{}
+	if err := getBySecondaryKey(b.locations, b.names, []byte(name), l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// List returns all locations ordered by ID.
+func (b *LocationBuckets) List() ([]goshopify.Location, error) {
+	locations := []goshopify.Location{}
+	if b.locations == nil {
+		return locations, nil
+	}
+	err := b.locations.ForEach(func(k, v []byte) error {
+		l := goshopify.Location{}
+		if err := json.Unmarshal(v, &l); err != nil {
+			return err
+		}
+		locations = append(locations, l)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Keys are ordered as strings, which doesn't match the order of the IDs.
+	sort.Slice(locations, func(i, j int) bool {
+		return locations[i].ID < locations[j].ID
+	})
+	return locations, nil
+}
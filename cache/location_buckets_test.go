@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestLocationBuckets_Set(t *testing.T) {
+	db := newTestDB(t)
+	db.Update(func(tx *bolt.Tx) error {
+		buckets, err := NewLocationBuckets(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := buckets.Set(goshopify.Location{ID: 1, Name: "Old"}); err != nil {
+			t.Fatal(err)
+		}
+		locations := []goshopify.Location{{ID: 10, Name: "B"}, {ID: 9, Name: "A"}}
+		if err := buckets.Set(locations...); err != nil {
+			t.Fatal(err)
+		}
+		return nil
+	})
+	db.View(func(tx *bolt.Tx) error {
+		buckets, err := NewLocationBuckets(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := buckets.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []goshopify.Location{{ID: 9, Name: "A"}, {ID: 10, Name: "B"}}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+		l, err := buckets.GetByName("B")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.ID != 10 {
+			t.Fatalf("got location %+v by name, want ID 10", l)
+		}
+		// The names of replaced locations are removed.
+		if _, err := buckets.GetByName("Old"); !errors.Is(err, ErrNotExist) {
+			t.Fatalf("got error %v, want %v", err, ErrNotExist)
+		}
+		return nil
+	})
+}
+
+func TestLocationBuckets_Set_duplicateName(t *testing.T) {
+	db := newTestDB(t)
+	db.Update(func(tx *bolt.Tx) error {
+		buckets, err := NewLocationBuckets(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = buckets.Set(goshopify.Location{ID: 1, Name: "A"}, goshopify.Location{ID: 2, Name: "A"})
+		if err == nil {
+			t.Fatal("got no error for duplicate names")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	bolt "go.etcd.io/bbolt"
+)
+
+// LocationCache stores the locations of a store.
+type LocationCache interface {
+	Set(locations ...goshopify.Location) error
+	GetByID(id int64) (*goshopify.Location, error)
+	GetByName(name string) (*goshopify.Location, error)
+	List() ([]goshopify.Location, error)
+}
+
+func NewLocationCache(o DBOpener) LocationCache {
+	return &locationCache{dbOpener: o}
+}
+
+type locationCache struct {
+	dbOpener DBOpener
+}
+
+func (cache *locationCache) Set(locations ...goshopify.Location) error {
+	return cache.update(func(b *LocationBuckets) error {
+		return b.Set(locations...)
+	})
+}
+
+func (cache *locationCache) GetByID(id int64) (l *goshopify.Location, err error) {
+	err = cache.view(func(b *LocationBuckets) error {
+		l, err = b.GetByID(id)
+		return err
+	})
+	return l, err
+}
+
+func (cache *locationCache) GetByName(name string) (l *goshopify.Location, err error) {
+	err = cache.view(func(b *LocationBuckets) error {
+		l, err = b.GetByName(name)
+		return err
+	})
+	return l, err
+}
+
+func (cache *locationCache) List() (locations []goshopify.Location, err error) {
+	err = cache.view(func(b *LocationBuckets) error {
+		locations, err = b.List()
+		return err
+	})
+	return locations, err
+}
+
+func (cache *locationCache) view(fn func(b *LocationBuckets) error) error {
+	db, err := cache.dbOpener.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(func(tx *bolt.Tx) error {
+		buckets, err := NewLocationBuckets(tx)
+		if err != nil {
+			return err
+		}
+		return fn(buckets)
+	})
+}
+
+func (cache *locationCache) update(fn func(b *LocationBuckets) error) error {
+	db, err := cache.dbOpener.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(func(tx *bolt.Tx) error {
+		buckets, err := NewLocationBuckets(tx)
+		if err != nil {
+			return err
+		}
+		return fn(buckets)
+	})
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/samherrmann/merchant/cache/bkeys"
+	bolt "go.etcd.io/bbolt"
+)
+
+func NewMetaBuckets(tx *bolt.Tx) (*MetaBuckets, error) {
+	return &MetaBuckets{
+		tx:   tx,
+		meta: tx.Bucket([]byte(bkeys.Meta)),
+	}, nil
+}
+
+// MetaBuckets is a collection of Bolt Buckets to store details about the cache
+// itself, such as its schema version.
+type MetaBuckets struct {
+	tx   *bolt.Tx
+	meta *bolt.Bucket
+}
+
+// SchemaVersion returns the schema version of the cache. It is 0 if the cache
+// predates schema versions or is empty.
+func (b *MetaBuckets) SchemaVersion() (int, error) {
+	v := b.get(bkeys.SchemaVersion)
+	if v == nil {
+		return 0, nil
+	}
+	return strconv.Atoi(string(v))
+}
+
+func (b *MetaBuckets) SetSchemaVersion(version int) error {
+	return b.put(bkeys.SchemaVersion, []byte(strconv.Itoa(version)))
+}
+
+// Store returns the name of the store of the cache. It is empty if the name
+// has never been set.
+func (b *MetaBuckets) Store() (string, error) {
+	return string(b.get(bkeys.Store)), nil
+}
+
+func (b *MetaBuckets) SetStore(name string) error {
+	return b.put(bkeys.Store, []byte(name))
+}
+
+// ClonedAt returns the start time of the last successful clone. It is the zero
+// time if the store has never been cloned.
+func (b *MetaBuckets) ClonedAt() (time.Time, error) {
+	t := time.Time{}
+	v := b.get(bkeys.ClonedAt)
+	if v == nil {
+		return t, nil
+	}
+	err := t.UnmarshalText(v)
+	return t, err
+}
+
+func (b *MetaBuckets) SetClonedAt(t time.Time) error {
+	v, err := t.MarshalText()
+	if err != nil {
+		return err
+	}
+	return b.put(bkeys.ClonedAt, v)
+}
+
+func (b *MetaBuckets) get(k string) []byte {
+	if b.meta == nil {
+		return nil
+	}
+	return b.meta.Get([]byte(k))
+}
+
+func (b *MetaBuckets) put(k string, v []byte) error {
+	var err error
+	b.meta, err = b.tx.CreateBucketIfNotExists([]byte(bkeys.Meta))
+	if err != nil {
+		return err
+	}
+	return b.meta.Put([]byte(k), v)
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Info describes a cache.
+type Info struct {
+	SchemaVersion int
+	// Store is the name of the store of the cache.
+	Store string
+	// ClonedAt is the start time of the last successful clone. It is the zero
+	// time if the store has never been cloned.
+	ClonedAt time.Time
+}
+
+// MetaCache stores details about the cache itself.
+type MetaCache interface {
+	Info() (*Info, error)
+}
+
+func NewMetaCache(o DBOpener) MetaCache {
+	return &metaCache{dbOpener: o}
+}
+
+type metaCache struct {
+	dbOpener DBOpener
+}
+
+func (cache *metaCache) Info() (info *Info, err error) {
+	err = cache.view(func(b *MetaBuckets) error {
+		info = &Info{}
+		if info.SchemaVersion, err = b.SchemaVersion(); err != nil {
+			return err
+		}
+		if info.Store, err = b.Store(); err != nil {
+			return err
+		}
+		info.ClonedAt, err = b.ClonedAt()
+		return err
+	})
+	return info, err
+}
+
+func (cache *metaCache) view(fn func(b *MetaBuckets) error) error {
+	db, err := cache.dbOpener.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(func(tx *bolt.Tx) error {
+		buckets, err := NewMetaBuckets(tx)
+		if err != nil {
+			return err
+		}
+		return fn(buckets)
+	})
+}
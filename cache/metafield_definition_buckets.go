@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/samherrmann/merchant/cache/bkeys"
+	"github.com/samherrmann/merchant/shopify"
+	bolt "go.etcd.io/bbolt"
+)
+
+func NewMetafieldDefinitionBuckets(tx *bolt.Tx) (*MetafieldDefinitionBuckets, error) {
+	return &MetafieldDefinitionBuckets{
+		tx:          tx,
+		definitions: tx.Bucket([]byte(bkeys.MetafieldDefinitions)),
+		keys:        tx.Bucket([]byte(bkeys.MetafieldDefinitionKeys)),
+	}, nil
+}
+
+// MetafieldDefinitionBuckets is a collection of Bolt Buckets to store the
+// metafield definitions of a store, indexed by owner resource, namespace and
+// key.
+type MetafieldDefinitionBuckets struct {
+	tx          *bolt.Tx
+	definitions *bolt.Bucket
+	keys        *bolt.Bucket
+}
+
+// Set replaces all metafield definitions with the given definitions.
+func (b *MetafieldDefinitionBuckets) Set(defs ...shopify.MetafieldDefinition) error {
+	var err error
+	if b.definitions, err = recreateBucket(b.tx, bkeys.MetafieldDefinitions); err != nil {
+		return err
+	}
+	if b.keys, err = recreateBucket(b.tx, bkeys.MetafieldDefinitionKeys); err != nil {
+		return err
+	}
+	for _, d := range defs {
+		k := int64ToBytes(d.ID)
+		if err := setOnce(b.keys, definitionKey(d.OwnerResource, d.Namespace, d.Key), k); err != nil {
+			return fmt.Errorf("metafield definition %v.%v: %w", d.Namespace, d.Key, err)
+		}
+		v, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		if err := b.definitions.Put(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *MetafieldDefinitionBuckets) GetByID(id int64) (*shopify.MetafieldDefinition, error) {
+	d := &shopify.MetafieldDefinition{}
+	if err := getJSON(b.definitions, int64ToBytes(id), d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// GetByKey returns the definition of the metafields with the given namespace
+// and key of the given owner resource, e.g. "product".
+func (b *MetafieldDefinitionBuckets) GetByKey(ownerResource string, namespace string, key string) (*shopify.MetafieldDefinition, error) {
+	d := &shopify.MetafieldDefinition{}
+	if err := getBySecondaryKey(b.definitions, b.keys, definitionKey(ownerResource, namespace, key), d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// List returns all metafield definitions ordered by ID.
+func (b *MetafieldDefinitionBuckets) List() ([]shopify.MetafieldDefinition, error) {
+	defs := []shopify.MetafieldDefinition{}
+	if b.definitions == nil {
+		return defs, nil
+	}
+	err := b.definitions.ForEach(func(k, v []byte) error {
+		d := shopify.MetafieldDefinition{}
+		if err := json.Unmarshal(v, &d); err != nil {
+			return err
+		}
+		defs = append(defs, d)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].ID < defs[j].ID
+	})
+	return defs, nil
+}
+
+// definitionKey returns the key of a metafield definition in the keys bucket,
+// e.g. "product/custom.fabric".
+func definitionKey(ownerResource string, namespace string, key string) []byte {
+	return []byte(fmt.Sprintf("%v/%v.%v", ownerResource, namespace, key))
+}
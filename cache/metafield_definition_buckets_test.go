@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/samherrmann/merchant/shopify"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestMetafieldDefinitionBuckets(t *testing.T) {
+	db := newTestDB(t)
+	db.Update(func(tx *bolt.Tx) error {
+		buckets, err := NewMetafieldDefinitionBuckets(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buckets.Set(
+			shopify.MetafieldDefinition{ID: 1, OwnerResource: "product", Namespace: "custom", Key: "fabric", Type: "single_line_text_field"},
+			shopify.MetafieldDefinition{ID: 2, OwnerResource: "variant", Namespace: "custom", Key: "fabric", Type: "multi_line_text_field"},
+		)
+	})
+	db.View(func(tx *bolt.Tx) error {
+		buckets, err := NewMetafieldDefinitionBuckets(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Definitions with the same namespace and key are told apart by their
+		// owner resource.
+		d, err := buckets.GetByKey("variant", "custom", "fabric")
+		if err != nil || d.ID != 2 {
+			t.Fatalf("got definition %+v and error %v", d, err)
+		}
+		if _, err := buckets.GetByKey("collection", "custom", "fabric"); !errors.Is(err, ErrNotExist) {
+			t.Fatalf("got error %v, want %v", err, ErrNotExist)
+		}
+		list, err := buckets.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 2 || list[0].Type != "single_line_text_field" {
+			t.Fatalf("got definitions %+v", list)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"github.com/samherrmann/merchant/shopify"
+	bolt "go.etcd.io/bbolt"
+)
+
+// MetafieldDefinitionCache stores the metafield definitions of a store.
+type MetafieldDefinitionCache interface {
+	Set(defs ...shopify.MetafieldDefinition) error
+	GetByID(id int64) (*shopify.MetafieldDefinition, error)
+	GetByKey(ownerResource string, namespace string, key string) (*shopify.MetafieldDefinition, error)
+	List() ([]shopify.MetafieldDefinition, error)
+}
+
+func NewMetafieldDefinitionCache(o DBOpener) MetafieldDefinitionCache {
+	return &metafieldDefinitionCache{dbOpener: o}
+}
+
+type metafieldDefinitionCache struct {
+	dbOpener DBOpener
+}
+
+func (cache *metafieldDefinitionCache) Set(defs ...shopify.MetafieldDefinition) error {
+	return cache.update(func(b *MetafieldDefinitionBuckets) error {
+		return b.Set(defs...)
+	})
+}
+
+func (cache *metafieldDefinitionCache) GetByID(id int64) (d *shopify.MetafieldDefinition, err error) {
+	err = cache.view(func(b *MetafieldDefinitionBuckets) error {
+		d, err = b.GetByID(id)
+		return err
+	})
+	return d, err
+}
+
+func (cache *metafieldDefinitionCache) GetByKey(ownerResource string, namespace string, key string) (d *shopify.MetafieldDefinition, err error) {
+	err = cache.view(func(b *MetafieldDefinitionBuckets) error {
+		d, err = b.GetByKey(ownerResource, namespace, key)
+		return err
+	})
+	return d, err
+}
+
+func (cache *metafieldDefinitionCache) List() (defs []shopify.MetafieldDefinition, err error) {
+	err = cache.view(func(b *MetafieldDefinitionBuckets) error {
+		defs, err = b.List()
+		return err
+	})
+	return defs, err
+}
+
+func (cache *metafieldDefinitionCache) view(fn func(b *MetafieldDefinitionBuckets) error) error {
+	db, err := cache.dbOpener.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(func(tx *bolt.Tx) error {
+		buckets, err := NewMetafieldDefinitionBuckets(tx)
+		if err != nil {
+			return err
+		}
+		return fn(buckets)
+	})
+}
+
+func (cache *metafieldDefinitionCache) update(fn func(b *MetafieldDefinitionBuckets) error) error {
+	db, err := cache.dbOpener.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(func(tx *bolt.Tx) error {
+		buckets, err := NewMetafieldDefinitionBuckets(tx)
+		if err != nil {
+			return err
+		}
+		return fn(buckets)
+	})
+}
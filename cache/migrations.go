@@ -0,0 +1,269 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/samherrmann/merchant/cache/bkeys"
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrSchemaVersion is returned if the cache has been written by a newer
+// version of the application.
+var ErrSchemaVersion = errors.New("unsupported cache schema version")
+
+// migration upgrades the cache from the previous schema version. Migrations
+// must also succeed on an empty cache. They only operate on the buckets
+// directly, because the buckets types of the application follow the latest
+// schema version.
+type migration func(tx *bolt.Tx) error
+
+// migrations are the migrations by schema version, starting at version 1.
+// Version 0 is the layout of caches that predate schema versions. A migration
+// is appended whenever the layout of the buckets or the stored JSON changes.
+var migrations = [...]migration{
+	rebuildIndexes,
+	moveClonedAt,
+}
+
+// SchemaVersion is the schema version of caches that are written by this
+// version of the application.
+const SchemaVersion = len(migrations)
+
+// adoptLegacyDB moves the cache database of versions that supported a single
+// store only to filename, unless a database already exists there, and reports
+// the move to out. The legacy database is thereby adopted by the first store
+// that opens its cache, which after an upgrade is the single store of the
+// converted configuration. The database is migrated when it is opened next.
+func adoptLegacyDB(out io.Writer, filename string) error {
+	root, err := os.UserCacheDir()
+	if err != nil {
+		return err
+	}
+	legacy := filepath.Join(root, AppName, dbFilename)
+	if _, err := os.Stat(legacy); errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filename); err == nil {
+		return nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Rename(legacy, filename); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "Moved the cache of a previous version to %v\n", filename)
+	return err
+}
+
+// migrate upgrades the cache of the given store to SchemaVersion and records
+// the name of the store if it isn't recorded yet.
+func migrate(db *bolt.DB, store string) error {
+	upToDate := false
+	err := db.View(func(tx *bolt.Tx) error {
+		meta, err := NewMetaBuckets(tx)
+		if err != nil {
+			return err
+		}
+		version, err := meta.SchemaVersion()
+		if err != nil {
+			return err
+		}
+		name, err := meta.Store()
+		if err != nil {
+			return err
+		}
+		if version > SchemaVersion {
+			return fmt.Errorf("%w %v, the latest supported version is %v: update %v or clear the cache", ErrSchemaVersion, version, SchemaVersion, AppName)
+		}
+		upToDate = version == SchemaVersion && name != ""
+		return nil
+	})
+	if err != nil || upToDate {
+		return err
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		meta, err := NewMetaBuckets(tx)
+		if err != nil {
+			return err
+		}
+		version, err := meta.SchemaVersion()
+		if err != nil {
+			return err
+		}
+		// The version is checked again because another process may have
+		// migrated the cache in the meantime.
+		if version > SchemaVersion {
+			return fmt.Errorf("%w %v", ErrSchemaVersion, version)
+		}
+		for i := version; i < SchemaVersion; i++ {
+			if err := migrations[i](tx); err != nil {
+				return fmt.Errorf("failed to migrate cache to schema version %v: %w", i+1, err)
+			}
+		}
+		if err := meta.SetSchemaVersion(SchemaVersion); err != nil {
+			return err
+		}
+		name, err := meta.Store()
+		if err != nil {
+			return err
+		}
+		if name != "" {
+			return nil
+		}
+		return meta.SetStore(store)
+	})
+}
+
+// rebuildIndexes rebuilds the handle and title indexes of the products,
+// which could contain stale keys, and adds the variant and location name
+// indexes. SKUs and barcodes are indexed to a comma separated list of the IDs
+// of their variants. All other keys must be unique like in the live buckets,
+// and an error listing every duplicate key is returned otherwise.
+func rebuildIndexes(tx *bolt.Tx) error {
+	indexes := map[string]*bolt.Bucket{
+		bkeys.ProductHandles:        nil,
+		bkeys.ProductTitles:         nil,
+		bkeys.Variants:              nil,
+		bkeys.VariantSKUs:           nil,
+		bkeys.VariantBarcodes:       nil,
+		bkeys.VariantInventoryItems: nil,
+		bkeys.LocationNames:         nil,
+	}
+	for name := range indexes {
+		err := tx.DeleteBucket([]byte(name))
+		if err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return err
+		}
+		if indexes[name], err = tx.CreateBucket([]byte(name)); err != nil {
+			return err
+		}
+	}
+	// duplicates are the errors of the keys that are indexed to several
+	// records.
+	duplicates := []error{}
+	// putOnce indexes k to v with setOnce, like the live buckets, and records
+	// the error if k is already indexed to another value.
+	putOnce := func(bucket string, k string, v int64) error {
+		if k == "" {
+			return nil
+		}
+		if err := setOnce(indexes[bucket], []byte(k), []byte(strconv.FormatInt(v, 10))); err != nil {
+			duplicates = append(duplicates, fmt.Errorf("%v %q: %w", indexNames[bucket], k, err))
+		}
+		return nil
+	}
+	// appendID appends v to the IDs of k.
+	appendID := func(bucket string, k string, v int64) error {
+		if k == "" {
+			return nil
+		}
+		id := strconv.FormatInt(v, 10)
+		if ids := indexes[bucket].Get([]byte(k)); ids != nil {
+			id = string(ids) + "," + id
+		}
+		return indexes[bucket].Put([]byte(k), []byte(id))
+	}
+
+	if products := tx.Bucket([]byte(bkeys.Products)); products != nil {
+		err := products.ForEach(func(k, v []byte) error {
+			p := struct {
+				ID       int64  `json:"id"`
+				Title    string `json:"title"`
+				Handle   string `json:"handle"`
+				Variants []struct {
+					ID              int64  `json:"id"`
+					Sku             string `json:"sku"`
+					Barcode         string `json:"barcode"`
+					InventoryItemID int64  `json:"inventory_item_id"`
+				} `json:"variants"`
+			}{}
+			if err := json.Unmarshal(v, &p); err != nil {
+				return err
+			}
+			if err := putOnce(bkeys.ProductHandles, p.Handle, p.ID); err != nil {
+				return err
+			}
+			if err := putOnce(bkeys.ProductTitles, p.Title, p.ID); err != nil {
+				return err
+			}
+			for _, variant := range p.Variants {
+				id := strconv.FormatInt(variant.ID, 10)
+				if err := putOnce(bkeys.Variants, id, p.ID); err != nil {
+					return err
+				}
+				if err := appendID(bkeys.VariantSKUs, variant.Sku, variant.ID); err != nil {
+					return err
+				}
+				if err := appendID(bkeys.VariantBarcodes, variant.Barcode, variant.ID); err != nil {
+					return err
+				}
+				if variant.InventoryItemID != 0 {
+					item := strconv.FormatInt(variant.InventoryItemID, 10)
+					if err := putOnce(bkeys.VariantInventoryItems, item, variant.ID); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if locations := tx.Bucket([]byte(bkeys.Locations)); locations != nil {
+		err := locations.ForEach(func(k, v []byte) error {
+			l := struct {
+				ID   int64  `json:"id"`
+				Name string `json:"name"`
+			}{}
+			if err := json.Unmarshal(v, &l); err != nil {
+				return err
+			}
+			return putOnce(bkeys.LocationNames, l.Name, l.ID)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(duplicates) > 0 {
+		return fmt.Errorf("%w\nclear the cache and clone the store again", errors.Join(duplicates...))
+	}
+	return nil
+}
+
+// indexNames are the names of the keys of the unique indexes in errors.
+var indexNames = map[string]string{
+	bkeys.ProductHandles:        "product handle",
+	bkeys.ProductTitles:         "product title",
+	bkeys.Variants:              "variant",
+	bkeys.VariantInventoryItems: "inventory item",
+	bkeys.LocationNames:         "location name",
+}
+
+// moveClonedAt moves the clone time from the ProductsMeta bucket into the Meta
+// bucket.
+func moveClonedAt(tx *bolt.Tx) error {
+	old := tx.Bucket([]byte(bkeys.ProductsMeta))
+	if old == nil {
+		return nil
+	}
+	if v := old.Get([]byte(bkeys.ClonedAt)); v != nil {
+		meta, err := NewMetaBuckets(tx)
+		if err != nil {
+			return err
+		}
+		if err := meta.put(bkeys.ClonedAt, v); err != nil {
+			return err
+		}
+	}
+	return tx.DeleteBucket([]byte(bkeys.ProductsMeta))
+}
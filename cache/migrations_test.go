@@ -0,0 +1,231 @@
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samherrmann/merchant/cache/bkeys"
+	bolt "go.etcd.io/bbolt"
+)
+
+func Test_migrate(t *testing.T) {
+	t.Run("upgrades cache without schema version", func(t *testing.T) {
+		clonedAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+		db := newTestDB(t)
+		// Write a cache in the layout that predates schema versions.
+		db.Update(func(tx *bolt.Tx) error {
+			put := func(bucket string, k []byte, v []byte) {
+				b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := b.Put(k, v); err != nil {
+					t.Fatal(err)
+				}
+			}
+			put(bkeys.Products, int64ToBytes(1), []byte(`{"id":1,"title":"T-Shirt","handle":"shirt","variants":[{"id":11,"sku":"SHIRT"}]}`))
+			// SKUs are not unique.
+			put(bkeys.Products, int64ToBytes(2), []byte(`{"id":2,"title":"Hat","handle":"hat","variants":[{"id":21,"sku":"SHIRT"}]}`))
+			put(bkeys.ProductTitles, []byte("Shirt"), int64ToBytes(1))
+			put(bkeys.ProductHandles, []byte("shirt"), int64ToBytes(1))
+			put(bkeys.Locations, int64ToBytes(5), []byte(`{"id":5,"name":"Warehouse"}`))
+			v, _ := clonedAt.MarshalText()
+			put(bkeys.ProductsMeta, []byte(bkeys.ClonedAt), v)
+			return nil
+		})
+
+		if err := migrate(db, "test"); err != nil {
+			t.Fatal(err)
+		}
+
+		db.View(func(tx *bolt.Tx) error {
+			meta, err := NewMetaBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v, err := meta.SchemaVersion(); err != nil || v != SchemaVersion {
+				t.Errorf("got schema version %v and error %v, want %v", v, err, SchemaVersion)
+			}
+			if s, err := meta.Store(); err != nil || s != "test" {
+				t.Errorf("got store %q and error %v, want test", s, err)
+			}
+			if got, err := meta.ClonedAt(); err != nil || !got.Equal(clonedAt) {
+				t.Errorf("got clone time %v and error %v, want %v", got, err, clonedAt)
+			}
+			if tx.Bucket([]byte(bkeys.ProductsMeta)) != nil {
+				t.Error("expected old products meta bucket to be deleted")
+			}
+
+			products, err := NewProductBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := products.GetByTitle("Shirt"); !errors.Is(err, ErrNotExist) {
+				t.Errorf("got %v for stale title, want %v", err, ErrNotExist)
+			}
+			if _, err := products.GetByTitle("T-Shirt"); err != nil {
+				t.Error(err)
+			}
+			if v, err := products.GetVariantBySKU("SHIRT"); err != nil || v.ID != 11 {
+				t.Errorf("got variant %+v and error %v", v, err)
+			}
+			if ids := products.skus.Get([]byte("SHIRT")); string(ids) != "11,21" {
+				t.Errorf("got variant IDs %q of SKU, want 11,21", ids)
+			}
+
+			locations, err := NewLocationBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := locations.GetByName("Warehouse"); err != nil {
+				t.Error(err)
+			}
+			return nil
+		})
+	})
+
+	t.Run("reports duplicate titles", func(t *testing.T) {
+		db := newTestDB(t)
+		db.Update(func(tx *bolt.Tx) error {
+			b, err := tx.CreateBucketIfNotExists([]byte(bkeys.Products))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for id, handle := range map[int64]string{1: "shirt", 2: "shirt-2"} {
+				v := fmt.Sprintf(`{"id":%v,"title":"Shirt","handle":%q}`, id, handle)
+				if err := b.Put(int64ToBytes(id), []byte(v)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			return nil
+		})
+
+		err := migrate(db, "test")
+		if err == nil || !strings.Contains(err.Error(), `product title "Shirt"`) {
+			t.Fatalf("got error %v, want duplicate title", err)
+		}
+		db.View(func(tx *bolt.Tx) error {
+			meta, err := NewMetaBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v, err := meta.SchemaVersion(); err != nil || v != 0 {
+				t.Errorf("got schema version %v and error %v, want 0", v, err)
+			}
+			return nil
+		})
+	})
+
+	t.Run("sets up empty cache", func(t *testing.T) {
+		db := newTestDB(t)
+		if err := migrate(db, "test"); err != nil {
+			t.Fatal(err)
+		}
+		db.View(func(tx *bolt.Tx) error {
+			meta, err := NewMetaBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v, err := meta.SchemaVersion(); err != nil || v != SchemaVersion {
+				t.Errorf("got schema version %v and error %v, want %v", v, err, SchemaVersion)
+			}
+			return nil
+		})
+	})
+
+	t.Run("keeps recorded store", func(t *testing.T) {
+		db := newTestDB(t)
+		if err := migrate(db, "foo"); err != nil {
+			t.Fatal(err)
+		}
+		if err := migrate(db, "bar"); err != nil {
+			t.Fatal(err)
+		}
+		db.View(func(tx *bolt.Tx) error {
+			meta, err := NewMetaBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s, err := meta.Store(); err != nil || s != "foo" {
+				t.Errorf("got store %q and error %v, want foo", s, err)
+			}
+			return nil
+		})
+	})
+
+	t.Run("rejects newer schema version", func(t *testing.T) {
+		db := newTestDB(t)
+		db.Update(func(tx *bolt.Tx) error {
+			meta, err := NewMetaBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return meta.SetSchemaVersion(SchemaVersion + 1)
+		})
+		if err := migrate(db, "test"); !errors.Is(err, ErrSchemaVersion) {
+			t.Fatalf("got %v, want %v", err, ErrSchemaVersion)
+		}
+	})
+}
+
+func Test_adoptLegacyDB(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", root)
+	legacy := filepath.Join(root, AppName, dbFilename)
+	writeFile := func(filename string, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The cache directory of the store exists by the time the database is
+	// adopted.
+	filename := filepath.Join(root, AppName, "test", dbFilename)
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("moves legacy database", func(t *testing.T) {
+		writeFile(legacy, "legacy")
+		out := &bytes.Buffer{}
+		if err := adoptLegacyDB(out, filename); err != nil {
+			t.Fatal(err)
+		}
+		if b, err := os.ReadFile(filename); err != nil || string(b) != "legacy" {
+			t.Fatalf("got %q and error %v, want legacy database", b, err)
+		}
+		if _, err := os.Stat(legacy); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("got error %v, want legacy database to be moved", err)
+		}
+		if !strings.Contains(out.String(), filename) {
+			t.Fatalf("got output %q, want new path", out)
+		}
+	})
+
+	t.Run("keeps existing database silently", func(t *testing.T) {
+		writeFile(legacy, "legacy")
+		writeFile(filename, "current")
+		out := &bytes.Buffer{}
+		if err := adoptLegacyDB(out, filename); err != nil {
+			t.Fatal(err)
+		}
+		if b, err := os.ReadFile(filename); err != nil || string(b) != "current" {
+			t.Fatalf("got %q and error %v, want current database", b, err)
+		}
+		if _, err := os.Stat(legacy); err != nil {
+			t.Fatalf("got error %v, want legacy database to be kept", err)
+		}
+		if out.Len() != 0 {
+			t.Fatalf("got output %q, want none", out)
+		}
+	})
+}
@@ -3,8 +3,11 @@ package cache
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	goshopify "github.com/bold-commerce/go-shopify/v3"
 	"github.com/samherrmann/merchant/cache/bkeys"
@@ -17,15 +20,28 @@ func NewProductBuckets(tx *bolt.Tx) (*ProductBuckets, error) {
 		products: tx.Bucket([]byte(bkeys.Products)),
 		handles:  tx.Bucket([]byte(bkeys.ProductHandles)),
 		titles:   tx.Bucket([]byte(bkeys.ProductTitles)),
+		alts:     tx.Bucket([]byte(bkeys.ImageAlts)),
+		variants: tx.Bucket([]byte(bkeys.Variants)),
+		skus:     tx.Bucket([]byte(bkeys.VariantSKUs)),
+		barcodes: tx.Bucket([]byte(bkeys.VariantBarcodes)),
+		items:    tx.Bucket([]byte(bkeys.VariantInventoryItems)),
 	}, nil
 }
 
-// ProductBuckets is a collection of Bolt Buckets to store products.
+// ProductBuckets is a collection of Bolt Buckets to store products. Variants
+// are indexed by ID, SKU, barcode and inventory item ID. The variants bucket
+// maps variant IDs to the IDs of their products, and the other variant buckets
+// map to variant IDs.
 type ProductBuckets struct {
 	tx       *bolt.Tx
 	products *bolt.Bucket
 	titles   *bolt.Bucket
 	handles  *bolt.Bucket
+	alts     *bolt.Bucket
+	variants *bolt.Bucket
+	skus     *bolt.Bucket
+	barcodes *bolt.Bucket
+	items    *bolt.Bucket
 }
 
 func (b *ProductBuckets) GetByID(id int64) (*goshopify.Product, error) {
@@ -53,8 +69,27 @@ func (b *ProductBuckets) GetByTitle(title string) (*goshopify.Product, error) {
 	return b.getBySecondaryKey(b.titles, []byte(title))
 }
 
+// Update inserts or replaces the given products. The secondary keys of the
+// cached versions of the products are removed first, so that renamed products
+// release their old handles and titles, and handles, titles, SKUs and barcodes
+// can move between the given products.
 func (b *ProductBuckets) Update(products ...goshopify.Product) error {
 	for _, p := range products {
+		current, err := b.GetByID(p.ID)
+		if errors.Is(err, ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := b.deleteSecondaryKeys(current); err != nil {
+			return err
+		}
+	}
+	for _, p := range products {
+		if err := b.putVariantKeys(&p); err != nil {
+			return err
+		}
 		// Insert in handle bucket.
 		if p.Handle != "" {
 			var err error
@@ -120,6 +155,282 @@ func (b *ProductBuckets) List() ([]goshopify.Product, error) {
 	return products, nil
 }
 
+// Find returns the products that match q with only their variants that match
+// q.
+func (b *ProductBuckets) Find(q ProductQuery) ([]goshopify.Product, error) {
+	products, err := b.List()
+	if err != nil {
+		return nil, err
+	}
+	found := []goshopify.Product{}
+	for _, p := range products {
+		if p, ok := q.Filter(p); ok {
+			found = append(found, p)
+		}
+	}
+	return found, nil
+}
+
+// Delete removes the products with the given IDs together with their handle
+// and title keys. IDs that don't exist are ignored.
+func (b *ProductBuckets) Delete(ids ...int64) error {
+	for _, id := range ids {
+		p, err := b.GetByID(id)
+		if errors.Is(err, ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := b.deleteSecondaryKeys(p); err != nil {
+			return err
+		}
+		if err := b.products.Delete(int64ToBytes(id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteSecondaryKeys removes the handle, title and variant keys of p. Keys
+// that have since been taken by another product are kept.
+func (b *ProductBuckets) deleteSecondaryKeys(p *goshopify.Product) error {
+	k := int64ToBytes(p.ID)
+	if p.Handle != "" && b.handles != nil {
+		if err := deleteIfEqual(b.handles, []byte(p.Handle), k); err != nil {
+			return err
+		}
+	}
+	if p.Title != "" && b.titles != nil {
+		if err := deleteIfEqual(b.titles, []byte(p.Title), k); err != nil {
+			return err
+		}
+	}
+	return b.deleteVariantKeys(p)
+}
+
+// IDs returns the IDs of all products.
+func (b *ProductBuckets) IDs() ([]int64, error) {
+	if b.products == nil {
+		return nil, nil
+	}
+	var ids []int64
+	err := b.products.ForEach(func(k, v []byte) error {
+		id, err := bytesToInt64(k)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ClonedAt returns the start time of the last successful clone. The zero time
+// is returned if no clone has completed yet.
+func (b *ProductBuckets) ClonedAt() (time.Time, error) {
+	meta, err := NewMetaBuckets(b.tx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return meta.ClonedAt()
+}
+
+// SetClonedAt sets the start time of the last successful clone.
+func (b *ProductBuckets) SetClonedAt(t time.Time) error {
+	meta, err := NewMetaBuckets(b.tx)
+	if err != nil {
+		return err
+	}
+	return meta.SetClonedAt(t)
+}
+
+// SetImageAlts replaces the alt texts of all product images with the given alt
+// texts by image ID.
+func (b *ProductBuckets) SetImageAlts(alts map[int64]string) error {
+	err := b.tx.DeleteBucket([]byte(bkeys.ImageAlts))
+	if err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+		return err
+	}
+	b.alts, err = b.tx.CreateBucket([]byte(bkeys.ImageAlts))
+	if err != nil {
+		return err
+	}
+	for id, alt := range alts {
+		if err := b.alts.Put(int64ToBytes(id), []byte(alt)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImageAlts returns the alt texts of the product images by image ID.
+func (b *ProductBuckets) ImageAlts() (map[int64]string, error) {
+	alts := map[int64]string{}
+	if b.alts == nil {
+		return alts, nil
+	}
+	err := b.alts.ForEach(func(k, v []byte) error {
+		id, err := bytesToInt64(k)
+		if err != nil {
+			return err
+		}
+		alts[id] = string(v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return alts, nil
+}
+
+// GetVariantByID returns the variant with the given ID.
+func (b *ProductBuckets) GetVariantByID(id int64) (*goshopify.Variant, error) {
+	if b.variants == nil {
+		return nil, ErrNotExist
+	}
+	productID := b.variants.Get(int64ToBytes(id))
+	if productID == nil {
+		return nil, ErrNotExist
+	}
+	pid, err := bytesToInt64(productID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := b.GetByID(pid)
+	if err != nil {
+		return nil, err
+	}
+	for i := range p.Variants {
+		if p.Variants[i].ID == id {
+			return &p.Variants[i], nil
+		}
+	}
+	return nil, ErrNotExist
+}
+
+// GetVariantBySKU returns the variant with the given SKU. SKUs are not unique,
+// so if several variants have the SKU, then the first one that was cached is
+// returned.
+func (b *ProductBuckets) GetVariantBySKU(sku string) (*goshopify.Variant, error) {
+	return b.getVariantBySecondaryKey(b.skus, []byte(sku), func(v *goshopify.Variant) bool {
+		return v.Sku == sku
+	})
+}
+
+// GetVariantByBarcode returns the variant with the given barcode. Barcodes are
+// not unique either, see GetVariantBySKU.
+func (b *ProductBuckets) GetVariantByBarcode(barcode string) (*goshopify.Variant, error) {
+	return b.getVariantBySecondaryKey(b.barcodes, []byte(barcode), func(v *goshopify.Variant) bool {
+		return v.Barcode == barcode
+	})
+}
+
+func (b *ProductBuckets) GetVariantByInventoryItemID(id int64) (*goshopify.Variant, error) {
+	return b.getVariantBySecondaryKey(b.items, int64ToBytes(id), func(v *goshopify.Variant) bool {
+		return v.InventoryItemId == id
+	})
+}
+
+// getVariantBySecondaryKey returns the first variant in the ID list of the key
+// in the given variant bucket that matches.
+func (b *ProductBuckets) getVariantBySecondaryKey(bucket *bolt.Bucket, key []byte, match func(v *goshopify.Variant) bool) (*goshopify.Variant, error) {
+	if bucket == nil {
+		return nil, ErrNotExist
+	}
+	ids, err := bytesToIDs(bucket.Get(key))
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		v, err := b.GetVariantByID(id)
+		if errors.Is(err, ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if match(v) {
+			return v, nil
+		}
+	}
+	return nil, ErrNotExist
+}
+
+// putVariantKeys inserts the variants of p into the variant buckets.
+func (b *ProductBuckets) putVariantKeys(p *goshopify.Product) error {
+	if len(p.Variants) == 0 {
+		return nil
+	}
+	var err error
+	if b.variants, err = b.tx.CreateBucketIfNotExists([]byte(bkeys.Variants)); err != nil {
+		return err
+	}
+	if b.skus, err = b.tx.CreateBucketIfNotExists([]byte(bkeys.VariantSKUs)); err != nil {
+		return err
+	}
+	if b.barcodes, err = b.tx.CreateBucketIfNotExists([]byte(bkeys.VariantBarcodes)); err != nil {
+		return err
+	}
+	if b.items, err = b.tx.CreateBucketIfNotExists([]byte(bkeys.VariantInventoryItems)); err != nil {
+		return err
+	}
+	for _, v := range p.Variants {
+		k := int64ToBytes(v.ID)
+		if err := b.variants.Put(k, int64ToBytes(p.ID)); err != nil {
+			return err
+		}
+		if v.Sku != "" {
+			if err := addID(b.skus, []byte(v.Sku), v.ID); err != nil {
+				return err
+			}
+		}
+		if v.Barcode != "" {
+			if err := addID(b.barcodes, []byte(v.Barcode), v.ID); err != nil {
+				return err
+			}
+		}
+		if v.InventoryItemId != 0 {
+			if err := setOnce(b.items, int64ToBytes(v.InventoryItemId), k); err != nil {
+				return fmt.Errorf("variant inventory item %v: %w", v.InventoryItemId, err)
+			}
+		}
+	}
+	return nil
+}
+
+// deleteVariantKeys removes the variants of p from the variant buckets. Keys
+// that already belong to other variants are kept.
+func (b *ProductBuckets) deleteVariantKeys(p *goshopify.Product) error {
+	for _, v := range p.Variants {
+		k := int64ToBytes(v.ID)
+		if b.variants != nil {
+			if err := deleteIfEqual(b.variants, k, int64ToBytes(p.ID)); err != nil {
+				return err
+			}
+		}
+		if v.Sku != "" && b.skus != nil {
+			if err := removeID(b.skus, []byte(v.Sku), v.ID); err != nil {
+				return err
+			}
+		}
+		if v.Barcode != "" && b.barcodes != nil {
+			if err := removeID(b.barcodes, []byte(v.Barcode), v.ID); err != nil {
+				return err
+			}
+		}
+		if v.InventoryItemId != 0 && b.items != nil {
+			if err := deleteIfEqual(b.items, int64ToBytes(v.InventoryItemId), k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // getProductBySecondaryKey returns the product from the primary bucket given a
 // secondary key. The value associated with the secondary key is expected to be
 // the key of the product in the primary bucket.
@@ -160,7 +471,81 @@ func setOnce(bucket *bolt.Bucket, k []byte, v []byte) error {
 	return bucket.Put(k, v)
 }
 
+// deleteIfEqual deletes the key from the bucket if its value is v.
+func deleteIfEqual(bucket *bolt.Bucket, k []byte, v []byte) error {
+	if !bytes.Equal(bucket.Get(k), v) {
+		return nil
+	}
+	return bucket.Delete(k)
+}
+
+// addID appends id to the comma separated list of IDs of the key in the
+// bucket, unless the list already contains it. Keys with ID lists index values
+// that are not unique, e.g. SKUs.
+func addID(bucket *bolt.Bucket, k []byte, id int64) error {
+	ids, err := bytesToIDs(bucket.Get(k))
+	if err != nil {
+		return err
+	}
+	for _, other := range ids {
+		if other == id {
+			return nil
+		}
+	}
+	return bucket.Put(k, idsToBytes(append(ids, id)))
+}
+
+// removeID removes id from the list of IDs of the key in the bucket, see addID.
+// The key is deleted once its list is empty.
+func removeID(bucket *bolt.Bucket, k []byte, id int64) error {
+	ids, err := bytesToIDs(bucket.Get(k))
+	if err != nil {
+		return err
+	}
+	kept := []int64{}
+	for _, other := range ids {
+		if other != id {
+			kept = append(kept, other)
+		}
+	}
+	if len(kept) == 0 {
+		return bucket.Delete(k)
+	}
+	return bucket.Put(k, idsToBytes(kept))
+}
+
+// idsToBytes returns the byte encoding of a list of IDs. A single ID is
+// encoded the same way as by int64ToBytes.
+func idsToBytes(ids []int64) []byte {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.FormatInt(id, 10)
+	}
+	return []byte(strings.Join(s, ","))
+}
+
+// bytesToIDs is the inverse of idsToBytes. It returns an empty list for nil.
+func bytesToIDs(b []byte) ([]int64, error) {
+	ids := []int64{}
+	if len(b) == 0 {
+		return ids, nil
+	}
+	for _, s := range strings.Split(string(b), ",") {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
 // int64ToBytes returns the byte encoding of v.
 func int64ToBytes(v int64) []byte {
 	return []byte(strconv.FormatInt(v, 10))
 }
+
+// bytesToInt64 is the inverse of int64ToBytes.
+func bytesToInt64(b []byte) (int64, error) {
+	return strconv.ParseInt(string(b), 10, 64)
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	goshopify "github.com/bold-commerce/go-shopify/v3"
 	"github.com/samherrmann/merchant/cache/bkeys"
@@ -390,6 +391,349 @@ func TestProductBuckets_Update(t *testing.T) {
 			return nil
 		})
 	})
+	t.Run("removes old keys of renamed product", func(t *testing.T) {
+		db := newTestDB(t)
+		db.Update(func(tx *bolt.Tx) error {
+			buckets, err := NewProductBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := goshopify.Product{ID: 123, Handle: "shirt", Title: "Shirt"}
+			if err := buckets.Update(p); err != nil {
+				t.Fatal(err)
+			}
+			p.Handle, p.Title = "t-shirt", "T-Shirt"
+			if err := buckets.Update(p); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := buckets.GetByHandle("shirt"); !errors.Is(err, ErrNotExist) {
+				t.Fatalf("got %v, want %v", err, ErrNotExist)
+			}
+			if _, err := buckets.GetByTitle("Shirt"); !errors.Is(err, ErrNotExist) {
+				t.Fatalf("got %v, want %v", err, ErrNotExist)
+			}
+			got, err := buckets.GetByTitle("T-Shirt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != p.ID {
+				t.Fatalf("got product %v, want %v", got.ID, p.ID)
+			}
+			// The old title can be taken by another product.
+			if err := buckets.Update(goshopify.Product{ID: 456, Handle: "shirt", Title: "Shirt"}); err != nil {
+				t.Fatal(err)
+			}
+			return nil
+		})
+	})
+
+	t.Run("allows products to swap titles", func(t *testing.T) {
+		db := newTestDB(t)
+		db.Update(func(tx *bolt.Tx) error {
+			buckets, err := NewProductBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p1 := goshopify.Product{ID: 123, Handle: "product-123", Title: "Hat"}
+			p2 := goshopify.Product{ID: 456, Handle: "product-456", Title: "Cap"}
+			if err := buckets.Update(p1, p2); err != nil {
+				t.Fatal(err)
+			}
+			p1.Title, p2.Title = p2.Title, p1.Title
+			if err := buckets.Update(p1, p2); err != nil {
+				t.Fatal(err)
+			}
+			got, err := buckets.GetByTitle("Hat")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != p2.ID {
+				t.Fatalf("got product %v, want %v", got.ID, p2.ID)
+			}
+			return nil
+		})
+	})
+}
+
+func TestProductBuckets_Delete(t *testing.T) {
+	t.Run("removes product and its secondary keys", func(t *testing.T) {
+		db := newTestDB(t)
+		db.Update(func(tx *bolt.Tx) error {
+			buckets, err := NewProductBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := goshopify.Product{ID: 123, Handle: "foo", Title: "Foo"}
+			if err := buckets.Update(p); err != nil {
+				t.Fatal(err)
+			}
+			if err := buckets.Delete(123); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := buckets.GetByID(123); !errors.Is(err, ErrNotExist) {
+				t.Fatalf("got %v, want %v", err, ErrNotExist)
+			}
+			if v := buckets.handles.Get([]byte("foo")); v != nil {
+				t.Fatalf("expected handle key to be deleted, got %q", v)
+			}
+			if v := buckets.titles.Get([]byte("Foo")); v != nil {
+				t.Fatalf("expected title key to be deleted, got %q", v)
+			}
+			// The same handle and title can now be used by another product.
+			p = goshopify.Product{ID: 456, Handle: "foo", Title: "Foo"}
+			if err := buckets.Update(p); err != nil {
+				t.Fatal(err)
+			}
+			return nil
+		})
+	})
+
+	t.Run("ignores products that don't exist", func(t *testing.T) {
+		db := newTestDB(t)
+		db.Update(func(tx *bolt.Tx) error {
+			buckets, err := NewProductBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := buckets.Delete(123); err != nil {
+				t.Fatal(err)
+			}
+			return nil
+		})
+	})
+}
+
+func TestProductBuckets_variants(t *testing.T) {
+	shirt := goshopify.Product{ID: 1, Title: "Shirt", Variants: []goshopify.Variant{
+		{ID: 11, ProductID: 1, Sku: "SHIRT-S", Barcode: "111", InventoryItemId: 21},
+		{ID: 12, ProductID: 1, Sku: "SHIRT-M", InventoryItemId: 22},
+	}}
+	hat := goshopify.Product{ID: 2, Title: "Hat", Variants: []goshopify.Variant{
+		{ID: 13, ProductID: 2, Sku: "HAT", InventoryItemId: 23},
+	}}
+
+	t.Run("looks up variants", func(t *testing.T) {
+		db := newTestDB(t)
+		db.Update(func(tx *bolt.Tx) error {
+			buckets, err := NewProductBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := buckets.Update(shirt, hat); err != nil {
+				t.Fatal(err)
+			}
+			lookups := map[string]func() (*goshopify.Variant, error){
+				"ID":             func() (*goshopify.Variant, error) { return buckets.GetVariantByID(12) },
+				"SKU":            func() (*goshopify.Variant, error) { return buckets.GetVariantBySKU("SHIRT-M") },
+				"barcode":        func() (*goshopify.Variant, error) { return buckets.GetVariantByBarcode("111") },
+				"inventory item": func() (*goshopify.Variant, error) { return buckets.GetVariantByInventoryItemID(23) },
+			}
+			want := map[string]int64{"ID": 12, "SKU": 12, "barcode": 11, "inventory item": 13}
+			for name, lookup := range lookups {
+				v, err := lookup()
+				if err != nil {
+					t.Fatalf("by %v: %v", name, err)
+				}
+				if v.ID != want[name] {
+					t.Errorf("by %v: got variant %v, want %v", name, v.ID, want[name])
+				}
+			}
+			if _, err := buckets.GetVariantBySKU("SOCKS"); !errors.Is(err, ErrNotExist) {
+				t.Fatalf("got %v, want %v", err, ErrNotExist)
+			}
+			return nil
+		})
+	})
+
+	t.Run("moves keys between updated products", func(t *testing.T) {
+		db := newTestDB(t)
+		db.Update(func(tx *bolt.Tx) error {
+			buckets, err := NewProductBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := buckets.Update(shirt, hat); err != nil {
+				t.Fatal(err)
+			}
+			// SHIRT-M is deleted and its SKU is reused by the hat, which is
+			// updated first.
+			newHat := hat
+			newHat.Variants = []goshopify.Variant{{ID: 13, ProductID: 2, Sku: "SHIRT-M", InventoryItemId: 23}}
+			newShirt := shirt
+			newShirt.Variants = shirt.Variants[:1]
+			if err := buckets.Update(newHat, newShirt); err != nil {
+				t.Fatal(err)
+			}
+			v, err := buckets.GetVariantBySKU("SHIRT-M")
+			if err != nil || v.ID != 13 {
+				t.Fatalf("got variant %+v and error %v", v, err)
+			}
+			if _, err := buckets.GetVariantBySKU("HAT"); !errors.Is(err, ErrNotExist) {
+				t.Fatalf("got %v, want %v", err, ErrNotExist)
+			}
+			if _, err := buckets.GetVariantByID(12); !errors.Is(err, ErrNotExist) {
+				t.Fatalf("got %v, want %v", err, ErrNotExist)
+			}
+			return nil
+		})
+	})
+
+	t.Run("removes keys of deleted products", func(t *testing.T) {
+		db := newTestDB(t)
+		db.Update(func(tx *bolt.Tx) error {
+			buckets, err := NewProductBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := buckets.Update(shirt); err != nil {
+				t.Fatal(err)
+			}
+			if err := buckets.Delete(shirt.ID); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := buckets.GetVariantByBarcode("111"); !errors.Is(err, ErrNotExist) {
+				t.Fatalf("got %v, want %v", err, ErrNotExist)
+			}
+			if v := buckets.items.Get(int64ToBytes(21)); v != nil {
+				t.Fatalf("expected inventory item key to be deleted, got %q", v)
+			}
+			return nil
+		})
+	})
+
+	t.Run("tolerates duplicate SKUs and barcodes", func(t *testing.T) {
+		db := newTestDB(t)
+		db.Update(func(tx *bolt.Tx) error {
+			buckets, err := NewProductBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			hat := goshopify.Product{ID: 2, Title: "Hat", Variants: []goshopify.Variant{{ID: 13, Sku: "HAT", Barcode: "999"}}}
+			duplicate := goshopify.Product{ID: 3, Title: "Cap", Variants: []goshopify.Variant{{ID: 14, Sku: "HAT", Barcode: "999"}}}
+			if err := buckets.Update(hat, duplicate); err != nil {
+				t.Fatal(err)
+			}
+			if v, err := buckets.GetVariantBySKU("HAT"); err != nil || v.ID != 13 {
+				t.Fatalf("got variant %+v and error %v, want variant 13", v, err)
+			}
+			// The SKU still finds the duplicate once the first variant is gone
+			// or has another SKU.
+			if err := buckets.Delete(2); err != nil {
+				t.Fatal(err)
+			}
+			if v, err := buckets.GetVariantBySKU("HAT"); err != nil || v.ID != 14 {
+				t.Fatalf("got variant %+v and error %v, want variant 14", v, err)
+			}
+			duplicate.Variants[0].Sku = "CAP"
+			if err := buckets.Update(duplicate); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := buckets.GetVariantBySKU("HAT"); !errors.Is(err, ErrNotExist) {
+				t.Fatalf("got %v, want %v", err, ErrNotExist)
+			}
+			if v, err := buckets.GetVariantByBarcode("999"); err != nil || v.ID != 14 {
+				t.Fatalf("got variant %+v and error %v, want variant 14", v, err)
+			}
+			return nil
+		})
+	})
+}
+
+func TestProductBuckets_IDs(t *testing.T) {
+	db := newTestDB(t)
+	db.Update(func(tx *bolt.Tx) error {
+		buckets, err := NewProductBuckets(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := buckets.Update(goshopify.Product{ID: 1}, goshopify.Product{ID: 2}); err != nil {
+			t.Fatal(err)
+		}
+		got, err := buckets.IDs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []int64{1, 2}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		return nil
+	})
+}
+
+func TestProductBuckets_ClonedAt(t *testing.T) {
+	t.Run("returns zero time if never set", func(t *testing.T) {
+		db := newTestDB(t)
+		db.View(func(tx *bolt.Tx) error {
+			buckets, err := NewProductBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := buckets.ClonedAt()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.IsZero() {
+				t.Fatalf("got %v, want zero time", got)
+			}
+			return nil
+		})
+	})
+
+	t.Run("returns time that was set", func(t *testing.T) {
+		want := time.Date(2023, 1, 2, 3, 4, 5, 6, time.UTC)
+		db := newTestDB(t)
+		db.Update(func(tx *bolt.Tx) error {
+			buckets, err := NewProductBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return buckets.SetClonedAt(want)
+		})
+		db.View(func(tx *bolt.Tx) error {
+			buckets, err := NewProductBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := buckets.ClonedAt()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(want) {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+			return nil
+		})
+	})
+}
+
+func TestProductBuckets_ImageAlts(t *testing.T) {
+	db := newTestDB(t)
+	for _, alts := range []map[int64]string{{1: "Front", 2: "Back"}, {2: "Side"}} {
+		alts := alts
+		db.Update(func(tx *bolt.Tx) error {
+			buckets, err := NewProductBuckets(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return buckets.SetImageAlts(alts)
+		})
+	}
+	db.View(func(tx *bolt.Tx) error {
+		buckets, err := NewProductBuckets(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := buckets.ImageAlts()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The second call replaces the alt texts of the first one.
+		if want := map[int64]string{2: "Side"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		return nil
+	})
 }
 
 func Test_setOnce(t *testing.T) {
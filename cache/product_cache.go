@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"time"
+
 	goshopify "github.com/bold-commerce/go-shopify/v3"
 	bolt "go.etcd.io/bbolt"
 )
@@ -10,7 +12,18 @@ type ProductCache interface {
 	GetByID(id int64) (*goshopify.Product, error)
 	GetByTitle(title string) (*goshopify.Product, error)
 	GetByHandle(handle string) (*goshopify.Product, error)
+	GetVariantByID(id int64) (*goshopify.Variant, error)
+	GetVariantBySKU(sku string) (*goshopify.Variant, error)
+	GetVariantByBarcode(barcode string) (*goshopify.Variant, error)
+	GetVariantByInventoryItemID(id int64) (*goshopify.Variant, error)
 	List() ([]goshopify.Product, error)
+	Find(q ProductQuery) ([]goshopify.Product, error)
+	Delete(ids ...int64) error
+	IDs() ([]int64, error)
+	ClonedAt() (time.Time, error)
+	SetClonedAt(t time.Time) error
+	SetImageAlts(alts map[int64]string) error
+	ImageAlts() (map[int64]string, error)
 }
 
 func NewProductCache(o DBOpener) ProductCache {
@@ -51,6 +64,46 @@ func (cache *productCache) GetByHandle(handle string) (p *goshopify.Product, err
 	return p, err
 }
 
+func (cache *productCache) Find(q ProductQuery) (products []goshopify.Product, err error) {
+	err = cache.view(func(buckets *ProductBuckets) error {
+		products, err = buckets.Find(q)
+		return err
+	})
+	return products, err
+}
+
+func (cache *productCache) GetVariantByID(id int64) (v *goshopify.Variant, err error) {
+	err = cache.view(func(buckets *ProductBuckets) error {
+		v, err = buckets.GetVariantByID(id)
+		return err
+	})
+	return v, err
+}
+
+func (cache *productCache) GetVariantBySKU(sku string) (v *goshopify.Variant, err error) {
+	err = cache.view(func(buckets *ProductBuckets) error {
+		v, err = buckets.GetVariantBySKU(sku)
+		return err
+	})
+	return v, err
+}
+
+func (cache *productCache) GetVariantByBarcode(barcode string) (v *goshopify.Variant, err error) {
+	err = cache.view(func(buckets *ProductBuckets) error {
+		v, err = buckets.GetVariantByBarcode(barcode)
+		return err
+	})
+	return v, err
+}
+
+func (cache *productCache) GetVariantByInventoryItemID(id int64) (v *goshopify.Variant, err error) {
+	err = cache.view(func(buckets *ProductBuckets) error {
+		v, err = buckets.GetVariantByInventoryItemID(id)
+		return err
+	})
+	return v, err
+}
+
 func (cache *productCache) List() (p []goshopify.Product, err error) {
 	err = cache.view(func(buckets *ProductBuckets) error {
 		p, err = buckets.List()
@@ -59,6 +112,48 @@ func (cache *productCache) List() (p []goshopify.Product, err error) {
 	return p, err
 }
 
+func (cache *productCache) Delete(ids ...int64) error {
+	return cache.update(func(b *ProductBuckets) error {
+		return b.Delete(ids...)
+	})
+}
+
+func (cache *productCache) IDs() (ids []int64, err error) {
+	err = cache.view(func(buckets *ProductBuckets) error {
+		ids, err = buckets.IDs()
+		return err
+	})
+	return ids, err
+}
+
+func (cache *productCache) ClonedAt() (t time.Time, err error) {
+	err = cache.view(func(buckets *ProductBuckets) error {
+		t, err = buckets.ClonedAt()
+		return err
+	})
+	return t, err
+}
+
+func (cache *productCache) SetClonedAt(t time.Time) error {
+	return cache.update(func(b *ProductBuckets) error {
+		return b.SetClonedAt(t)
+	})
+}
+
+func (cache *productCache) SetImageAlts(alts map[int64]string) error {
+	return cache.update(func(b *ProductBuckets) error {
+		return b.SetImageAlts(alts)
+	})
+}
+
+func (cache *productCache) ImageAlts() (alts map[int64]string, err error) {
+	err = cache.view(func(buckets *ProductBuckets) error {
+		alts, err = buckets.ImageAlts()
+		return err
+	})
+	return alts, err
+}
+
 func (cache *productCache) view(fn func(b *ProductBuckets) error) error {
 	db, err := cache.dbOpener.Open()
 	if err != nil {
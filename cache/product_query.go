@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"strings"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/samherrmann/merchant/memdb"
+	"github.com/shopspring/decimal"
+)
+
+// ProductQuery selects products and variants. Fields that are not set match
+// everything. Text is compared case-insensitively.
+type ProductQuery struct {
+	Vendor      string
+	ProductType string
+	// Tag selects products that have the tag.
+	Tag string
+	// MinPrice and MaxPrice are the inclusive bounds of the price of variants.
+	MinPrice *decimal.Decimal
+	MaxPrice *decimal.Decimal
+	// SKUPrefix selects variants whose SKU starts with the prefix. It is case
+	// sensitive like SKUs in the store.
+	SKUPrefix string
+	// MissingBarcode selects variants without a barcode.
+	MissingBarcode bool
+	// Metafield selects products and variants that have a metafield with the
+	// namespace, key and value of the query. A product that has the metafield
+	// is selected with all its variants.
+	Metafield *MetafieldQuery
+}
+
+// MetafieldQuery selects the resources that have a metafield with the given
+// namespace, key and value. The value is compared by the type of the
+// metafield, see memdb.EqualMetafieldValues.
+type MetafieldQuery struct {
+	Namespace string
+	Key       string
+	Value     string
+}
+
+// Filter returns p with only the variants that match q. false is returned if
+// p itself or none of its variants match q.
+func (q *ProductQuery) Filter(p goshopify.Product) (goshopify.Product, bool) {
+	if !q.matchProduct(&p) {
+		return p, false
+	}
+	productMetafield := q.Metafield == nil || hasMetafield(p.Metafields, q.Metafield)
+	variants := []goshopify.Variant{}
+	for _, v := range p.Variants {
+		if !q.matchVariant(&v) {
+			continue
+		}
+		if !productMetafield && !hasMetafield(v.Metafields, q.Metafield) {
+			continue
+		}
+		variants = append(variants, v)
+	}
+	if len(variants) == 0 {
+		return p, false
+	}
+	p.Variants = variants
+	return p, true
+}
+
+func (q *ProductQuery) matchProduct(p *goshopify.Product) bool {
+	if q.Vendor != "" && !strings.EqualFold(p.Vendor, q.Vendor) {
+		return false
+	}
+	if q.ProductType != "" && !strings.EqualFold(p.ProductType, q.ProductType) {
+		return false
+	}
+	if q.Tag != "" {
+		found := false
+		for _, t := range memdb.SplitTags(p.Tags) {
+			if strings.EqualFold(t, q.Tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *ProductQuery) matchVariant(v *goshopify.Variant) bool {
+	if q.MinPrice != nil || q.MaxPrice != nil {
+		if v.Price == nil {
+			return false
+		}
+		if q.MinPrice != nil && v.Price.LessThan(*q.MinPrice) {
+			return false
+		}
+		if q.MaxPrice != nil && v.Price.GreaterThan(*q.MaxPrice) {
+			return false
+		}
+	}
+	if !strings.HasPrefix(v.Sku, q.SKUPrefix) {
+		return false
+	}
+	if q.MissingBarcode && v.Barcode != "" {
+		return false
+	}
+	return true
+}
+
+func hasMetafield(metafields []goshopify.Metafield, q *MetafieldQuery) bool {
+	for _, m := range metafields {
+		if m.Namespace == q.Namespace && m.Key == q.Key && memdb.EqualMetafieldValues(m.Type, m.Value, q.Value) {
+			return true
+		}
+	}
+	return false
+}
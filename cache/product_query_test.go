@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/shopspring/decimal"
+)
+
+func TestProductQuery_Filter(t *testing.T) {
+	price := func(s string) *decimal.Decimal {
+		d := decimal.RequireFromString(s)
+		return &d
+	}
+	shirt := goshopify.Product{
+		ID:          1,
+		Vendor:      "Acme",
+		ProductType: "Shirts",
+		Tags:        "summer, Cotton",
+		Metafields: []goshopify.Metafield{
+			{Namespace: "custom", Key: "fabric", Value: "cotton"},
+		},
+		Variants: []goshopify.Variant{
+			{ID: 11, Sku: "SHIRT-S", Barcode: "111", Price: price("10")},
+			{ID: 12, Sku: "SHIRT-M", Price: price("12.50")},
+			{ID: 13, Sku: "TEE-L", Price: price("15"), Metafields: []goshopify.Metafield{
+				{Namespace: "custom", Key: "fit", Value: "loose"},
+				{Namespace: "custom", Key: "pack_size", Value: float64(1000000), Type: "number_integer"},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		query ProductQuery
+		want  []int64
+	}{
+		{name: "should match all variants of empty query", query: ProductQuery{}, want: []int64{11, 12, 13}},
+		{name: "should match vendor case-insensitively", query: ProductQuery{Vendor: "acme"}, want: []int64{11, 12, 13}},
+		{name: "should not match other vendor", query: ProductQuery{Vendor: "Globex"}, want: nil},
+		{name: "should match product type", query: ProductQuery{ProductType: "shirts"}, want: []int64{11, 12, 13}},
+		{name: "should match tag", query: ProductQuery{Tag: "cotton"}, want: []int64{11, 12, 13}},
+		{name: "should not match part of tag", query: ProductQuery{Tag: "cot"}, want: nil},
+		{name: "should match inclusive price range", query: ProductQuery{MinPrice: price("10"), MaxPrice: price("12.5")}, want: []int64{11, 12}},
+		{name: "should match minimum price", query: ProductQuery{MinPrice: price("12.51")}, want: []int64{13}},
+		{name: "should match SKU prefix", query: ProductQuery{SKUPrefix: "SHIRT-"}, want: []int64{11, 12}},
+		{name: "should match missing barcode", query: ProductQuery{MissingBarcode: true}, want: []int64{12, 13}},
+		{name: "should match product metafield", query: ProductQuery{Metafield: &MetafieldQuery{Namespace: "custom", Key: "fabric", Value: "cotton"}}, want: []int64{11, 12, 13}},
+		{name: "should match variant metafield", query: ProductQuery{Metafield: &MetafieldQuery{Namespace: "custom", Key: "fit", Value: "loose"}}, want: []int64{13}},
+		{name: "should match numeric metafield", query: ProductQuery{Metafield: &MetafieldQuery{Namespace: "custom", Key: "pack_size", Value: "1000000"}}, want: []int64{13}},
+		{name: "should not match other metafield value", query: ProductQuery{Metafield: &MetafieldQuery{Namespace: "custom", Key: "fabric", Value: "linen"}}, want: nil},
+		{name: "should combine filters", query: ProductQuery{Vendor: "Acme", SKUPrefix: "SHIRT", MissingBarcode: true}, want: []int64{12}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := tt.query.Filter(shirt)
+			if ok != (tt.want != nil) {
+				t.Fatalf("got match %v, want %v", ok, tt.want != nil)
+			}
+			if !ok {
+				return
+			}
+			var got []int64
+			for _, v := range p.Variants {
+				got = append(got, v.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got variants %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/samherrmann/merchant/cache/bkeys"
+	bolt "go.etcd.io/bbolt"
+)
+
+func NewStockBuckets(tx *bolt.Tx) (*StockBuckets, error) {
+	return &StockBuckets{
+		tx:     tx,
+		levels: tx.Bucket([]byte(bkeys.InventoryLevels)),
+		items:  tx.Bucket([]byte(bkeys.InventoryItems)),
+	}, nil
+}
+
+// StockBuckets is a collection of Bolt Buckets to store the inventory items of
+// a store and their inventory levels at the locations of the store.
+type StockBuckets struct {
+	tx     *bolt.Tx
+	levels *bolt.Bucket
+	items  *bolt.Bucket
+}
+
+// SetLevels replaces all inventory levels with the given levels.
+func (b *StockBuckets) SetLevels(levels ...goshopify.InventoryLevel) error {
+	if err := b.deleteBucket(bkeys.InventoryLevels); err != nil {
+		return err
+	}
+	b.levels = nil
+	return b.UpdateLevels(levels...)
+}
+
+// UpdateLevels inserts the given inventory levels or replaces the levels of
+// the same inventory items at the same locations.
+func (b *StockBuckets) UpdateLevels(levels ...goshopify.InventoryLevel) error {
+	var err error
+	b.levels, err = b.tx.CreateBucketIfNotExists([]byte(bkeys.InventoryLevels))
+	if err != nil {
+		return err
+	}
+	for _, l := range levels {
+		v, err := json.Marshal(l)
+		if err != nil {
+			return err
+		}
+		if err := b.levels.Put(levelKey(l.InventoryItemId, l.LocationId), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Levels returns all inventory levels.
+func (b *StockBuckets) Levels() ([]goshopify.InventoryLevel, error) {
+	levels := []goshopify.InventoryLevel{}
+	if b.levels == nil {
+		return levels, nil
+	}
+	err := b.levels.ForEach(func(k, v []byte) error {
+		l := goshopify.InventoryLevel{}
+		if err := json.Unmarshal(v, &l); err != nil {
+			return err
+		}
+		levels = append(levels, l)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
+
+// DeleteLevels removes the inventory levels of the inventory items with the
+// given IDs at all locations. IDs that don't exist are ignored.
+func (b *StockBuckets) DeleteLevels(itemIDs ...int64) error {
+	if b.levels == nil {
+		return nil
+	}
+	for _, id := range itemIDs {
+		prefix := append(int64ToBytes(id), '/')
+		keys := [][]byte{}
+		c := b.levels.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			keys = append(keys, copyBytes(k))
+		}
+		for _, k := range keys {
+			if err := b.levels.Delete(k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetItems replaces all inventory items with the given items.
+func (b *StockBuckets) SetItems(items ...goshopify.InventoryItem) error {
+	if err := b.deleteBucket(bkeys.InventoryItems); err != nil {
+		return err
+	}
+	b.items = nil
+	return b.UpdateItems(items...)
+}
+
+// UpdateItems inserts the given inventory items or replaces the items with the
+// same IDs.
+func (b *StockBuckets) UpdateItems(items ...goshopify.InventoryItem) error {
+	var err error
+	b.items, err = b.tx.CreateBucketIfNotExists([]byte(bkeys.InventoryItems))
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		v, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if err := b.items.Put(int64ToBytes(item.ID), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Items returns all inventory items ordered by ID.
+func (b *StockBuckets) Items() ([]goshopify.InventoryItem, error) {
+	items := []goshopify.InventoryItem{}
+	if b.items == nil {
+		return items, nil
+	}
+	err := b.items.ForEach(func(k, v []byte) error {
+		item := goshopify.InventoryItem{}
+		if err := json.Unmarshal(v, &item); err != nil {
+			return err
+		}
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ID < items[j].ID
+	})
+	return items, nil
+}
+
+// DeleteItems removes the inventory items with the given IDs. IDs that don't
+// exist are ignored.
+func (b *StockBuckets) DeleteItems(ids ...int64) error {
+	if b.items == nil {
+		return nil
+	}
+	for _, id := range ids {
+		if err := b.items.Delete(int64ToBytes(id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *StockBuckets) deleteBucket(name string) error {
+	err := b.tx.DeleteBucket([]byte(name))
+	if err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+		return err
+	}
+	return nil
+}
+
+// levelKey returns the key of the inventory level of an inventory item at a
+// location, e.g. "<item ID>/<location ID>".
+func levelKey(itemID int64, locationID int64) []byte {
+	return []byte(fmt.Sprintf("%v/%v", itemID, locationID))
+}
@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/shopspring/decimal"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestStockBuckets_Levels(t *testing.T) {
+	tests := []struct {
+		name   string
+		update func(b *StockBuckets) error
+		want   []goshopify.InventoryLevel
+	}{
+		{
+			name: "updates levels",
+			update: func(b *StockBuckets) error {
+				return b.UpdateLevels(goshopify.InventoryLevel{InventoryItemId: 1, LocationId: 2, Available: 7})
+			},
+			want: []goshopify.InventoryLevel{
+				{InventoryItemId: 1, LocationId: 2, Available: 7},
+				{InventoryItemId: 1, LocationId: 3, Available: 6},
+				{InventoryItemId: 11, LocationId: 2, Available: 3},
+			},
+		},
+		{
+			name: "replaces levels",
+			update: func(b *StockBuckets) error {
+				return b.SetLevels(goshopify.InventoryLevel{InventoryItemId: 5, LocationId: 2, Available: 1})
+			},
+			want: []goshopify.InventoryLevel{
+				{InventoryItemId: 5, LocationId: 2, Available: 1},
+			},
+		},
+		{
+			name: "deletes levels of item at all locations",
+			update: func(b *StockBuckets) error {
+				return b.DeleteLevels(1, 99)
+			},
+			want: []goshopify.InventoryLevel{
+				{InventoryItemId: 11, LocationId: 2, Available: 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			db.Update(func(tx *bolt.Tx) error {
+				buckets, err := NewStockBuckets(tx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = buckets.SetLevels(
+					goshopify.InventoryLevel{InventoryItemId: 1, LocationId: 2, Available: 5},
+					goshopify.InventoryLevel{InventoryItemId: 1, LocationId: 3, Available: 6},
+					goshopify.InventoryLevel{InventoryItemId: 11, LocationId: 2, Available: 3},
+				)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := tt.update(buckets); err != nil {
+					t.Fatal(err)
+				}
+				return nil
+			})
+			db.View(func(tx *bolt.Tx) error {
+				buckets, err := NewStockBuckets(tx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := buckets.Levels()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("got %+v, want %+v", got, tt.want)
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestStockBuckets_Items(t *testing.T) {
+	cost := decimal.NewFromInt(4)
+	db := newTestDB(t)
+	db.Update(func(tx *bolt.Tx) error {
+		buckets, err := NewStockBuckets(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := buckets.SetItems(goshopify.InventoryItem{ID: 10}, goshopify.InventoryItem{ID: 3}); err != nil {
+			t.Fatal(err)
+		}
+		if err := buckets.UpdateItems(goshopify.InventoryItem{ID: 9, Cost: &cost}); err != nil {
+			t.Fatal(err)
+		}
+		if err := buckets.DeleteItems(3, 99); err != nil {
+			t.Fatal(err)
+		}
+		return nil
+	})
+	db.View(func(tx *bolt.Tx) error {
+		buckets, err := NewStockBuckets(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := buckets.Items()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 || got[0].ID != 9 || !got[0].Cost.Equal(cost) || got[1].ID != 10 {
+			t.Fatalf("got %+v", got)
+		}
+		return nil
+	})
+}
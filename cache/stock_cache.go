@@ -0,0 +1,108 @@
+package cache
+
+import (
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	bolt "go.etcd.io/bbolt"
+)
+
+// StockCache stores the inventory items of a store and their inventory levels.
+type StockCache interface {
+	SetLevels(levels ...goshopify.InventoryLevel) error
+	UpdateLevels(levels ...goshopify.InventoryLevel) error
+	Levels() ([]goshopify.InventoryLevel, error)
+	DeleteLevels(itemIDs ...int64) error
+	SetItems(items ...goshopify.InventoryItem) error
+	UpdateItems(items ...goshopify.InventoryItem) error
+	Items() ([]goshopify.InventoryItem, error)
+	DeleteItems(ids ...int64) error
+}
+
+func NewStockCache(o DBOpener) StockCache {
+	return &stockCache{dbOpener: o}
+}
+
+type stockCache struct {
+	dbOpener DBOpener
+}
+
+func (cache *stockCache) SetLevels(levels ...goshopify.InventoryLevel) error {
+	return cache.update(func(b *StockBuckets) error {
+		return b.SetLevels(levels...)
+	})
+}
+
+func (cache *stockCache) UpdateLevels(levels ...goshopify.InventoryLevel) error {
+	return cache.update(func(b *StockBuckets) error {
+		return b.UpdateLevels(levels...)
+	})
+}
+
+func (cache *stockCache) Levels() (levels []goshopify.InventoryLevel, err error) {
+	err = cache.view(func(b *StockBuckets) error {
+		levels, err = b.Levels()
+		return err
+	})
+	return levels, err
+}
+
+func (cache *stockCache) DeleteLevels(itemIDs ...int64) error {
+	return cache.update(func(b *StockBuckets) error {
+		return b.DeleteLevels(itemIDs...)
+	})
+}
+
+func (cache *stockCache) SetItems(items ...goshopify.InventoryItem) error {
+	return cache.update(func(b *StockBuckets) error {
+		return b.SetItems(items...)
+	})
+}
+
+func (cache *stockCache) UpdateItems(items ...goshopify.InventoryItem) error {
+	return cache.update(func(b *StockBuckets) error {
+		return b.UpdateItems(items...)
+	})
+}
+
+func (cache *stockCache) Items() (items []goshopify.InventoryItem, err error) {
+	err = cache.view(func(b *StockBuckets) error {
+		items, err = b.Items()
+		return err
+	})
+	return items, err
+}
+
+func (cache *stockCache) DeleteItems(ids ...int64) error {
+	return cache.update(func(b *StockBuckets) error {
+		return b.DeleteItems(ids...)
+	})
+}
+
+func (cache *stockCache) view(fn func(b *StockBuckets) error) error {
+	db, err := cache.dbOpener.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(func(tx *bolt.Tx) error {
+		buckets, err := NewStockBuckets(tx)
+		if err != nil {
+			return err
+		}
+		return fn(buckets)
+	})
+}
+
+func (cache *stockCache) update(fn func(b *StockBuckets) error) error {
+	db, err := cache.dbOpener.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(func(tx *bolt.Tx) error {
+		buckets, err := NewStockBuckets(tx)
+		if err != nil {
+			return err
+		}
+		return fn(buckets)
+	})
+}
@@ -13,7 +13,12 @@ func newCacheClearCommand() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Command usage is correct at this point.
 			cmd.SilenceUsage = true
-			return cache.Clear()
+
+			_, storeCfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			return cache.Clear(storeCfg.Name)
 		},
 	}
 }
@@ -18,7 +18,11 @@ func newCacheDumpCommand() *cobra.Command {
 			// Command usage is correct at this point.
 			cmd.SilenceUsage = true
 
-			c, err := cache.New()
+			_, storeCfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			c, err := cache.New(storeCfg.Name)
 			if err != nil {
 				return fmt.Errorf("cache: %w", err)
 			}
@@ -33,7 +37,7 @@ func newCacheDumpCommand() *cobra.Command {
 				return fmt.Errorf("json marshal products: %w", err)
 			}
 
-			filename := fmt.Sprintf("%s.cache.products.json", cache.AppName)
+			filename := fmt.Sprintf("%s.%s.cache.products.json", cache.AppName, storeCfg.Name)
 
 			if err := os.WriteFile(filename, b, 0644); err != nil {
 				return fmt.Errorf("writing cache to file: %w", err)
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	units "github.com/docker/go-units"
+	"github.com/samherrmann/merchant/cache"
+	"github.com/spf13/cobra"
+)
+
+func newCacheInfoCommand(out io.Writer) *cobra.Command {
+	return &cobra.Command{
+		Use:   "info",
+		Short: "Prints the schema version, store, clone time and size of the cache",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Command usage is correct at this point.
+			cmd.SilenceUsage = true
+
+			_, storeCfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			c, err := cache.New(storeCfg.Name)
+			if err != nil {
+				return fmt.Errorf("cache: %w", err)
+			}
+			info, err := c.Meta().Info()
+			if err != nil {
+				return err
+			}
+			size, err := cache.Size(storeCfg.Name)
+			if err != nil {
+				return err
+			}
+			clonedAt := "never"
+			if !info.ClonedAt.IsZero() {
+				clonedAt = info.ClonedAt.Local().Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "Schema Version: %v\n", info.SchemaVersion)
+			fmt.Fprintf(out, "Store:          %v\n", info.Store)
+			fmt.Fprintf(out, "Cloned At:      %v\n", clonedAt)
+			fmt.Fprintf(out, "Size:           %v\n", units.HumanSize(float64(size)))
+			return nil
+		},
+	}
+}
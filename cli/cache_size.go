@@ -18,7 +18,11 @@ func newCacheSizeCommand(out io.Writer) *cobra.Command {
 			// Command usage is correct at this point.
 			cmd.SilenceUsage = true
 
-			size, err := cache.Size()
+			_, storeCfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			size, err := cache.Size(storeCfg.Name)
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,823 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/samherrmann/merchant/cache"
+	"github.com/samherrmann/merchant/config"
+	"github.com/samherrmann/merchant/memdb"
+	"github.com/samherrmann/merchant/shopify"
+	"github.com/samherrmann/merchant/shopify/shopifytest"
+	"github.com/shopspring/decimal"
+	bolt "go.etcd.io/bbolt"
+)
+
+// testConfig is the configuration file of the tests.
+const testConfig = `{
+	"stores": [{"name": "test", "accessToken": "token"}],
+	"metafieldDefinitions": {
+		"product": [{"namespace": "custom", "key": "fabric", "type": "single_line_text_field"}]
+	}
+}`
+
+// setupStore starts a fake store that contains the given products and
+// prepares an environment in which the commands use it: a configuration file,
+// an empty cache and a working directory for the files that the commands
+// write.
+func setupStore(t *testing.T, products ...goshopify.Product) *shopifytest.Server {
+	t.Helper()
+	server := shopifytest.NewServer(shopifytest.WithAccessToken("token"))
+	t.Cleanup(server.Close)
+	server.AddProducts(products...)
+
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	configDir := filepath.Join(dir, "config", config.AppName)
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, config.AppName+".json"), []byte(testConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return server
+}
+
+// run executes the root command against server with the given arguments and
+// returns its output.
+func run(t *testing.T, server *shopifytest.Server, args ...string) string {
+	t.Helper()
+	out := &bytes.Buffer{}
+	cmd := newRootCommand(out, shopify.WithTransport(server.Transport()))
+	cmd.SetArgs(args)
+	cmd.SetErr(&bytes.Buffer{})
+	if err := cmd.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("%v: %v", strings.Join(args, " "), err)
+	}
+	return out.String()
+}
+
+// runErr executes the root command against server with the given arguments
+// and returns its error.
+func runErr(server *shopifytest.Server, args ...string) error {
+	cmd := newRootCommand(&bytes.Buffer{}, shopify.WithTransport(server.Transport()))
+	cmd.SetArgs(args)
+	cmd.SetErr(&bytes.Buffer{})
+	return cmd.ExecuteContext(context.Background())
+}
+
+// editCSV sets the column to value in the rows in which the key column equals
+// key.
+func editCSV(t *testing.T, filename string, keyColumn string, key string, column string, value string) {
+	t.Helper()
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(f).ReadAll()
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := rows[0]
+	col := -1
+	keyCol := -1
+	for i, name := range header {
+		switch name {
+		case column:
+			col = i
+		case keyColumn:
+			keyCol = i
+		}
+	}
+	if col < 0 || keyCol < 0 {
+		t.Fatalf("columns %q or %q not found in %v", column, keyColumn, header)
+	}
+	found := false
+	for _, row := range rows[1:] {
+		if row[keyCol] == key {
+			row[col] = value
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("%v %q not found", keyColumn, key)
+	}
+	f, err = os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if err := w.WriteAll(rows); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// appendCSV appends a row with the given values by column to the CSV file.
+// Columns that are not in values are left empty.
+func appendCSV(t *testing.T, filename string, values map[string]string) {
+	t.Helper()
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := csv.NewReader(f).Read()
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := make([]string, len(header))
+	for i, name := range header {
+		row[i] = values[name]
+		delete(values, name)
+	}
+	if len(values) > 0 {
+		t.Fatalf("columns %v not found in %v", values, header)
+	}
+	f, err = os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if err := w.WriteAll([][]string{row}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProductsWorkflow(t *testing.T) {
+	price := decimal.NewFromInt(10)
+	server := setupStore(t,
+		goshopify.Product{
+			Title:   "Shirt",
+			Options: []goshopify.ProductOption{{Name: "Size"}},
+			Variants: []goshopify.Variant{
+				{Option1: "S", Sku: "SHIRT-S", Price: &price},
+				{Option1: "M", Sku: "SHIRT-M", Price: &price},
+			},
+			Metafields: []goshopify.Metafield{
+				{Namespace: "custom", Key: "fabric", Value: "cotton", Type: "single_line_text_field"},
+			},
+		},
+		goshopify.Product{Title: "Hat", Variants: []goshopify.Variant{{Sku: "HAT", Price: &price}}},
+	)
+
+	run(t, server, "products", "clone")
+	run(t, server, "products", "checkout")
+	editCSV(t, "products.csv", "SKU", "SHIRT-S", "Price", "12.5")
+	editCSV(t, "products.csv", "Title", "Shirt", "product.metafields.custom.fabric", "linen")
+
+	summary := run(t, server, "products", "fake-push", "products.csv")
+	for _, want := range []string{
+		"Variant Updates:    1",
+		"Metafield Updates:  1",
+		"Unchanged Variants: 2",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("fake-push summary does not contain %q:\n%v", want, summary)
+		}
+	}
+
+	if out := run(t, server, "products", "push", "products.csv"); !strings.Contains(out, "completed") {
+		t.Fatalf("got push output %q", out)
+	}
+	shirt := server.Products()[0]
+	if got := shirt.Variants[0].Price; !got.Equal(decimal.NewFromFloat(12.5)) {
+		t.Errorf("got price %v, want 12.5", got)
+	}
+	if got := shirt.Metafields[0].Value; got != "linen" {
+		t.Errorf("got metafield value %v, want linen", got)
+	}
+
+	// The pushed changes are picked up by the next clone.
+	run(t, server, "products", "clone")
+	summary = run(t, server, "products", "fake-push", "--from-cache", "products.csv")
+	if !strings.Contains(summary, "Variant Updates:    0") {
+		t.Errorf("got fake-push summary after push:\n%v", summary)
+	}
+}
+
+func TestPlanWorkflow(t *testing.T) {
+	price := decimal.NewFromInt(10)
+	server := setupStore(t, goshopify.Product{Title: "Hat", Variants: []goshopify.Variant{{Sku: "HAT", Price: &price}}})
+
+	run(t, server, "products", "clone")
+	run(t, server, "products", "checkout")
+	editCSV(t, "products.csv", "SKU", "HAT", "Price", "12")
+	run(t, server, "products", "fake-push", "products.csv")
+
+	// A plan of another store is rejected.
+	planFilename := config.AppName + ".push.json"
+	b, err := os.ReadFile(planFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := map[string]any{}
+	if err := json.Unmarshal(b, &plan); err != nil {
+		t.Fatal(err)
+	}
+	if plan["Store"] != "test" {
+		t.Fatalf("got plan store %v, want test", plan["Store"])
+	}
+	plan["Store"] = "other"
+	other, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("other.json", other, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := runErr(server, "products", "push", "--plan", "other.json"); err == nil || !strings.Contains(err.Error(), `"other"`) {
+		t.Fatalf("got error %v, want store mismatch", err)
+	}
+
+	run(t, server, "products", "push", "--plan", planFilename)
+	if got := server.Products()[0].Variants[0].Price; !got.Equal(decimal.NewFromInt(12)) {
+		t.Errorf("got price %v, want 12", got)
+	}
+}
+
+func TestPlanInventoryDrift(t *testing.T) {
+	server := setupStore(t, goshopify.Product{Title: "Hat", Variants: []goshopify.Variant{{Sku: "HAT"}}})
+	locations := server.AddLocations("Warehouse")
+	item := server.Products()[0].Variants[0].InventoryItemId
+	server.SetInventoryLevel(item, locations[0].ID, 5)
+
+	run(t, server, "products", "clone")
+	run(t, server, "products", "checkout")
+	editCSV(t, "products.csv", "SKU", "HAT", "Inventory: Warehouse", "9")
+	run(t, server, "products", "fake-push", "products.csv")
+	// A sale in the store after the plan was made.
+	server.SetInventoryLevel(item, locations[0].ID, 4)
+
+	err := runErr(server, "products", "push", "--plan", config.AppName+".push.json")
+	if !errors.Is(err, shopify.ErrDrift) {
+		t.Fatalf("got error %v, want %v", err, shopify.ErrDrift)
+	}
+	if got := server.InventoryLevels()[0].Available; got != 4 {
+		t.Fatalf("got available quantity %v, want 4", got)
+	}
+	c, err := cache.New("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Journal().Current(); !errors.Is(err, cache.ErrNotExist) {
+		t.Fatalf("got error %v, want no push to be started", err)
+	}
+}
+
+func TestDiscardPush(t *testing.T) {
+	server := setupStore(t)
+
+	c, err := cache.New("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Journal().Begin("incomplete", []byte("{}"), []byte("[]")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runErr(server, "products", "push", "products.csv"); err != errIncompletePush {
+		t.Fatalf("got error %v, want %v", err, errIncompletePush)
+	}
+	run(t, server, "products", "push", "--discard")
+
+	c, err = cache.New("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Journal().Current(); err != cache.ErrNotExist {
+		t.Fatalf("got error %v, want %v", err, cache.ErrNotExist)
+	}
+	history, err := c.Journal().History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"incomplete"}; !reflect.DeepEqual(history, want) {
+		t.Fatalf("got history %v, want %v", history, want)
+	}
+}
+
+func TestBeginPush(t *testing.T) {
+	setupStore(t)
+
+	c, err := cache.New("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal := c.Journal()
+	// Pushes that start within the same second get distinct IDs.
+	ids := []string{}
+	for i := 0; i < 2; i++ {
+		id, err := beginPush(journal, &memdb.Operations{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := journal.End(nil); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	history, err := journal.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(history, ids) {
+		t.Fatalf("got history %v, want %v", history, ids)
+	}
+}
+
+func TestInventoryWorkflow(t *testing.T) {
+	server := setupStore(t, goshopify.Product{
+		Title:   "Shirt",
+		Options: []goshopify.ProductOption{{Name: "Size"}},
+		Variants: []goshopify.Variant{
+			{Option1: "S", Sku: "SHIRT-S"},
+			{Option1: "M", Sku: "SHIRT-M"},
+		},
+	})
+	locations := server.AddLocations("Warehouse", "Shop")
+	variants := server.Products()[0].Variants
+	server.SetInventoryLevel(variants[0].InventoryItemId, locations[0].ID, 10)
+	server.SetInventoryLevel(variants[1].InventoryItemId, locations[0].ID, 4)
+
+	run(t, server, "products", "clone")
+	run(t, server, "products", "checkout")
+	editCSV(t, "products.csv", "SKU", "SHIRT-S", "Inventory: Warehouse", "-3")
+	editCSV(t, "products.csv", "SKU", "SHIRT-M", "Inventory: Shop", "6")
+
+	summary := run(t, server, "products", "fake-push", "--from-cache", "products.csv")
+	if !strings.Contains(summary, "Inventory Updates:  2") {
+		t.Errorf("got fake-push summary:\n%v", summary)
+	}
+	run(t, server, "products", "push", "products.csv")
+
+	got := map[int64]map[int64]int{}
+	for _, l := range server.InventoryLevels() {
+		if got[l.InventoryItemId] == nil {
+			got[l.InventoryItemId] = map[int64]int{}
+		}
+		got[l.InventoryItemId][l.LocationId] = l.Available
+	}
+	want := map[int64]map[int64]int{
+		variants[0].InventoryItemId: {locations[0].ID: 7},
+		variants[1].InventoryItemId: {locations[0].ID: 4, locations[1].ID: 6},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got inventory levels %v, want %v", got, want)
+	}
+}
+
+func TestInventoryChangedSinceCheckout(t *testing.T) {
+	server := setupStore(t, goshopify.Product{
+		Title:   "Shirt",
+		Options: []goshopify.ProductOption{{Name: "Size"}},
+		Variants: []goshopify.Variant{
+			{Option1: "S", Sku: "SHIRT-S"},
+			{Option1: "M", Sku: "SHIRT-M"},
+		},
+	})
+	locations := server.AddLocations("Warehouse")
+	variants := server.Products()[0].Variants
+	server.SetInventoryLevel(variants[0].InventoryItemId, locations[0].ID, 10)
+	server.SetInventoryLevel(variants[1].InventoryItemId, locations[0].ID, 4)
+
+	run(t, server, "products", "clone")
+	run(t, server, "products", "checkout")
+	// A sale in the store after checkout.
+	server.SetInventoryLevel(variants[0].InventoryItemId, locations[0].ID, 8)
+	editCSV(t, "products.csv", "SKU", "SHIRT-M", "Inventory: Warehouse", "9")
+	run(t, server, "products", "push", "products.csv")
+
+	got := map[int64]int{}
+	for _, l := range server.InventoryLevels() {
+		got[l.InventoryItemId] = l.Available
+	}
+	want := map[int64]int{variants[0].InventoryItemId: 8, variants[1].InventoryItemId: 9}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got inventory levels %v, want %v", got, want)
+	}
+
+	editCSV(t, "products.csv", "SKU", "SHIRT-S", "Inventory: Warehouse", "12")
+	if err := runErr(server, "products", "push", "products.csv"); !errors.Is(err, memdb.ErrChanged) {
+		t.Fatalf("got error %v, want %v", err, memdb.ErrChanged)
+	}
+	// The cache can't tell that the level has changed, but the store can.
+	if err := runErr(server, "products", "push", "--from-cache", "products.csv"); !errors.Is(err, shopify.ErrDrift) {
+		t.Fatalf("got error %v, want %v", err, shopify.ErrDrift)
+	}
+	if got := server.InventoryLevels()[0].Available; got != 8 {
+		t.Fatalf("got available quantity %v, want 8", got)
+	}
+}
+
+func TestVariantFieldsWorkflow(t *testing.T) {
+	compareAt := decimal.NewFromInt(20)
+	server := setupStore(t, goshopify.Product{
+		Title:   "Shirt",
+		Options: []goshopify.ProductOption{{Name: "Size"}},
+		Variants: []goshopify.Variant{
+			{Option1: "S", Sku: "SHIRT-S", CompareAtPrice: &compareAt},
+			{Option1: "M", Sku: "SHIRT-M"},
+		},
+	})
+
+	run(t, server, "products", "clone")
+	run(t, server, "products", "checkout")
+	summary := run(t, server, "products", "fake-push", "--from-cache", "products.csv")
+	if !strings.Contains(summary, "Unchanged Variants: 2") {
+		t.Fatalf("got fake-push summary of unedited file:\n%v", summary)
+	}
+	editCSV(t, "products.csv", "SKU", "SHIRT-S", "Compare At Price", "")
+	editCSV(t, "products.csv", "SKU", "SHIRT-M", "Taxable", "false")
+	editCSV(t, "products.csv", "SKU", "SHIRT-M", "Cost per Item", "4.5")
+
+	summary = run(t, server, "products", "fake-push", "--from-cache", "products.csv")
+	for _, want := range []string{"Variant Updates:    0", "Cost Updates:       1", "Unchanged Variants: 0"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("fake-push summary does not contain %q:\n%v", want, summary)
+		}
+	}
+	run(t, server, "products", "push", "products.csv")
+
+	variants := server.Products()[0].Variants
+	if variants[0].CompareAtPrice != nil {
+		t.Errorf("got compare at price %v, want none", variants[0].CompareAtPrice)
+	}
+	if variants[1].Taxable || !variants[0].Taxable {
+		t.Errorf("got taxable %v and %v, want true and false", variants[0].Taxable, variants[1].Taxable)
+	}
+	cost := server.InventoryItems()[1].Cost
+	if cost == nil || !cost.Equal(decimal.NewFromFloat(4.5)) {
+		t.Errorf("got cost %v, want 4.5", cost)
+	}
+}
+
+func TestProductFieldsWorkflow(t *testing.T) {
+	server := setupStore(t, goshopify.Product{
+		Title:                    "Shirt",
+		Tags:                     "sale, cotton",
+		MetafieldsGlobalTitleTag: "Cotton shirt",
+	})
+
+	run(t, server, "products", "clone")
+	run(t, server, "products", "checkout")
+	summary := run(t, server, "products", "fake-push", "--from-cache", "products.csv")
+	if !strings.Contains(summary, "Unchanged Products: 1") {
+		t.Fatalf("got fake-push summary of unedited file:\n%v", summary)
+	}
+	editCSV(t, "products.csv", "Title", "Shirt", "Tags", "cotton,summer")
+	editCSV(t, "products.csv", "Title", "Shirt", "Status", "draft")
+	editCSV(t, "products.csv", "Title", "Shirt", "SEO Title", "Summer shirt")
+
+	summary = run(t, server, "products", "fake-push", "--from-cache", "products.csv")
+	for _, want := range []string{"Product Updates:    1", "Tag Updates:        1"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("fake-push summary does not contain %q:\n%v", want, summary)
+		}
+	}
+	run(t, server, "products", "push", "products.csv")
+
+	shirt := server.Products()[0]
+	if shirt.Tags != "cotton, summer" || shirt.Status != "draft" {
+		t.Errorf("got tags %q and status %q", shirt.Tags, shirt.Status)
+	}
+	if m := shirt.Metafields; len(m) != 1 || m[0].Value != "Summer shirt" {
+		t.Errorf("got metafields %+v, want SEO title", m)
+	}
+
+	// Empty cells clear the fields, including the last tags.
+	run(t, server, "products", "clone")
+	run(t, server, "products", "checkout")
+	editCSV(t, "products.csv", "Title", "Shirt", "Tags", "")
+	editCSV(t, "products.csv", "Title", "Shirt", "SEO Title", "")
+	run(t, server, "products", "push", "products.csv")
+
+	shirt = server.Products()[0]
+	if shirt.Tags != "" || len(shirt.Metafields) != 0 {
+		t.Errorf("got tags %q and metafields %+v, want none", shirt.Tags, shirt.Metafields)
+	}
+}
+
+func TestRollbackWorkflow(t *testing.T) {
+	price := decimal.NewFromInt(10)
+	server := setupStore(t, goshopify.Product{
+		Title:    "Shirt",
+		Variants: []goshopify.Variant{{Sku: "SHIRT", Price: &price}},
+	})
+
+	run(t, server, "products", "clone")
+	run(t, server, "products", "checkout")
+	editCSV(t, "products.csv", "SKU", "SHIRT", "Compare At Price", "20")
+	editCSV(t, "products.csv", "SKU", "SHIRT", "Barcode", "123")
+	editCSV(t, "products.csv", "SKU", "SHIRT", "Tags", "sale")
+	run(t, server, "products", "push", "products.csv")
+	first := strings.TrimSpace(run(t, server, "products", "rollback", "--list"))
+
+	// A later push changes the same product.
+	server.Advance(time.Second)
+	run(t, server, "products", "clone")
+	run(t, server, "products", "checkout")
+	editCSV(t, "products.csv", "SKU", "SHIRT", "Price", "12")
+	run(t, server, "products", "push", "products.csv")
+
+	if err := runErr(server, "products", "rollback", first); !errors.Is(err, shopify.ErrDrift) {
+		t.Fatalf("got error %v, want %v", err, shopify.ErrDrift)
+	}
+	run(t, server, "products", "rollback", "--force", first)
+
+	shirt := server.Products()[0]
+	v := shirt.Variants[0]
+	if shirt.Tags != "" || v.CompareAtPrice != nil || v.Barcode != "" || !v.Price.Equal(price) {
+		t.Errorf("got tags %q, compare at price %v, barcode %q and price %v, want the state before the first push",
+			shirt.Tags, v.CompareAtPrice, v.Barcode, v.Price)
+	}
+}
+
+func TestRenameWorkflow(t *testing.T) {
+	server := setupStore(t, goshopify.Product{Title: "Hat"}, goshopify.Product{Title: "Cap"})
+
+	run(t, server, "products", "clone")
+	run(t, server, "products", "checkout")
+	editCSV(t, "products.csv", "Handle", "hat", "Title", "Cap")
+	editCSV(t, "products.csv", "Handle", "cap", "Title", "Hat")
+	run(t, server, "products", "push", "products.csv")
+	// The swapped titles replace the old ones in the cache.
+	run(t, server, "products", "clone")
+
+	c, err := cache.New("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.Products().GetByTitle("Hat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Handle != "cap" {
+		t.Errorf("got product %q for title Hat, want cap", p.Handle)
+	}
+}
+
+func TestFindWorkflow(t *testing.T) {
+	price := decimal.NewFromInt(10)
+	server := setupStore(t,
+		goshopify.Product{
+			Title:       "Shirt",
+			Vendor:      "Acme",
+			ProductType: "Shirts",
+			Options:     []goshopify.ProductOption{{Name: "Size"}},
+			Variants: []goshopify.Variant{
+				{Option1: "S", Sku: "SHIRT-S", Barcode: "111", Price: &price},
+				{Option1: "M", Sku: "SHIRT-M", Price: &price},
+			},
+		},
+		goshopify.Product{Title: "Hat", Vendor: "Globex", Variants: []goshopify.Variant{{Sku: "HAT", Price: &price}}},
+	)
+	run(t, server, "products", "clone")
+
+	table := run(t, server, "products", "list")
+	for _, want := range []string{"SHIRT-S", "SHIRT-M", "HAT", "10.00"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("products list does not contain %q:\n%v", want, table)
+		}
+	}
+
+	out := run(t, server, "products", "find", "--vendor", "acme", "--missing-barcode", "--format", "csv")
+	rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1][5] != "SHIRT-M" {
+		t.Fatalf("got rows %v, want SHIRT-M only", rows)
+	}
+
+	out = run(t, server, "products", "find", "--sku-prefix", "HAT", "--format", "json")
+	products := []goshopify.Product{}
+	if err := json.Unmarshal([]byte(out), &products); err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != 1 || products[0].Title != "Hat" {
+		t.Fatalf("got products %+v, want Hat", products)
+	}
+}
+
+func TestImagesWorkflow(t *testing.T) {
+	server := setupStore(t, goshopify.Product{
+		Title:   "Shirt",
+		Options: []goshopify.ProductOption{{Name: "Size"}},
+		Variants: []goshopify.Variant{
+			{Option1: "S", Sku: "SHIRT-S"},
+			{Option1: "M", Sku: "SHIRT-M"},
+		},
+		Images: []goshopify.Image{{Src: "https://example.com/front.jpg"}},
+	})
+	shirt := server.Products()[0]
+	front := shirt.Images[0]
+
+	run(t, server, "products", "clone")
+	run(t, server, "products", "checkout")
+	summary := run(t, server, "products", "fake-push", "--from-cache", "products.csv")
+	if !strings.Contains(summary, "Unchanged Products: 1") {
+		t.Fatalf("got fake-push summary of unedited file:\n%v", summary)
+	}
+	if err := os.WriteFile("back.png", []byte("png"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	editCSV(t, "products.csv", "Image Src", front.Src, "Image Alt", "Front")
+	editCSV(t, "products.csv", "SKU", "SHIRT-M", "Variant Image", "back.png")
+	appendCSV(t, "products.csv", map[string]string{
+		"Product ID": fmt.Sprint(shirt.ID),
+		"Title":      "Shirt",
+		"Image Src":  "back.png",
+		"Image Alt":  "Back",
+	})
+
+	summary = run(t, server, "products", "fake-push", "--from-cache", "products.csv")
+	for _, want := range []string{"New Images:         1", "Image Updates:      1"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("fake-push summary does not contain %q:\n%v", want, summary)
+		}
+	}
+	run(t, server, "products", "push", "products.csv")
+
+	shirt = server.Products()[0]
+	if len(shirt.Images) != 2 {
+		t.Fatalf("got images %+v, want 2", shirt.Images)
+	}
+	back := shirt.Images[1]
+	if shirt.Variants[1].ImageID != back.ID || shirt.Variants[0].ImageID != 0 {
+		t.Errorf("got variant images %v and %v, want 0 and %v", shirt.Variants[0].ImageID, shirt.Variants[1].ImageID, back.ID)
+	}
+	want := map[int64]string{front.ID: "Front", back.ID: "Back"}
+	if got := server.ImageAlts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got alt texts %v, want %v", got, want)
+	}
+
+	// Pushing the same file again matches the uploaded image through the file
+	// that it was created from instead of uploading it again.
+	run(t, server, "products", "clone")
+	summary = run(t, server, "products", "fake-push", "--from-cache", "products.csv")
+	if !strings.Contains(summary, "Unchanged Products: 1") {
+		t.Errorf("got fake-push summary of pushed file:\n%v", summary)
+	}
+
+	// The next checkout contains the uploaded image, so pushing it again
+	// doesn't change anything.
+	run(t, server, "products", "checkout")
+	summary = run(t, server, "products", "fake-push", "--from-cache", "products.csv")
+	if !strings.Contains(summary, "Unchanged Products: 1") {
+		t.Errorf("got fake-push summary after push:\n%v", summary)
+	}
+}
+
+func TestCloneWorkflow(t *testing.T) {
+	server := setupStore(t, goshopify.Product{Title: "Shirt"})
+	server.AddLocations("Warehouse")
+	server.AddCustomCollections("Summer")
+	server.AddSmartCollections("New Arrivals")
+	server.AddMetafieldDefinitions(shopifytest.MetafieldDefinition{
+		OwnerType: "PRODUCT",
+		Namespace: "custom",
+		Key:       "fabric",
+		Name:      "Fabric",
+		Type:      "single_line_text_field",
+	})
+
+	run(t, server, "clone")
+
+	c, err := cache.New("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, err := c.Products().GetByTitle("Shirt"); err != nil {
+		t.Errorf("got product %+v and error %v", p, err)
+	}
+	if l, err := c.Locations().GetByName("Warehouse"); err != nil {
+		t.Errorf("got location %+v and error %v", l, err)
+	}
+	collections, err := c.Collections().List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collections) != 2 {
+		t.Errorf("got collections %+v, want 2", collections)
+	}
+	if col, err := c.Collections().GetByHandle("new-arrivals"); err != nil || col.Title != "New Arrivals" {
+		t.Errorf("got collection %+v and error %v", col, err)
+	}
+	d, err := c.MetafieldDefinitions().GetByKey("product", "custom", "fabric")
+	if err != nil || d.Type != "single_line_text_field" {
+		t.Errorf("got metafield definition %+v and error %v", d, err)
+	}
+
+	info := run(t, server, "cache", "info")
+	for _, want := range []string{
+		fmt.Sprintf("Schema Version: %v", cache.SchemaVersion),
+		"Store:          test",
+	} {
+		if !strings.Contains(info, want) {
+			t.Errorf("cache info does not contain %q:\n%v", want, info)
+		}
+	}
+	if strings.Contains(info, "Cloned At:      never") {
+		t.Errorf("got cache info without clone time:\n%v", info)
+	}
+}
+
+func TestMetafieldDefinitionsFromCache(t *testing.T) {
+	server := setupStore(t,
+		goshopify.Product{Title: "Shirt", Metafields: []goshopify.Metafield{
+			{Namespace: "custom", Key: "care", Value: "Hand wash", Type: "single_line_text_field"},
+		}},
+		goshopify.Product{Title: "Hat"},
+	)
+	// The configuration file doesn't define custom.care.
+	server.AddMetafieldDefinitions(shopifytest.MetafieldDefinition{
+		OwnerType: "PRODUCT",
+		Namespace: "custom",
+		Key:       "care",
+		Name:      "Care",
+		Type:      "single_line_text_field",
+	})
+
+	run(t, server, "clone")
+	run(t, server, "products", "checkout")
+	editCSV(t, "products.csv", "Title", "Hat", "product.metafields.custom.care", "Dry clean")
+	run(t, server, "products", "push", "products.csv")
+
+	hat := server.Products()[1]
+	if m := hat.Metafields; len(m) != 1 || m[0].Value != "Dry clean" || m[0].Type != "single_line_text_field" {
+		t.Fatalf("got metafields %+v, want custom.care", m)
+	}
+}
+
+func TestLegacyCache(t *testing.T) {
+	server := setupStore(t)
+	// Versions that supported a single store only kept the cache directly in
+	// the cache directory of the application.
+	legacy := filepath.Join(os.Getenv("XDG_CACHE_HOME"), config.AppName, "bolt.db")
+	writeLegacy := func() {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(legacy), 0700); err != nil {
+			t.Fatal(err)
+		}
+		db, err := bolt.Open(legacy, 0600, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		err = db.Update(func(tx *bolt.Tx) error {
+			b, err := tx.CreateBucketIfNotExists([]byte("products.id"))
+			if err != nil {
+				return err
+			}
+			return b.Put([]byte("1"), []byte(`{"id":1,"title":"Shirt"}`))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeLegacy()
+	run(t, server, "cache", "info")
+	c, err := cache.New("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, err := c.Products().GetByTitle("Shirt"); err != nil || p.ID != 1 {
+		t.Fatalf("got product %+v and error %v from legacy cache", p, err)
+	}
+	if _, err := os.Stat(legacy); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("got error %v for legacy cache, want it to be moved", err)
+	}
+
+	// A legacy cache doesn't replace the cache of the store.
+	writeLegacy()
+	run(t, server, "cache", "info")
+	if _, err := os.Stat(legacy); err != nil {
+		t.Fatalf("got error %v for legacy cache, want it to be kept", err)
+	}
+}
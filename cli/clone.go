@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"context"
+	"os"
+
+	"github.com/samherrmann/merchant/cache"
+	"github.com/samherrmann/merchant/shopify"
+	"github.com/spf13/cobra"
+)
+
+func newCloneCommand(clients *clientFactory) *cobra.Command {
+	var full *bool
+
+	cmd := &cobra.Command{
+		Use:   "clone",
+		Short: "Clone all supported resources from the store into the cache",
+		Long: "Clone all supported resources from the store into the cache: the " +
+			"products as with the products clone command, the custom and smart " +
+			"collections, the locations and the metafield definitions of products, " +
+			"variants and collections. Other commands use the cached resources to " +
+			"resolve names, handles and keys to IDs without the store.\n\n" +
+			"Collections, locations and metafield definitions are always fetched in " +
+			"full.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Command usage is correct at this point.
+			cmd.SilenceUsage = true
+
+			cfg, storeCfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			store, err := clients.newClient(
+				cmd.Context(),
+				cfg,
+				storeCfg,
+				shopify.WithReporter(newProgressReporter(os.Stderr, "Cloning metafields")),
+			)
+			if err != nil {
+				return err
+			}
+
+			c, err := cache.New(storeCfg.Name)
+			if err != nil {
+				return err
+			}
+			if err := cloneProducts(cmd.Context(), store, c, *full); err != nil {
+				return err
+			}
+			return cloneStoreResources(cmd.Context(), store, c)
+		},
+	}
+	full = cmd.Flags().Bool("full", false, "Fetch all products instead of only the ones updated since the last clone")
+	return cmd
+}
+
+// cloneStoreResources fetches the collections and metafield definitions of the
+// store into the cache. Locations are cloned together with the products.
+func cloneStoreResources(ctx context.Context, store *shopify.Client, c cache.Cache) error {
+	collections, err := store.GetCollections()
+	if err != nil {
+		return err
+	}
+	if err := c.Collections().Set(collections...); err != nil {
+		return err
+	}
+	defs, err := store.GetMetafieldDefinitions(ctx)
+	if err != nil {
+		return err
+	}
+	return c.MetafieldDefinitions().Set(defs...)
+}
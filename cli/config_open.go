@@ -15,7 +15,7 @@ func newConfigOpenCommand() *cobra.Command {
 			// Command usage is correct at this point.
 			cmd.SilenceUsage = true
 
-			cfg, err := config.Load()
+			cfg, err := config.LoadUnchecked()
 			if err != nil {
 				if !os.IsNotExist(err) {
 					return err
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/samherrmann/merchant/config"
+	"github.com/spf13/cobra"
+)
+
+func newConfigValidateCommand(clients *clientFactory, out io.Writer) *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Validate configuration file and verify store credentials",
+		Long: "Validate configuration file and verify store credentials.\n\n" +
+			"All stores are verified unless a store is selected with the store flag.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Command usage is correct at this point.
+			cmd.SilenceUsage = true
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+			names := []string{}
+			if name, _ := cmd.Flags().GetString(storeFlag); name != "" {
+				names = append(names, name)
+			} else {
+				for _, s := range cfg.Stores {
+					names = append(names, s.Name)
+				}
+			}
+
+			errs := []error{}
+			for _, name := range names {
+				storeCfg, err := cfg.Store(name)
+				if err != nil {
+					return err
+				}
+				store, err := clients.newClient(cmd.Context(), cfg, storeCfg)
+				if err != nil {
+					return err
+				}
+				shop, missing, err := store.CheckAccess()
+				if err != nil && shop == nil {
+					errs = append(errs, fmt.Errorf("store %q: %w", name, err))
+					continue
+				}
+				if _, err := fmt.Fprintf(out, "%v: connected to %q\n", name, shop.Name); err != nil {
+					return err
+				}
+				if err != nil {
+					errs = append(errs, fmt.Errorf("store %q: %w", name, err))
+					continue
+				}
+				if len(missing) > 0 {
+					errs = append(errs, fmt.Errorf("store %q: missing access scopes: %v", name, strings.Join(missing, ", ")))
+				}
+			}
+			return errors.Join(errs...)
+		},
+	}
+}
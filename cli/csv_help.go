@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// newCSVHelpCommand returns a help topic that describes how the push command
+// interprets the columns of a CSV file. The topic has no Run function, so it
+// is only shown by the help command.
+func newCSVHelpCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "csv",
+		Short: "Columns of the CSV files read by the push command",
+		Long: "Columns of the CSV files read by the push and fake-push commands.\n\n" +
+			"Only the values that differ from the store are pushed.\n\n" +
+			"Inventory\n\n" +
+			"Inventory columns, e.g. \"Inventory: Warehouse\", set the available " +
+			"quantity of a variant at that location, or change it by the given " +
+			"amount if the value has a leading sign, e.g. \"+3\" or \"-2\". The " +
+			"\"Cost per Item\" column sets the cost of the inventory item of a " +
+			"variant. Only quantities and costs that differ from the checked out " +
+			"ones are pushed, so sales and restocks since checkout are kept. The " +
+			"push is refused if an edited quantity or cost has also changed in the " +
+			"store since checkout.\n\n" +
+			"Empty fields\n\n" +
+			"An empty \"Compare At Price\" or \"Barcode\" removes that value from an " +
+			"existing variant, and \"false\" in the \"Taxable\" or \"Requires " +
+			"Shipping\" column clears that flag. If the \"Tags\", \"Body HTML\", " +
+			"\"SEO Title\" or \"SEO Description\" column is empty in all rows of an " +
+			"existing product, then that field of the product is cleared.\n\n" +
+			"Tags\n\n" +
+			"The \"Tags\" column is compared as a set, ignoring case: tags that are " +
+			"missing from it are removed from the product and new tags are added, " +
+			"while tags that have been added in the store since are kept.\n\n" +
+			"Deletions\n\n" +
+			"To delete products or variants, add a \"Delete\" column and set it to " +
+			"\"true\" in their rows. Deleting all variants of a product deletes the " +
+			"product.\n\n" +
+			"Images\n\n" +
+			"Images are matched by their \"Image Src\". A source that is not a URL " +
+			"is a local file, relative to the CSV file, that is uploaded. Images " +
+			"that earlier pushes created are also matched by the source that they " +
+			"were created from, so pushing a file again doesn't upload its images " +
+			"again. Rows that only contain a title and an image add further images " +
+			"to a product. If a product lists any images, then its images that are " +
+			"not listed are deleted. \"Variant Image\" assigns one of the images of " +
+			"the product to a variant. Images can only be added to products and " +
+			"variants that already exist in the store.",
+	}
+}
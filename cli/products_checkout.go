@@ -19,12 +19,12 @@ func newProductsCheckoutCommand() *cobra.Command {
 			// Command usage is correct at this point.
 			cmd.SilenceUsage = true
 
-			cfg, err := config.Load()
+			cfg, storeCfg, err := loadConfig(cmd)
 			if err != nil {
 				return err
 			}
 
-			c, err := cache.New()
+			c, err := cache.New(storeCfg.Name)
 			if err != nil {
 				return err
 			}
@@ -34,7 +34,17 @@ func newProductsCheckoutCommand() *cobra.Command {
 				return err
 			}
 
-			if err := csv.WriteProductsFile(products); err != nil {
+			stock, err := cachedStock(c)
+			if err != nil {
+				return err
+			}
+
+			alts, err := c.Products().ImageAlts()
+			if err != nil {
+				return err
+			}
+
+			if err := csv.WriteProductsFile(products, stock, alts); err != nil {
 				return err
 			}
 
@@ -1,39 +1,194 @@
 package cli
 
 import (
+	"context"
+	"os"
+	"time"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
 	"github.com/samherrmann/merchant/cache"
-	"github.com/samherrmann/merchant/config"
 	"github.com/samherrmann/merchant/shopify"
 	"github.com/spf13/cobra"
 )
 
-func newProductsCloneCommand() *cobra.Command {
+// cloneOverlap is subtracted from the time of the last clone when fetching
+// updated products, to allow for clock differences between this machine and
+// the store.
+const cloneOverlap = 5 * time.Minute
+
+func newProductsCloneCommand(clients *clientFactory) *cobra.Command {
+	var full *bool
+
 	cmd := &cobra.Command{
 		Use:   "clone",
 		Short: "Clone products and their metadata from the store into the cache",
-		Args:  cobra.NoArgs,
+		Long: "Clone products and their metadata from the store into the cache, " +
+			"together with the alt texts of the product images, the locations of " +
+			"the store and the inventory levels at these locations.\n\n" +
+			"After the first clone, only products and inventory levels that have " +
+			"been updated since the last clone are fetched, and products that have " +
+			"been deleted from the store are removed from the cache. Products are " +
+			"selected by their updated_at timestamp, which Shopify doesn't change " +
+			"when only the metafields of a product or its variants are edited. Use " +
+			"the full flag to pick up such edits.",
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Command usage is correct at this point.
 			cmd.SilenceUsage = true
 
-			cfg, err := config.Load()
+			cfg, storeCfg, err := loadConfig(cmd)
 			if err != nil {
 				return err
 			}
-			store := shopify.NewClient(&cfg.Store)
-
-			products, err := store.GetProducts()
+			store, err := clients.newClient(
+				cmd.Context(),
+				cfg,
+				storeCfg,
+				shopify.WithReporter(newProgressReporter(os.Stderr, "Cloning metafields")),
+			)
 			if err != nil {
 				return err
 			}
 
-			c, err := cache.New()
+			c, err := cache.New(storeCfg.Name)
 			if err != nil {
 				return err
 			}
-
-			return c.Products().Update(products...)
+			return cloneProducts(cmd.Context(), store, c, *full)
 		},
 	}
+	full = cmd.Flags().Bool("full", false, "Fetch all products instead of only the ones updated since the last clone")
 	return cmd
 }
+
+// cloneProducts fetches the products of the store into the cache, together with
+// the alt texts of their images and the stock of their variants. Unless full is
+// true, only the products that have been updated since the last clone are
+// fetched, which misses edits that only change metafields because they don't
+// update the updated_at timestamp of products.
+func cloneProducts(ctx context.Context, store *shopify.Client, c cache.Cache, full bool) error {
+	clonedAt, err := c.Products().ClonedAt()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	fullClone := full || clonedAt.IsZero()
+	var products []goshopify.Product
+	var storeIDs []int64
+	if fullClone {
+		products, err = store.GetProducts(ctx)
+		if err != nil {
+			return err
+		}
+		for _, p := range products {
+			storeIDs = append(storeIDs, p.ID)
+		}
+	} else {
+		products, err = store.GetProductsUpdatedSince(ctx, clonedAt.Add(-cloneOverlap))
+		if err != nil {
+			return err
+		}
+		storeIDs, err = store.GetProductIDs()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Deleted products are removed first, so that their handles and titles can
+	// be taken by the fetched products.
+	cachedIDs, err := c.Products().IDs()
+	if err != nil {
+		return err
+	}
+	if err := c.Products().Delete(missingIDs(cachedIDs, storeIDs)...); err != nil {
+		return err
+	}
+	if err := c.Products().Update(products...); err != nil {
+		return err
+	}
+	// Alt text changes don't update any timestamp that could be filtered on, so
+	// they are always fetched in full.
+	alts, err := store.GetImageAlts()
+	if err != nil {
+		return err
+	}
+	if err := c.Products().SetImageAlts(alts); err != nil {
+		return err
+	}
+	if err := cloneStock(store, c, fullClone, clonedAt); err != nil {
+		return err
+	}
+	// The clone time is only recorded once the cache is complete, so that an
+	// interrupted clone is picked up again by the next one.
+	return c.Products().SetClonedAt(start)
+}
+
+// cloneStock fetches the locations of the store, the inventory levels at these
+// locations and the inventory items of the cached variants into the cache.
+// Unless full is true, only the levels that have been updated since clonedAt
+// are fetched. Levels of inventory items that no longer belong to a cached
+// variant are removed. Inventory items are always fetched in full because
+// their changes don't update any timestamp that could be filtered on.
+func cloneStock(store *shopify.Client, c cache.Cache, full bool, clonedAt time.Time) error {
+	locations, err := store.GetLocations()
+	if err != nil {
+		return err
+	}
+	if err := c.Locations().Set(locations...); err != nil {
+		return err
+	}
+	since := time.Time{}
+	if !full {
+		since = clonedAt.Add(-cloneOverlap)
+	}
+	levels, err := store.GetInventoryLevels(shopify.LocationIDs(locations), since)
+	if err != nil {
+		return err
+	}
+	if full {
+		err = c.Stock().SetLevels(levels...)
+	} else {
+		err = c.Stock().UpdateLevels(levels...)
+	}
+	if err != nil {
+		return err
+	}
+
+	products, err := c.Products().List()
+	if err != nil {
+		return err
+	}
+	itemIDs := shopify.InventoryItemIDs(products)
+	items, err := store.GetInventoryItems(itemIDs)
+	if err != nil {
+		return err
+	}
+	if err := c.Stock().SetItems(items...); err != nil {
+		return err
+	}
+	cached, err := c.Stock().Levels()
+	if err != nil {
+		return err
+	}
+	cachedItemIDs := []int64{}
+	for _, l := range cached {
+		cachedItemIDs = append(cachedItemIDs, l.InventoryItemId)
+	}
+	return c.Stock().DeleteLevels(missingIDs(cachedItemIDs, itemIDs)...)
+}
+
+// missingIDs returns the IDs in ids that are not in existing.
+func missingIDs(ids []int64, existing []int64) []int64 {
+	set := make(map[int64]bool, len(existing))
+	for _, id := range existing {
+		set[id] = true
+	}
+	missing := []int64{}
+	for _, id := range ids {
+		if !set[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
@@ -4,12 +4,10 @@ import (
 	"fmt"
 	"io"
 
-	"github.com/samherrmann/merchant/config"
-	"github.com/samherrmann/merchant/shopify"
 	"github.com/spf13/cobra"
 )
 
-func newProductsCountCommand(w io.Writer) *cobra.Command {
+func newProductsCountCommand(clients *clientFactory, w io.Writer) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "count",
 		Args:  cobra.NoArgs,
@@ -18,11 +16,14 @@ func newProductsCountCommand(w io.Writer) *cobra.Command {
 			// Command usage is correct at this point.
 			cmd.SilenceUsage = true
 
-			cfg, err := config.Load()
+			cfg, storeCfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			store, err := clients.newClient(cmd.Context(), cfg, storeCfg)
 			if err != nil {
 				return err
 			}
-			store := shopify.NewClient(&cfg.Store)
 			productCount, err := store.Product.Count(nil)
 			if err != nil {
 				return err
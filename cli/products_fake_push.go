@@ -5,59 +5,141 @@ import (
 	"io"
 	"os"
 
-	"github.com/samherrmann/merchant/config"
+	"github.com/samherrmann/merchant/cache"
 	"github.com/samherrmann/merchant/csv"
 	"github.com/samherrmann/merchant/memdb"
 	"github.com/samherrmann/merchant/shopify"
 	"github.com/spf13/cobra"
 )
 
-func newProductsFakePushCommand(output io.Writer, outputFilename string) *cobra.Command {
+// Output formats of the fake-push command.
+const (
+	formatSummary  = "summary"
+	formatDiff     = "diff"
+	formatJSON     = "json"
+	formatMarkdown = "markdown"
+)
+
+func newProductsFakePushCommand(clients *clientFactory, output io.Writer, outputFilename string) *cobra.Command {
+	var fromCache *bool
+	var offline *bool
+	var format *string
+
 	cmd := &cobra.Command{
 		Use:   "fake-push <filename>",
 		Short: "Print the data that the push command would send to the store",
 		Args:  cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateFormat(*format)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Command usage is correct at this point.
 			cmd.SilenceUsage = true
 			inputFilename := args[0]
 
-			cfg, err := config.Load()
+			cfg, storeCfg, err := loadConfig(cmd)
 			if err != nil {
 				return err
 			}
-			store := shopify.NewClient(&cfg.Store)
-			incoming, err := csv.ReadProducts(inputFilename)
+			store, err := clients.newClient(
+				cmd.Context(),
+				cfg,
+				storeCfg,
+				shopify.WithReporter(newProgressReporter(os.Stderr, "Fetching metafields")),
+			)
 			if err != nil {
 				return err
 			}
-			inventory, err := store.GetProducts()
+			c, err := cache.New(storeCfg.Name)
 			if err != nil {
 				return err
 			}
-			db, err := memdb.New(inventory)
-			if err != nil {
-				return err
+			opts := inventoryOptions{
+				fromCache: *fromCache || *offline,
+				offline:   *offline,
 			}
-			operations, err := db.Operations(incoming)
+			inventory, err := loadInventory(cmd.Context(), store, c, opts)
 			if err != nil {
 				return err
 			}
-
-			file, err := os.Create(outputFilename)
+			defs, err := metafieldDefinitions(cfg, c)
 			if err != nil {
 				return err
 			}
-			defer file.Close()
-			if err := operations.PrintJSON(file); err != nil {
+			file, err := csv.ReadProducts(inputFilename, defs)
+			if err != nil {
 				return err
 			}
-			if err := operations.PrintSummary(output); err != nil {
+			operations, err := planPush(file, inventory, func() (memdb.ImageAlts, error) {
+				return loadImageAlts(store, c, opts)
+			}, func() (memdb.CreatedImages, error) {
+				return createdImages(c.Journal())
+			}, func() (*memdb.Stock, *memdb.Stock, error) {
+				return loadStock(store, c, inventory, opts)
+			})
+			if err != nil {
 				return err
 			}
-			_, err = fmt.Fprintf(output, "\nSee file %q for details\n", outputFilename)
-			return err
+			operations.Store = storeCfg.Name
+			return printPlan(output, *format, operations, outputFilename)
 		},
 	}
+	fromCache = cmd.Flags().Bool("from-cache", false, "Compare against the cache instead of the store")
+	offline = cmd.Flags().Bool("offline", false, "Compare against the cache without verifying that it is up to date with the store")
+	format = cmd.Flags().String("format", formatSummary, "Output format: summary, diff, json or markdown")
 	return cmd
 }
+
+// validateFormat returns an error if format is not a known output format.
+func validateFormat(format string) error {
+	switch format {
+	case formatSummary, formatDiff, formatJSON, formatMarkdown:
+		return nil
+	}
+	return fmt.Errorf("invalid format %q", format)
+}
+
+// printPlan writes the operations as JSON to the file with the given name and
+// prints them to output in the given format.
+func printPlan(output io.Writer, format string, operations *memdb.Operations, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := operations.PrintJSON(file); err != nil {
+		return err
+	}
+
+	switch format {
+	case formatDiff:
+		return operations.PrintDiff(output, useColor(output))
+	case formatJSON:
+		return operations.PrintJSON(output)
+	case formatMarkdown:
+		return operations.PrintMarkdown(output)
+	}
+	if err := operations.PrintSummary(output); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(output, "\nSee file %q for details\n", filename)
+	return err
+}
+
+// useColor returns true if w is a terminal and the NO_COLOR environment
+// variable is not set.
+// https://no-color.org/
+func useColor(w io.Writer) bool {
+	if _, set := os.LookupEnv("NO_COLOR"); set {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/samherrmann/merchant/cache"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+)
+
+// Output formats of the list and find commands, in addition to formatJSON.
+const (
+	formatTable = "table"
+	formatCSV   = "csv"
+)
+
+// productColumns are the columns of the table and CSV output of the list and
+// find commands. Every row is a variant.
+var productColumns = []string{"Product ID", "Variant ID", "Title", "Vendor", "Product Type", "SKU", "Barcode", "Price"}
+
+func newProductsListCommand(out io.Writer) *cobra.Command {
+	var format *string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "Print the cached products and variants",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateListFormat(*format)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Command usage is correct at this point.
+			cmd.SilenceUsage = true
+
+			return findProducts(cmd, out, cache.ProductQuery{}, *format)
+		},
+	}
+	format = cmd.Flags().String("format", formatTable, "Output format: table, json or csv")
+	return cmd
+}
+
+func newProductsFindCommand(out io.Writer) *cobra.Command {
+	var format *string
+	var minPrice *string
+	var maxPrice *string
+	var metafield *string
+	query := cache.ProductQuery{}
+
+	cmd := &cobra.Command{
+		Use:   "find",
+		Short: "Print the cached products and variants that match the given filters",
+		Long: "Print the cached products and variants that match the given filters.\n\n" +
+			"Only the variants that match all filters are printed. Vendor, product " +
+			"type and tag are compared case-insensitively. A metafield filter, e.g. " +
+			"\"custom.fabric=cotton\", selects products with all their variants and " +
+			"variants that have the metafield. The store is not contacted, so clone " +
+			"the store first to search its latest products.",
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateListFormat(*format); err != nil {
+				return err
+			}
+			var err error
+			if query.MinPrice, err = parsePrice("min-price", *minPrice); err != nil {
+				return err
+			}
+			if query.MaxPrice, err = parsePrice("max-price", *maxPrice); err != nil {
+				return err
+			}
+			if *metafield != "" {
+				if query.Metafield, err = parseMetafieldQuery(*metafield); err != nil {
+					return err
+				}
+			}
+			if query == (cache.ProductQuery{}) {
+				return errors.New("at least one filter is required, use the list command to print all products")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Command usage is correct at this point.
+			cmd.SilenceUsage = true
+
+			return findProducts(cmd, out, query, *format)
+		},
+	}
+	cmd.Flags().StringVar(&query.Vendor, "vendor", "", "Vendor of the products")
+	cmd.Flags().StringVar(&query.ProductType, "product-type", "", "Product type of the products")
+	cmd.Flags().StringVar(&query.Tag, "tag", "", "Tag that the products have")
+	minPrice = cmd.Flags().String("min-price", "", "Minimum price of the variants")
+	maxPrice = cmd.Flags().String("max-price", "", "Maximum price of the variants")
+	cmd.Flags().StringVar(&query.SKUPrefix, "sku-prefix", "", "Prefix of the SKUs of the variants")
+	cmd.Flags().BoolVar(&query.MissingBarcode, "missing-barcode", false, "Only variants without a barcode")
+	metafield = cmd.Flags().String("metafield", "", "Metafield value of the products or variants as namespace.key=value")
+	format = cmd.Flags().String("format", formatTable, "Output format: table, json or csv")
+	return cmd
+}
+
+// findProducts prints the cached products that match query in the given
+// format.
+func findProducts(cmd *cobra.Command, out io.Writer, query cache.ProductQuery, format string) error {
+	_, storeCfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	c, err := cache.New(storeCfg.Name)
+	if err != nil {
+		return err
+	}
+	products, err := c.Products().Find(query)
+	if err != nil {
+		return err
+	}
+	return printProducts(out, format, products)
+}
+
+// validateListFormat returns an error if format is not an output format of the
+// list and find commands.
+func validateListFormat(format string) error {
+	switch format {
+	case formatTable, formatJSON, formatCSV:
+		return nil
+	}
+	return fmt.Errorf("invalid format %q", format)
+}
+
+// parsePrice parses the value of the price flag with the given name. nil is
+// returned if the value is empty.
+func parsePrice(flag string, value string) (*decimal.Decimal, error) {
+	if value == "" {
+		return nil, nil
+	}
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v %q", flag, value)
+	}
+	return &d, nil
+}
+
+// parseMetafieldQuery parses a metafield filter of the form
+// namespace.key=value.
+func parseMetafieldQuery(s string) (*cache.MetafieldQuery, error) {
+	name, value, ok := strings.Cut(s, "=")
+	namespace, key, dot := strings.Cut(name, ".")
+	if !ok || !dot || namespace == "" || key == "" {
+		return nil, fmt.Errorf("invalid metafield %q, expected namespace.key=value", s)
+	}
+	return &cache.MetafieldQuery{Namespace: namespace, Key: key, Value: value}, nil
+}
+
+// printProducts prints the products in the given format. The table and CSV
+// formats have a row per variant, the JSON format contains the products
+// themselves.
+func printProducts(out io.Writer, format string, products []goshopify.Product) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(products)
+	case formatCSV:
+		w := csv.NewWriter(out)
+		if err := w.Write(productColumns); err != nil {
+			return err
+		}
+		if err := w.WriteAll(productRows(products)); err != nil {
+			return err
+		}
+		return w.Error()
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(productColumns, "\t"))
+	for _, row := range productRows(products) {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// productRows returns the values of productColumns for every variant of the
+// products.
+func productRows(products []goshopify.Product) [][]string {
+	rows := [][]string{}
+	for _, p := range products {
+		for _, v := range p.Variants {
+			price := ""
+			if v.Price != nil {
+				price = v.Price.StringFixed(2)
+			}
+			rows = append(rows, []string{
+				strconv.FormatInt(p.ID, 10),
+				strconv.FormatInt(v.ID, 10),
+				p.Title,
+				p.Vendor,
+				p.ProductType,
+				v.Sku,
+				v.Barcode,
+				price,
+			})
+		}
+	}
+	return rows
+}
@@ -0,0 +1,213 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/samherrmann/merchant/cache"
+	"github.com/samherrmann/merchant/config"
+	"github.com/samherrmann/merchant/csv"
+	"github.com/samherrmann/merchant/memdb"
+	"github.com/samherrmann/merchant/shopify"
+)
+
+var errStaleCache = errors.New("cache is out of date with the store, run the clone command to update it")
+
+// inventoryOptions defines where the current inventory is loaded from.
+type inventoryOptions struct {
+	// fromCache loads the inventory from the cache instead of the store.
+	fromCache bool
+	// offline skips verifying that the cache is up to date with the store. Only
+	// applicable when fromCache is true.
+	offline bool
+}
+
+// loadInventory returns the current products of the store, either from the
+// store itself or from the cache of the store.
+func loadInventory(ctx context.Context, store *shopify.Client, c cache.Cache, opts inventoryOptions) ([]goshopify.Product, error) {
+	if !opts.fromCache {
+		return store.GetProducts(ctx)
+	}
+	products, err := c.Products().List()
+	if err != nil {
+		return nil, err
+	}
+	if opts.offline {
+		return products, nil
+	}
+	stale, err := store.IsStale(products)
+	if err != nil {
+		return nil, err
+	}
+	if stale {
+		return nil, errStaleCache
+	}
+	return products, nil
+}
+
+// loadStock returns the stock in the cache of the store, which the CSV files
+// are checked out from, together with the locations and inventory levels of
+// the store and the inventory items of the variants in inventory, either from
+// the store itself or from the cache. Inventory changes don't update products,
+// so the cached stock can't be verified to be up to date with the store.
+func loadStock(store *shopify.Client, c cache.Cache, inventory []goshopify.Product, opts inventoryOptions) (checkedOut *memdb.Stock, current *memdb.Stock, err error) {
+	checkedOut, err = cachedStock(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.fromCache {
+		return checkedOut, checkedOut, nil
+	}
+	current, err = store.GetStock(shopify.InventoryItemIDs(inventory))
+	if err != nil {
+		return nil, nil, err
+	}
+	return checkedOut, current, nil
+}
+
+// cachedStock returns the locations, inventory levels and inventory items in
+// the cache.
+func cachedStock(c cache.Cache) (*memdb.Stock, error) {
+	locations, err := c.Locations().List()
+	if err != nil {
+		return nil, err
+	}
+	levels, err := c.Stock().Levels()
+	if err != nil {
+		return nil, err
+	}
+	items, err := c.Stock().Items()
+	if err != nil {
+		return nil, err
+	}
+	return &memdb.Stock{Locations: locations, Levels: levels, Items: items}, nil
+}
+
+// metafieldDefinitions returns the product and variant metafield definitions
+// in the cache of the store, which the clone command fetches from the store.
+// The definitions in cfg apply to the metafields that the cache has no
+// definitions for, e.g. because the store hasn't been cloned yet.
+func metafieldDefinitions(cfg *config.Config, c cache.Cache) (config.MetafieldDefinitions, error) {
+	cached, err := c.MetafieldDefinitions().List()
+	if err != nil {
+		return config.MetafieldDefinitions{}, err
+	}
+	defs := config.MetafieldDefinitions{Product: []config.MetafieldDefinition{}, Variant: []config.MetafieldDefinition{}}
+	owners := map[string]*[]config.MetafieldDefinition{
+		memdb.MetafieldOwnerProduct: &defs.Product,
+		memdb.MetafieldOwnerVariant: &defs.Variant,
+	}
+	for _, d := range cached {
+		if owner, ok := owners[d.OwnerResource]; ok {
+			*owner = append(*owner, config.MetafieldDefinition{Namespace: d.Namespace, Key: d.Key, Type: d.Type})
+		}
+	}
+	defs.Product = appendMissingDefinitions(defs.Product, cfg.MetafieldDefinitions.Product)
+	defs.Variant = appendMissingDefinitions(defs.Variant, cfg.MetafieldDefinitions.Variant)
+	return defs, nil
+}
+
+// appendMissingDefinitions appends the definitions in fallback to defs that
+// have a namespace and key that none of defs has.
+func appendMissingDefinitions(defs []config.MetafieldDefinition, fallback []config.MetafieldDefinition) []config.MetafieldDefinition {
+	existing := map[string]bool{}
+	for _, d := range defs {
+		existing[d.Namespace+"."+d.Key] = true
+	}
+	for _, d := range fallback {
+		if !existing[d.Namespace+"."+d.Key] {
+			defs = append(defs, d)
+		}
+	}
+	return defs
+}
+
+// createdImages returns the images that the pushes in the history of journal
+// have created, by the source they were created from.
+func createdImages(journal cache.JournalCache) (memdb.CreatedImages, error) {
+	history, err := journal.History()
+	if err != nil {
+		return nil, err
+	}
+	created := memdb.CreatedImages{}
+	for _, id := range history {
+		push, err := journal.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		operations, err := memdb.DecodeOperations(bytes.NewReader(push.Plan))
+		if err != nil {
+			return nil, fmt.Errorf("push %v: %w", id, err)
+		}
+		// Later pushes take precedence because the history is in ascending
+		// order.
+		for _, img := range shopify.CreatedResources(operations, push.Completed).NewImages {
+			created[img.Src] = img.ID
+		}
+	}
+	return created, nil
+}
+
+// loadImageAlts returns the alt texts of the product images, either from the
+// store itself or from the cache of the store.
+func loadImageAlts(store *shopify.Client, c cache.Cache, opts inventoryOptions) (memdb.ImageAlts, error) {
+	if !opts.fromCache {
+		return store.GetImageAlts()
+	}
+	return c.Products().ImageAlts()
+}
+
+// planPush returns the operations needed to apply the products, cleared
+// product and variant fields, images, inventory quantities and costs of file
+// to the inventory. The alt texts of the images and the images created by
+// earlier pushes are only loaded if the file contains images, and the stock
+// only if it contains inventory quantities or costs.
+func planPush(
+	file *csv.Products,
+	inventory []goshopify.Product,
+	loadAlts func() (memdb.ImageAlts, error),
+	loadCreated func() (memdb.CreatedImages, error),
+	loadStock func() (checkedOut *memdb.Stock, current *memdb.Stock, err error),
+) (*memdb.Operations, error) {
+	db, err := memdb.New(inventory)
+	if err != nil {
+		return nil, err
+	}
+	operations, err := db.Operations(file.Products, file.Deleted)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.ClearOperations(operations, file.ClearedFields); err != nil {
+		return nil, err
+	}
+	if err := db.ClearProductOperations(operations, file.ClearedProductFields); err != nil {
+		return nil, err
+	}
+	if len(file.Images) > 0 {
+		alts, err := loadAlts()
+		if err != nil {
+			return nil, err
+		}
+		created, err := loadCreated()
+		if err != nil {
+			return nil, err
+		}
+		if err := db.ImageOperations(operations, file.Images, alts, created); err != nil {
+			return nil, err
+		}
+	}
+	if len(file.Quantities) == 0 && len(file.Costs) == 0 {
+		return operations, nil
+	}
+	checkedOut, stock, err := loadStock()
+	if err != nil {
+		return nil, err
+	}
+	if err := db.InventoryOperations(operations, file.Quantities, file.Costs, checkedOut, stock); err != nil {
+		return nil, err
+	}
+	return operations, nil
+}
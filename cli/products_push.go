@@ -1,32 +1,308 @@
 package cli
 
 import (
-	"github.com/samherrmann/merchant/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/samherrmann/merchant/cache"
+	"github.com/samherrmann/merchant/collection"
 	"github.com/samherrmann/merchant/csv"
+	"github.com/samherrmann/merchant/memdb"
 	"github.com/samherrmann/merchant/shopify"
 	"github.com/spf13/cobra"
 )
 
-func newProductsPushCommand() *cobra.Command {
-	return &cobra.Command{
-		Use:   "push <filename>",
-		Short: "Update products in store with data from CSV file",
-		Args:  cobra.ExactArgs(1),
+var (
+	errIncompletePush  = errors.New("a previous push did not complete, run push with the resume flag to complete it or with the discard flag to abandon it")
+	errNothingToResume = errors.New("there is no incomplete push to resume")
+)
+
+func newProductsPushCommand(clients *clientFactory, out io.Writer) *cobra.Command {
+	var fromCache *bool
+	var planFilename *string
+	var resume *bool
+	var discard *bool
+
+	cmd := &cobra.Command{
+		Use:   "push [filename]",
+		Short: "Update products in store with data from CSV file or a saved plan",
+		Long: "Update products in store with data from CSV file or a saved plan.\n\n" +
+			"See \"help csv\" for the columns of the CSV file. A saved plan is " +
+			"refused if its products, quantities or costs have changed in the store " +
+			"since it was made.\n\n" +
+			"Every applied operation is recorded in a journal in the cache. If a push " +
+			"fails part way through, the resume flag applies the remaining operations " +
+			"of that push and the discard flag abandons them. Completed pushes can be " +
+			"reverted with the rollback command.",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if *resume {
+				if *discard {
+					return errors.New("the resume flag cannot be combined with the discard flag")
+				}
+				if *planFilename != "" || len(args) > 0 {
+					return errors.New("the resume flag cannot be combined with a CSV file or the plan flag")
+				}
+				return nil
+			}
+			if *planFilename != "" {
+				if len(args) > 0 {
+					return errors.New("a CSV file cannot be combined with the plan flag")
+				}
+				return nil
+			}
+			if *discard {
+				// Discarding on its own only abandons the incomplete push.
+				return cobra.MaximumNArgs(1)(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Command usage is correct at this point.
 			cmd.SilenceUsage = true
 
-			cfg, err := config.Load()
+			cfg, storeCfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			store, err := clients.newClient(
+				cmd.Context(),
+				cfg,
+				storeCfg,
+				shopify.WithReporter(newProgressReporter(os.Stderr, "Fetching metafields")),
+			)
+			if err != nil {
+				return err
+			}
+			c, err := cache.New(storeCfg.Name)
 			if err != nil {
 				return err
 			}
-			store := shopify.NewClient(&cfg.Store)
+			journal := c.Journal()
 
-			products, err := csv.ReadProducts(args[0])
+			if *resume {
+				push, err := journal.Current()
+				if errors.Is(err, cache.ErrNotExist) {
+					return errNothingToResume
+				}
+				if err != nil {
+					return err
+				}
+				operations, err := memdb.DecodeOperations(bytes.NewReader(push.Plan))
+				if err != nil {
+					return err
+				}
+				// The baseline is not checked because the store has been changed
+				// by the interrupted push itself.
+				return applyJournaled(cmd.Context(), out, store, journal, push.ID, operations)
+			}
+
+			if *discard {
+				// The discarded push is kept in the history so that the operations
+				// that have been applied can still be rolled back.
+				if err := journal.End(nil); err != nil {
+					return err
+				}
+				if *planFilename == "" && len(args) == 0 {
+					_, err := fmt.Fprintln(out, "Incomplete push discarded")
+					return err
+				}
+			}
+			if err := checkNoIncompletePush(journal); err != nil {
+				return err
+			}
+
+			var operations *memdb.Operations
+			var snapshot []goshopify.Product
+			if *planFilename != "" {
+				operations, err = readPlan(*planFilename)
+				if err != nil {
+					return err
+				}
+				if operations.Store != storeCfg.Name {
+					return fmt.Errorf("plan was made for store %q, not %q", operations.Store, storeCfg.Name)
+				}
+				if err := store.CheckBaseline(operations); err != nil {
+					return err
+				}
+				// Quantities that are set overwrite sales since the plan was made.
+				if err := store.CheckInventory(operations); err != nil {
+					return err
+				}
+				snapshot, err = store.GetProductsByID(cmd.Context(), baselineIDs(operations))
+				if err != nil {
+					return err
+				}
+			} else {
+				// Without the from-cache flag, the latest inventory is fetched from
+				// the live store so that we don't accidentally make updates based on
+				// an outdated cache.
+				opts := inventoryOptions{fromCache: *fromCache}
+				inventory, err := loadInventory(cmd.Context(), store, c, opts)
+				if err != nil {
+					return err
+				}
+				defs, err := metafieldDefinitions(cfg, c)
+				if err != nil {
+					return err
+				}
+				file, err := csv.ReadProducts(args[0], defs)
+				if err != nil {
+					return err
+				}
+				operations, err = planPush(file, inventory, func() (memdb.ImageAlts, error) {
+					return loadImageAlts(store, c, opts)
+				}, func() (memdb.CreatedImages, error) {
+					return createdImages(c.Journal())
+				}, func() (*memdb.Stock, *memdb.Stock, error) {
+					return loadStock(store, c, inventory, opts)
+				})
+				if err != nil {
+					return err
+				}
+				operations.Store = storeCfg.Name
+				snapshot = filterProducts(inventory, baselineIDs(operations))
+			}
+
+			id, err := beginPush(journal, operations, snapshot)
 			if err != nil {
 				return err
 			}
-			return store.UpdateProducts(products)
+			return applyJournaled(cmd.Context(), out, store, journal, id, operations)
 		},
 	}
+	fromCache = cmd.Flags().Bool("from-cache", false, "Compare against the cache instead of the store after verifying that it is up to date")
+	planFilename = cmd.Flags().String("plan", "", "Apply the operations of a plan file written by the fake-push command")
+	resume = cmd.Flags().Bool("resume", false, "Apply the remaining operations of a push that did not complete")
+	discard = cmd.Flags().Bool("discard", false, "Abandon the remaining operations of a push that did not complete, before pushing the given CSV file or plan if any")
+	return cmd
+}
+
+// readPlan reads the operations from a plan file written by the fake-push
+// command.
+func readPlan(filename string) (*memdb.Operations, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return memdb.DecodeOperations(file)
+}
+
+// checkNoIncompletePush returns errIncompletePush if the journal contains a
+// push that has not ended.
+func checkNoIncompletePush(journal cache.JournalCache) error {
+	_, err := journal.Current()
+	if err == nil {
+		return errIncompletePush
+	}
+	if errors.Is(err, cache.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// beginPush starts a new push in the journal and returns its ID. snapshot is
+// the state of the affected products before the push.
+func beginPush(journal cache.JournalCache, operations *memdb.Operations, snapshot []goshopify.Product) (string, error) {
+	plan := &bytes.Buffer{}
+	if err := operations.PrintJSON(plan); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(snapshot)
+	if err != nil {
+		return "", err
+	}
+	// The ID has nanosecond precision so that pushes and rollbacks in quick
+	// succession don't collide. The fraction has a fixed width so that the IDs
+	// sort in the order of the pushes.
+	id := time.Now().UTC().Format("20060102T150405.000000000Z")
+	if err := journal.Begin(id, plan.Bytes(), b); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// applyJournaled applies the operations to the store while recording them in
+// the journal. The push is ended once all operations have been applied, with
+// the update time of the affected products so that a rollback can tell whether
+// they have changed since.
+func applyJournaled(
+	ctx context.Context,
+	out io.Writer,
+	store *shopify.Client,
+	journal cache.JournalCache,
+	id string,
+	operations *memdb.Operations,
+) error {
+	if err := store.ApplyOperations(ctx, operations, journal); err != nil {
+		return fmt.Errorf("%w\nrun push with the resume flag to retry the operations that have not been applied", err)
+	}
+	completed, err := journal.Completed()
+	if err != nil {
+		return err
+	}
+	updatedAt, err := store.UpdatedAt(affectedIDs(operations, completed))
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(updatedAt)
+	if err != nil {
+		return err
+	}
+	if err := journal.End(b); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "Push %v completed\n", id)
+	return err
+}
+
+// affectedIDs returns the IDs of the products that operations change,
+// including the products that have been created according to completed.
+func affectedIDs(operations *memdb.Operations, completed map[string]int64) []int64 {
+	ids := collection.NewOrderedMap[int64, int64]()
+	for _, id := range baselineIDs(operations) {
+		ids.Set(id, id)
+	}
+	for _, v := range operations.DeletedVariants {
+		ids.Set(v.ProductID, v.ProductID)
+	}
+	created := shopify.CreatedResources(operations, completed)
+	for _, p := range created.NewProducts {
+		ids.Set(p.ID, p.ID)
+	}
+	for _, v := range created.NewVariants {
+		ids.Set(v.ProductID, v.ProductID)
+	}
+	return ids.Slice()
+}
+
+// baselineIDs returns the IDs of the products in the baseline of operations.
+func baselineIDs(operations *memdb.Operations) []int64 {
+	ids := make([]int64, 0, len(operations.Baseline))
+	for id := range operations.Baseline {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// filterProducts returns the products with the given IDs.
+func filterProducts(products []goshopify.Product, ids []int64) []goshopify.Product {
+	set := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	filtered := []goshopify.Product{}
+	for _, p := range products {
+		if set[p.ID] {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
 }
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/samherrmann/merchant/cache"
+	"github.com/samherrmann/merchant/collection"
+	"github.com/samherrmann/merchant/memdb"
+	"github.com/samherrmann/merchant/shopify"
+	"github.com/spf13/cobra"
+)
+
+var (
+	errNothingToRollback = errors.New("there is no push to roll back")
+	errUnknownPushResult = errors.New("the state of the products after the push is unknown, run rollback with the force flag to roll it back anyway")
+)
+
+func newProductsRollbackCommand(clients *clientFactory, out io.Writer) *cobra.Command {
+	var list *bool
+	var dryRun *bool
+	var force *bool
+
+	cmd := &cobra.Command{
+		Use:   "rollback [push-id]",
+		Short: "Revert the changes of a push",
+		Long: "Revert the changes of a push. The last push is reverted if no push ID is given.\n\n" +
+			"Products and variants that the push created are deleted, and products " +
+			"and variants that the push deleted are recreated with new IDs. The " +
+			"rollback itself is applied as a new push. The rollback is refused if " +
+			"any of the affected products has changed since the push, including " +
+			"by a later push, unless the force flag is given. Pushes that were " +
+			"discarded can only be rolled back with the force flag.\n\n" +
+			"A rollback does not restore inventory quantities and costs, changes " +
+			"to existing images, or fields that were empty before the push other " +
+			"than the body HTML, tags, SEO title and description, compare-at " +
+			"price, barcode, taxable and requires shipping.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Command usage is correct at this point.
+			cmd.SilenceUsage = true
+
+			cfg, storeCfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			c, err := cache.New(storeCfg.Name)
+			if err != nil {
+				return err
+			}
+			journal := c.Journal()
+			history, err := journal.History()
+			if err != nil {
+				return err
+			}
+			if *list {
+				for _, id := range history {
+					if _, err := fmt.Fprintln(out, id); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+			if err := checkNoIncompletePush(journal); err != nil {
+				return err
+			}
+
+			var id string
+			if len(args) > 0 {
+				id = args[0]
+			} else if len(history) > 0 {
+				id = history[len(history)-1]
+			} else {
+				return errNothingToRollback
+			}
+			push, err := journal.Get(id)
+			if err != nil {
+				return fmt.Errorf("push %v: %w", id, err)
+			}
+			operations, err := memdb.DecodeOperations(bytes.NewReader(push.Plan))
+			if err != nil {
+				return err
+			}
+			snapshot := []goshopify.Product{}
+			if err := json.Unmarshal(push.Snapshot, &snapshot); err != nil {
+				return err
+			}
+			created := shopify.CreatedResources(operations, push.Completed)
+
+			store, err := clients.newClient(
+				cmd.Context(),
+				cfg,
+				storeCfg,
+				shopify.WithReporter(newProgressReporter(os.Stderr, "Fetching metafields")),
+			)
+			if err != nil {
+				return err
+			}
+			if !*force {
+				if err := checkUnchangedSince(store, push); err != nil {
+					return err
+				}
+			}
+			current, err := store.GetProductsByID(cmd.Context(), rollbackIDs(snapshot, created))
+			if err != nil {
+				return err
+			}
+			db, err := memdb.New(current)
+			if err != nil {
+				return err
+			}
+			rollback, err := db.Rollback(snapshot, created)
+			if err != nil {
+				return err
+			}
+			if *dryRun {
+				return rollback.PrintSummary(out)
+			}
+			rollbackID, err := beginPush(journal, rollback, current)
+			if err != nil {
+				return err
+			}
+			return applyJournaled(cmd.Context(), out, store, journal, rollbackID, rollback)
+		},
+	}
+	list = cmd.Flags().Bool("list", false, "List the IDs of all pushes that can be rolled back")
+	dryRun = cmd.Flags().Bool("dry-run", false, "Print a summary of the rollback without applying it")
+	force = cmd.Flags().Bool("force", false, "Roll back even if the affected products have changed since the push")
+	return cmd
+}
+
+// rollbackIDs returns the IDs of all products that are affected by rolling
+// back a push with the given snapshot and created resources.
+func rollbackIDs(snapshot []goshopify.Product, created *memdb.Operations) []int64 {
+	ids := collection.NewOrderedMap[int64, int64]()
+	for _, p := range snapshot {
+		ids.Set(p.ID, p.ID)
+	}
+	for _, p := range created.NewProducts {
+		ids.Set(p.ID, p.ID)
+	}
+	for _, v := range created.NewVariants {
+		ids.Set(v.ProductID, v.ProductID)
+	}
+	return ids.Slice()
+}
+
+// checkUnchangedSince returns an error if any of the products affected by push
+// has changed in the store since the push ended.
+func checkUnchangedSince(store *shopify.Client, push *cache.Push) error {
+	if push.UpdatedAt == nil {
+		return fmt.Errorf("push %v: %w", push.ID, errUnknownPushResult)
+	}
+	updatedAt := map[int64]time.Time{}
+	if err := json.Unmarshal(push.UpdatedAt, &updatedAt); err != nil {
+		return err
+	}
+	if err := store.CheckUpdatedAt(updatedAt); err != nil {
+		return fmt.Errorf("%w\nrun rollback with the force flag to roll back anyway", err)
+	}
+	return nil
+}
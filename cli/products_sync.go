@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/samherrmann/merchant/cache"
+	"github.com/samherrmann/merchant/memdb"
+	"github.com/samherrmann/merchant/shopify"
+	"github.com/spf13/cobra"
+)
+
+func newProductsSyncCommand(clients *clientFactory, output io.Writer, outputFilename string) *cobra.Command {
+	var from *string
+	var to *string
+	var fromCache *bool
+	var format *string
+	var apply *bool
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Copy products from one store to another",
+		Long: "Copy products from one store to another.\n\n" +
+			"Products are matched by handle or title, and variants by barcode, SKU " +
+			"or options. The resulting plan is printed and written to a file that " +
+			"can be applied to the target store with the push command. Use the " +
+			"apply flag to apply it right away.",
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if *from == *to {
+				return errors.New("the source and target stores must be different")
+			}
+			return validateFormat(*format)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Command usage is correct at this point.
+			cmd.SilenceUsage = true
+
+			cfg, _, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			sourceCfg, err := cfg.Store(*from)
+			if err != nil {
+				return err
+			}
+			targetCfg, err := cfg.Store(*to)
+			if err != nil {
+				return err
+			}
+			source, err := clients.newClient(
+				cmd.Context(),
+				cfg,
+				sourceCfg,
+				shopify.WithReporter(newProgressReporter(os.Stderr, "Cloning metafields")),
+			)
+			if err != nil {
+				return err
+			}
+			target, err := clients.newClient(
+				cmd.Context(),
+				cfg,
+				targetCfg,
+				shopify.WithReporter(newProgressReporter(os.Stderr, "Fetching metafields")),
+			)
+			if err != nil {
+				return err
+			}
+
+			products, err := source.GetProducts(cmd.Context())
+			if err != nil {
+				return err
+			}
+			c, err := cache.New(targetCfg.Name)
+			if err != nil {
+				return err
+			}
+			inventory, err := loadInventory(cmd.Context(), target, c, inventoryOptions{fromCache: *fromCache})
+			if err != nil {
+				return err
+			}
+			operations, err := planSync(products, inventory)
+			if err != nil {
+				return err
+			}
+			operations.Store = targetCfg.Name
+			if err := printPlan(output, *format, operations, outputFilename); err != nil {
+				return err
+			}
+			if !*apply {
+				return nil
+			}
+
+			journal := c.Journal()
+			if err := checkNoIncompletePush(journal); err != nil {
+				return err
+			}
+			id, err := beginPush(journal, operations, filterProducts(inventory, baselineIDs(operations)))
+			if err != nil {
+				return err
+			}
+			return applyJournaled(cmd.Context(), output, target, journal, id, operations)
+		},
+	}
+	from = cmd.Flags().String("from", "", "Name of the store to copy products from")
+	to = cmd.Flags().String("to", "", "Name of the store to copy products to")
+	fromCache = cmd.Flags().Bool("from-cache", false, "Compare against the cache of the target store instead of the store after verifying that it is up to date")
+	format = cmd.Flags().String("format", formatSummary, "Output format: summary, diff, json or markdown")
+	apply = cmd.Flags().Bool("apply", false, "Apply the plan to the target store")
+	cmd.MarkFlagRequired("from")
+	cmd.MarkFlagRequired("to")
+	return cmd
+}
+
+// planSync returns the operations needed to apply the products of a source
+// store to the inventory of a target store. The IDs of the source store are
+// discarded, so that all products and variants are matched against the target
+// store by their other properties.
+func planSync(products []goshopify.Product, inventory []goshopify.Product) (*memdb.Operations, error) {
+	incoming := make([]goshopify.Product, len(products))
+	for i, p := range products {
+		incoming[i] = memdb.Detach(p)
+	}
+	db, err := memdb.New(inventory)
+	if err != nil {
+		return nil, err
+	}
+	return db.Operations(incoming, nil)
+}
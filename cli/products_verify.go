@@ -3,14 +3,14 @@ package cli
 import (
 	"fmt"
 	"io"
+	"os"
 
-	"github.com/samherrmann/merchant/config"
 	"github.com/samherrmann/merchant/memdb"
 	"github.com/samherrmann/merchant/shopify"
 	"github.com/spf13/cobra"
 )
 
-func newProductsVerifyCommand(w io.Writer) *cobra.Command {
+func newProductsVerifyCommand(clients *clientFactory, w io.Writer) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "verify",
 		Short: "Verifies the integrity of products and variants",
@@ -19,13 +19,21 @@ func newProductsVerifyCommand(w io.Writer) *cobra.Command {
 			// Command usage is correct at this point.
 			cmd.SilenceUsage = true
 
-			cfg, err := config.Load()
+			cfg, storeCfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			store, err := clients.newClient(
+				cmd.Context(),
+				cfg,
+				storeCfg,
+				shopify.WithReporter(newProgressReporter(os.Stderr, "Fetching metafields")),
+			)
 			if err != nil {
 				return err
 			}
-			store := shopify.NewClient(&cfg.Store)
 
-			inventory, err := store.GetProducts()
+			inventory, err := store.GetProducts(cmd.Context())
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+)
+
+// progressReporter prints progress updates on a single line of w.
+type progressReporter struct {
+	w     io.Writer
+	label string
+}
+
+func newProgressReporter(w io.Writer, label string) *progressReporter {
+	return &progressReporter{w: w, label: label}
+}
+
+// Progress implements the shopify.Reporter interface.
+func (r *progressReporter) Progress(done int, total int) {
+	fmt.Fprintf(r.w, "\r%v [%v/%v]", r.label, done, total)
+	if done == total {
+		fmt.Fprintln(r.w)
+	}
+}
@@ -2,40 +2,63 @@
 package cli
 
 import (
+	"context"
+	"io"
 	"os"
+	"os/signal"
 
 	"github.com/samherrmann/merchant/config"
+	"github.com/samherrmann/merchant/shopify"
 	"github.com/spf13/cobra"
 )
 
 func Execute() error {
+	// Cancel long running commands on interrupt.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return newRootCommand(os.Stdout).ExecuteContext(ctx)
+}
 
+// newRootCommand returns the root command with all its subcommands. Commands
+// print their results to out. clientOpts are applied to every client of a
+// store that the commands create.
+func newRootCommand(out io.Writer, clientOpts ...shopify.Option) *cobra.Command {
+	clients := &clientFactory{options: clientOpts}
 	rootCmd := &cobra.Command{Use: config.AppName}
+	rootCmd.PersistentFlags().String(storeFlag, "", "Name of the store to use, defaults to the first store in the configuration file")
 
 	cacheCmd := newCacheCommand()
 	cacheCmd.AddCommand(
 		newCacheClearCommand(),
 		newCacheDumpCommand(),
-		newCacheSizeCommand(os.Stdout),
+		newCacheInfoCommand(out),
+		newCacheSizeCommand(out),
 	)
 	configCmd := newConfigCommand()
 	configCmd.AddCommand(
 		newConfigOpenCommand(),
+		newConfigValidateCommand(clients, out),
 	)
 	productsCmd := newProductsCommand()
 	productsCmd.AddCommand(
-		newProductsCountCommand(os.Stdout),
-		newProductsFakePushCommand(os.Stdout, config.AppName+".push.json"),
+		newProductsCountCommand(clients, out),
+		newProductsFakePushCommand(clients, out, config.AppName+".push.json"),
 		newProductsCheckoutCommand(),
-		newProductsCloneCommand(),
-		newProductsPushCommand(),
-		newProductsVerifyCommand(os.Stdout),
+		newProductsCloneCommand(clients),
+		newProductsFindCommand(out),
+		newProductsListCommand(out),
+		newProductsPushCommand(clients, out),
+		newProductsRollbackCommand(clients, out),
+		newProductsSyncCommand(clients, out, config.AppName+".sync.json"),
+		newProductsVerifyCommand(clients, out),
 	)
 	rootCmd.AddCommand(
 		cacheCmd,
+		newCloneCommand(clients),
 		configCmd,
+		newCSVHelpCommand(),
 		productsCmd,
 		newVersionCommand(config.AppName, config.Version),
 	)
-	return rootCmd.Execute()
+	return rootCmd
 }
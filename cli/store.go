@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/samherrmann/merchant/config"
+	"github.com/samherrmann/merchant/shopify"
+	"github.com/spf13/cobra"
+)
+
+// storeFlag is the name of the global flag that selects the store.
+const storeFlag = "store"
+
+// loadConfig loads the configuration and returns it together with the
+// configuration of the store that is selected by the store flag. The secrets
+// of the store are not resolved until a client is created for it, so that
+// commands that don't contact the store don't need them.
+func loadConfig(cmd *cobra.Command) (*config.Config, *shopify.Configuration, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	name, err := cmd.Flags().GetString(storeFlag)
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := cfg.Store(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, store, nil
+}
+
+// clientFactory creates the clients of the configured stores.
+type clientFactory struct {
+	// options are appended to the options of every client, e.g. to redirect
+	// the requests to a fake store in tests.
+	options []shopify.Option
+}
+
+// newClient resolves the secrets of storeCfg and returns a client for the
+// store. Its requests are aborted once ctx is done.
+func (f *clientFactory) newClient(ctx context.Context, cfg *config.Config, storeCfg *shopify.Configuration, opts ...shopify.Option) (*shopify.Client, error) {
+	if err := cfg.ResolveSecrets(storeCfg); err != nil {
+		return nil, err
+	}
+	opts = append(opts, shopify.WithContext(ctx))
+	return shopify.NewClient(storeCfg, append(opts, f.options...)...), nil
+}
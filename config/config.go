@@ -3,6 +3,7 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -18,13 +19,17 @@ const (
 	Version = "dev"
 )
 
+// ErrInsecure is returned when loading a configuration file that contains
+// secrets and is readable by other users.
+var ErrInsecure = errors.New("contains secrets and is readable by other users")
+
 // New returns a new configuration.
 func New() *Config {
 	// We need to manually initialize slices to be able to marshal the config to
 	// JSON without fields that are of type array set to null.
 	// https://github.com/golang/go/issues/27589
 	return &Config{
-		Store: shopify.Configuration{},
+		Stores: shopify.Configurations{{}},
 		MetafieldDefinitions: MetafieldDefinitions{
 			Product: []MetafieldDefinition{},
 			Variant: []MetafieldDefinition{},
@@ -35,8 +40,9 @@ func New() *Config {
 }
 
 type Config struct {
-	// Store contains the Shopify store access information.
-	Store shopify.Configuration `json:"store"`
+	// Stores contains the access information of all Shopify stores. The first
+	// store is used unless another one is selected by name.
+	Stores shopify.Configurations `json:"stores"`
 	// MetafieldDefinitions contains metafield definitions.
 	MetafieldDefinitions MetafieldDefinitions `json:"metafieldDefinitions"`
 	// TextEditorCmd is the command that launches the text editor.
@@ -48,25 +54,58 @@ type Config struct {
 // UnmarshalJSON implements the [encoding/json.Unmarshaler] interface.
 func (c *Config) UnmarshalJSON(b []byte) error {
 	type alias Config
-	a := alias{}
+	a := struct {
+		alias
+		// Store is the single store of configuration files written before
+		// multiple stores were supported.
+		Store *shopify.Configuration `json:"store"`
+	}{}
 	if err := json.Unmarshal(b, &a); err != nil {
 		return err
 	}
+	if a.Store != nil && len(a.Stores) == 0 {
+		a.Stores = shopify.Configurations{*a.Store}
+	}
 	if len(a.SpreadsheetEditor) == 0 {
 		a.SpreadsheetEditor = DefaultSpreadsheetEditor
 	}
 	if len(a.TextEditor) == 0 {
 		a.TextEditor = DefaultTextEditor
 	}
-	*c = Config(a)
+	*c = Config(a.alias)
 	return nil
 }
 
+// Store returns a copy of the configuration of the store with the given name.
+// The first store is returned if name is an empty string. Its secrets are not
+// resolved, see [Config.ResolveSecrets].
+func (c *Config) Store(name string) (*shopify.Configuration, error) {
+	if len(c.Stores) == 0 {
+		return nil, errors.New("no store is configured")
+	}
+	found := c.Stores.Get(name)
+	if found == nil {
+		return nil, fmt.Errorf("store %q is not configured", name)
+	}
+	// The secrets are resolved on a copy so that they never end up in the file
+	// when the configuration is written.
+	store := *found
+	return &store, nil
+}
+
+// ResolveSecrets resolves the secrets of store, a configuration returned by
+// [Config.Store]. The environment variables without a store name only apply to
+// the first store.
+func (c *Config) ResolveSecrets(store *shopify.Configuration) error {
+	return store.ResolveSecrets(len(c.Stores) > 0 && c.Stores[0].Name == store.Name)
+}
+
 // MetafieldDefinitions define product and variant metafields.
 //
-// At the time of writing, metafield definitions are not available via the REST
-// Admin API. In the meantime, the user must define the metafield definitions in
-// the merchant.json file.
+// The clone command fetches the metafield definitions of the store through the
+// GraphQL Admin API into the cache, where they take precedence. The definitions
+// in the merchant.json file apply to the metafields that the cache has no
+// definitions for, e.g. because the store hasn't been cloned yet.
 // https://shopify.dev/apps/metafields/definitions#structure-of-a-metafield-definition
 type MetafieldDefinitions struct {
 	Product []MetafieldDefinition `json:"product"`
@@ -98,6 +137,16 @@ func Load() (*Config, error) {
 	return load(dir)
 }
 
+// LoadUnchecked loads the configuration from file without checking its
+// permissions, so that an insecure file can still be opened to fix it.
+func LoadUnchecked() (*Config, error) {
+	dir, err := mkDefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return readFile(joinFilename(dir))
+}
+
 func FindMetafieldDefinition(defs []MetafieldDefinition, namespace string, key string) *MetafieldDefinition {
 	for _, def := range defs {
 		if namespace == def.Namespace && key == def.Key {
@@ -140,6 +189,26 @@ func initFile(dir string) (*Config, error) {
 // Load loads the configuration from file located in dir.
 func load(dir string) (*Config, error) {
 	filename := joinFilename(dir)
+	cfg, err := readFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if isWorldReadable(info.Mode()) && cfg.hasInlineSecrets() {
+		return nil, fmt.Errorf(
+			"file %v: %w, restrict its permissions (e.g. chmod 600) or move the secrets into the environment, a password command or the keyring",
+			filename,
+			ErrInsecure,
+		)
+	}
+	return cfg, nil
+}
+
+// readFile reads the configuration from the given file.
+func readFile(filename string) (*Config, error) {
 	bytes, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
@@ -151,6 +220,40 @@ func load(dir string) (*Config, error) {
 	return cfg, nil
 }
 
+// Validate returns an error if the configuration is invalid. The secrets of
+// the stores are resolved to verify that they are available.
+func (c *Config) Validate() error {
+	if len(c.Stores) == 0 {
+		return errors.New("no store is configured")
+	}
+	errs := []error{}
+	names := map[string]bool{}
+	for i, s := range c.Stores {
+		if names[s.Name] {
+			errs = append(errs, fmt.Errorf("store %q is configured more than once", s.Name))
+		}
+		names[s.Name] = true
+		if err := s.ResolveSecrets(i == 0); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := s.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("store %q: %w", s.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// hasInlineSecrets reports whether any store contains secrets in plain text.
+func (c *Config) hasInlineSecrets() bool {
+	for i := range c.Stores {
+		if c.Stores[i].HasInlineSecrets() {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Config) newTextEditor() editor.Editor {
 	cmd := c.TextEditor
 	if len(cmd) == 0 {
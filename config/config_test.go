@@ -3,9 +3,13 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"reflect"
+	"runtime"
 	"testing"
+
+	"github.com/samherrmann/merchant/shopify"
 )
 
 func TestConfig_UnmarshalJSON(t *testing.T) {
@@ -29,6 +33,26 @@ func TestConfig_UnmarshalJSON(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "should unmarshal stores",
+			json: `{"stores": [{"name": "foo"}, {"name": "bar"}]}`,
+			config: &Config{
+				Stores:            shopify.Configurations{{Name: "foo"}, {Name: "bar"}},
+				TextEditor:        DefaultTextEditor,
+				SpreadsheetEditor: DefaultSpreadsheetEditor,
+			},
+			wantErr: false,
+		},
+		{
+			name: "should convert single store to stores",
+			json: `{"store": {"name": "foo"}}`,
+			config: &Config{
+				Stores:            shopify.Configurations{{Name: "foo"}},
+				TextEditor:        DefaultTextEditor,
+				SpreadsheetEditor: DefaultSpreadsheetEditor,
+			},
+			wantErr: false,
+		},
 		{
 			name: "should set custom editors",
 			json: `{
@@ -59,6 +83,119 @@ func TestConfig_UnmarshalJSON(t *testing.T) {
 	}
 }
 
+func TestConfig_Store(t *testing.T) {
+	c := &Config{Stores: shopify.Configurations{{Name: "foo"}, {Name: "bar"}}}
+	tests := []struct {
+		name    string
+		store   string
+		want    string
+		wantErr bool
+	}{
+		{name: "should return first store if name is empty", store: "", want: "foo"},
+		{name: "should return store by name", store: "bar", want: "bar"},
+		{name: "should fail if store does not exist", store: "baz", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.Store(tt.store)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Name != tt.want {
+				t.Fatalf("got %v, want %v", got.Name, tt.want)
+			}
+		})
+	}
+
+	t.Run("should fail if no store is configured", func(t *testing.T) {
+		if _, err := (&Config{}).Store(""); err == nil {
+			t.Fatal("expected error but didn't get one")
+		}
+	})
+}
+
+func TestConfig_ResolveSecrets(t *testing.T) {
+	for _, k := range []string{"MERCHANT_STORE_FOO_PASSWORD", "MERCHANT_STORE_BAR_PASSWORD"} {
+		t.Setenv(k, "")
+		os.Unsetenv(k)
+	}
+	t.Setenv("MERCHANT_STORE_PASSWORD", "env")
+	c := &Config{Stores: shopify.Configurations{
+		{Name: "foo", PasswordCommand: []string{"fail"}},
+		{Name: "bar", Password: "inline"},
+	}}
+
+	foo, err := c.Store("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if foo.Password != "" {
+		t.Fatalf("got password %q before resolving secrets", foo.Password)
+	}
+	if err := c.ResolveSecrets(foo); err != nil {
+		t.Fatal(err)
+	}
+	if foo.Password != "env" {
+		t.Fatalf("got password %q, want env", foo.Password)
+	}
+
+	bar, err := c.Store("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ResolveSecrets(bar); err != nil {
+		t.Fatal(err)
+	}
+	if bar.Password != "inline" {
+		t.Fatalf("got password %q, want inline", bar.Password)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *Config
+		wantErr bool
+	}{
+		{
+			name: "should accept valid stores",
+			config: &Config{Stores: shopify.Configurations{
+				{Name: "foo", AccessToken: "token"},
+				{Name: "bar", APIKey: "key", Password: "password"},
+			}},
+		},
+		{
+			name:    "should fail without stores",
+			config:  &Config{},
+			wantErr: true,
+		},
+		{
+			name: "should fail with duplicate store names",
+			config: &Config{Stores: shopify.Configurations{
+				{Name: "foo", AccessToken: "token"},
+				{Name: "foo", AccessToken: "token"},
+			}},
+			wantErr: true,
+		},
+		{
+			name:    "should fail with invalid store",
+			config:  &Config{Stores: shopify.Configurations{{Name: "foo"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func Test_load(t *testing.T) {
 	t.Run("should return error if file does not exist", func(t *testing.T) {
 		_, err := load(t.TempDir())
@@ -76,6 +213,62 @@ func Test_load(t *testing.T) {
 			t.Fatal(err)
 		}
 	})
+	t.Run("should return error if file with secrets is world-readable", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("file modes do not control access on Windows")
+		}
+		dir := t.TempDir()
+		filename := joinFilename(dir)
+		if err := os.WriteFile(filename, []byte(`{"stores": [{"password": "foo"}]}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(filename, 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := load(dir)
+		if !errors.Is(err, ErrInsecure) {
+			t.Fatalf("got %v, want %v", err, ErrInsecure)
+		}
+	})
+	t.Run("should not return error if file with secrets is private", func(t *testing.T) {
+		dir := t.TempDir()
+		filename := joinFilename(dir)
+		if err := os.WriteFile(filename, []byte(`{"stores": [{"password": "foo"}]}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := load(dir); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("should not return error if world-readable file has no secrets", func(t *testing.T) {
+		dir := t.TempDir()
+		filename := joinFilename(dir)
+		if err := os.WriteFile(filename, []byte(`{"stores": [{"passwordCommand": ["pass"]}]}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(filename, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := load(dir); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("should read world-readable file with secrets unchecked", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("file modes do not control access on Windows")
+		}
+		dir := t.TempDir()
+		filename := joinFilename(dir)
+		if err := os.WriteFile(filename, []byte(`{"stores": [{"password": "foo"}]}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(filename, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readFile(filename); err != nil {
+			t.Fatal(err)
+		}
+	})
 	t.Run("should return error if file does not contain JSON", func(t *testing.T) {
 		dir := t.TempDir()
 		file, err := os.Create(joinFilename(dir))
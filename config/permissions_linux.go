@@ -0,0 +1,9 @@
+package config
+
+import "os"
+
+// isWorldReadable reports whether a file with the given mode can be read by
+// all users.
+func isWorldReadable(mode os.FileMode) bool {
+	return mode.Perm()&0004 != 0
+}
@@ -0,0 +1,9 @@
+package config
+
+import "os"
+
+// isWorldReadable always returns false because access to files on Windows is
+// controlled by access control lists rather than the file mode.
+func isWorldReadable(mode os.FileMode) bool {
+	return false
+}
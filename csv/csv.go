@@ -1,21 +1,89 @@
 // Package csv enables reading and writing product data to and from a CSV file.
 package csv
 
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	keyProductID          = "Product ID"
+	keyVariantID          = "Variant ID"
+	keySKU                = "SKU"
+	keyBarcode            = "Barcode"
+	keyTitle              = "Title"
+	keyVendor             = "Vendor"
+	keyProductType        = "Product Type"
+	keyBodyHTML           = "Body HTML"
+	keyTags               = "Tags"
+	keyStatus             = "Status"
+	keyHandle             = "Handle"
+	keyTemplateSuffix     = "Template Suffix"
+	keyPublishedScope     = "Published Scope"
+	keySEOTitle           = "SEO Title"
+	keySEODescription     = "SEO Description"
+	keyWeight             = "Weight"
+	keyWeightUnit         = "Weight Unit"
+	keyPrice              = "Price"
+	keyCompareAtPrice     = "Compare At Price"
+	keyCost               = "Cost per Item"
+	keyInventoryPolicy    = "Inventory Policy"
+	keyTaxable            = "Taxable"
+	keyRequiresShipping   = "Requires Shipping"
+	keyFulfillmentService = "Fulfillment Service"
+	keyImageSrc           = "Image Src"
+	keyImageAlt           = "Image Alt"
+	keyImagePosition      = "Image Position"
+	keyVariantImage       = "Variant Image"
+	keyOption1Name        = "Option1 Name"
+	keyOption1Value       = "Option1 Value"
+	keyOption2Name        = "Option2 Name"
+	keyOption2Value       = "Option2 Value"
+	keyOption3Name        = "Option3 Name"
+	keyOption3Value       = "Option3 Value"
+	keyDelete             = "Delete"
+	// keyInventoryPrefix is the prefix of the inventory columns, which are
+	// followed by the name of the location, e.g. "Inventory: Warehouse".
+	keyInventoryPrefix = "Inventory: "
+)
+
+// productStatuses are the valid values of the status column.
+var productStatuses = []string{"active", "draft", "archived"}
+
 const (
-	keyProductID    = "Product ID"
-	keyVariantID    = "Variant ID"
-	keySKU          = "SKU"
-	keyBarcode      = "Barcode"
-	keyTitle        = "Title"
-	keyVendor       = "Vendor"
-	keyProductType  = "Product Type"
-	keyWeight       = "Weight"
-	keyWeightUnit   = "Weight Unit"
-	keyPrice        = "Price"
-	keyOption1Name  = "Option1 Name"
-	keyOption1Value = "Option1 Value"
-	keyOption2Name  = "Option2 Name"
-	keyOption2Value = "Option2 Value"
-	keyOption3Name  = "Option3 Name"
-	keyOption3Value = "Option3 Value"
+	metafieldOwnerProduct = "product"
+	metafieldOwnerVariant = "variant"
 )
+
+// metafieldKey returns the column name for a metafield, e.g.
+// "product.metafields.<namespace>.<key>".
+func metafieldKey(owner string, namespace string, key string) string {
+	return fmt.Sprintf("%s.metafields.%s.%s", owner, namespace, key)
+}
+
+// parseMetafieldKey is the inverse of metafieldKey. ok is false if colName is
+// not a metafield column.
+func parseMetafieldKey(colName string) (owner string, namespace string, key string, ok bool) {
+	owner, rest, found := strings.Cut(colName, ".metafields.")
+	if !found || (owner != metafieldOwnerProduct && owner != metafieldOwnerVariant) {
+		return "", "", "", false
+	}
+	namespace, key, found = strings.Cut(rest, ".")
+	if !found || namespace == "" || key == "" {
+		return "", "", "", false
+	}
+	return owner, namespace, key, true
+}
+
+// inventoryKey returns the column name for the inventory at a location, e.g.
+// "Inventory: <location>".
+func inventoryKey(location string) string {
+	return keyInventoryPrefix + location
+}
+
+// parseInventoryKey is the inverse of inventoryKey. ok is false if colName is
+// not an inventory column.
+func parseInventoryKey(colName string) (location string, ok bool) {
+	location, ok = strings.CutPrefix(colName, keyInventoryPrefix)
+	return location, ok && location != ""
+}
@@ -2,21 +2,403 @@ package csv
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	goshopify "github.com/bold-commerce/go-shopify/v3"
 	"github.com/samherrmann/merchant/collection"
+	"github.com/samherrmann/merchant/config"
+	"github.com/samherrmann/merchant/memdb"
 	"github.com/shopspring/decimal"
 )
 
-func ReadProducts(filename string) ([]goshopify.Product, error) {
+// Products is the content of a products CSV file.
+type Products struct {
+	// Products are the products of the rows that are not marked for deletion.
+	// Metafield columns are typed according to the definitions passed to
+	// ReadProducts.
+	Products []goshopify.Product
+	// Deleted are the products of the rows that are marked for deletion,
+	// grouped into products the same way as all other rows.
+	Deleted []goshopify.Product
+	// ClearedFields are the variant fields that are empty or false, which
+	// can't be expressed by Products. Only the columns of
+	// [memdb.ClearableVariantFields] are read, and empty booleans are not
+	// considered to be false.
+	ClearedFields []memdb.ClearedFields
+	// ClearedProductFields are the product fields that are empty, which can't
+	// be expressed by Products. Only the columns of
+	// [memdb.ClearableProductFields] are read, and a field is only empty if its
+	// column is empty in all rows of the product. Image rows are skipped.
+	ClearedProductFields []memdb.ClearedProductFields
+	// Images are the images of the products and variants. Local image files
+	// are resolved relative to the directory of the file. Only products with
+	// images or variant images are included.
+	Images []memdb.ProductImages
+	// Quantities are the non-empty cells of the inventory columns, i.e. the
+	// quantities of the variants in their rows at the locations of the columns.
+	// Quantities with a leading sign, e.g. "+3" or "-2", are changes of the
+	// available quantity.
+	Quantities []memdb.InventoryQuantity
+	// Costs are the non-empty cells of the cost column.
+	Costs []memdb.InventoryCost
+}
+
+// ReadProducts reads the given CSV file. The file is read and parsed once, and
+// all parts of [Products] are taken from the parsed rows. Metafield columns are
+// typed according to defs. Apart from Deleted, rows that are marked for
+// deletion are skipped.
+func ReadProducts(filename string, defs config.MetafieldDefinitions) (*Products, error) {
 	rows, err := readFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	return groupVariants(rows)
+	// Local files are resolved to absolute paths so that their images are
+	// recognized regardless of the working directory, see memdb.CreatedImages.
+	dir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return nil, err
+	}
+	p := &Products{}
+	if p.Products, p.Deleted, err = groupVariants(rows, defs); err != nil {
+		return nil, err
+	}
+	if p.ClearedFields, err = parseClearedFields(rows); err != nil {
+		return nil, err
+	}
+	if p.ClearedProductFields, err = parseClearedProductFields(rows); err != nil {
+		return nil, err
+	}
+	if p.Images, err = parseImages(rows, dir); err != nil {
+		return nil, err
+	}
+	if p.Quantities, p.Costs, err = parseInventory(rows); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// parseInventory returns the inventory quantities and costs in rows. The first
+// row is expected to be the header.
+func parseInventory(rows [][]string) ([]memdb.InventoryQuantity, []memdb.InventoryCost, error) {
+	quantities := []memdb.InventoryQuantity{}
+	costs := []memdb.InventoryCost{}
+	if len(rows) < 1 {
+		return quantities, costs, nil
+	}
+	locations := map[int]string{}
+	for i, colName := range rows[0] {
+		if location, ok := parseInventoryKey(colName); ok {
+			locations[i] = location
+		}
+	}
+	costColIndex := collection.IndexOf(rows[0], keyCost)
+	if len(locations) == 0 && costColIndex < 0 {
+		return quantities, costs, nil
+	}
+	err := forEachVariant(rows, func(header []string, row []string, variant *goshopify.Variant) error {
+		for i := 0; i < len(header); i++ {
+			location, ok := locations[i]
+			if !ok || row[i] == "" {
+				continue
+			}
+			q, err := parseQuantity(row[i])
+			if err != nil {
+				return colError(header[i], err)
+			}
+			q.Variant = *variant
+			q.Location = location
+			quantities = append(quantities, q)
+		}
+		if costColIndex >= 0 && row[costColIndex] != "" {
+			cost, err := parseDecimal(row[costColIndex])
+			if err != nil {
+				return colError(keyCost, err)
+			}
+			costs = append(costs, memdb.InventoryCost{Variant: *variant, Cost: *cost})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return quantities, costs, nil
+}
+
+// parseClearedFields returns the cleared variant fields in rows. The first row
+// is expected to be the header.
+func parseClearedFields(rows [][]string) ([]memdb.ClearedFields, error) {
+	cleared := []memdb.ClearedFields{}
+	err := forEachVariant(rows, func(header []string, row []string, variant *goshopify.Variant) error {
+		fields := []string{}
+		for i, colName := range header {
+			switch colName {
+			case keyCompareAtPrice:
+				if row[i] == "" {
+					fields = append(fields, "compare_at_price")
+				}
+			case keyTaxable, keyRequiresShipping:
+				b, err := parseBool(row[i])
+				if err != nil {
+					return colError(colName, err)
+				}
+				if row[i] != "" && !b {
+					fields = append(fields, boolFields[colName])
+				}
+			}
+		}
+		if len(fields) > 0 {
+			cleared = append(cleared, memdb.ClearedFields{Variant: *variant, Fields: fields})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cleared, nil
+}
+
+// parseClearedProductFields returns the cleared product fields in rows. The
+// first row is expected to be the header.
+func parseClearedProductFields(rows [][]string) ([]memdb.ClearedProductFields, error) {
+	products := collection.NewOrderedMap[string, goshopify.Product]()
+	// set maps the titles of the products to the fields that are set in any of
+	// their rows.
+	set := map[string]map[string]bool{}
+	err := forEachRow(rows, func(header []string, row []string, product *goshopify.Product, variant *goshopify.Variant) error {
+		// Image rows don't repeat the fields of their product.
+		if variant == nil {
+			return nil
+		}
+		if _, ok := products.Get(product.Title); !ok {
+			products.Set(product.Title, *product)
+			set[product.Title] = map[string]bool{}
+		}
+		for i, colName := range header {
+			if _, ok := productStringFields[colName]; ok && row[i] != "" {
+				set[product.Title][colName] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	cleared := []memdb.ClearedProductFields{}
+	if len(rows) < 1 {
+		return cleared, nil
+	}
+	for _, p := range products.Slice() {
+		fields := []string{}
+		for _, colName := range rows[0] {
+			if name, ok := productStringFields[colName]; ok && !set[p.Title][colName] {
+				fields = append(fields, name)
+			}
+		}
+		if len(fields) > 0 {
+			cleared = append(cleared, memdb.ClearedProductFields{Product: p, Fields: fields})
+		}
+	}
+	return cleared, nil
+}
+
+// parseImages returns the images in rows. Local image files are resolved
+// relative to dir. Images without a position are positioned after the
+// preceding images of their product. The first row is expected to be the
+// header.
+func parseImages(rows [][]string, dir string) ([]memdb.ProductImages, error) {
+	products := collection.NewOrderedMap[string, memdb.ProductImages]()
+	err := forEachRow(rows, func(header []string, row []string, product *goshopify.Product, variant *goshopify.Variant) error {
+		images, _ := products.Get(product.Title)
+		images.Product = *product
+		image := memdb.Image{}
+		variantImage := ""
+		for i, colName := range header {
+			switch colName {
+			case keyImageSrc:
+				image.Src = imageSrc(row[i], dir)
+			case keyImageAlt:
+				image.Alt = row[i]
+			case keyImagePosition:
+				if row[i] == "" {
+					continue
+				}
+				n, err := strconv.Atoi(row[i])
+				if err != nil {
+					return colError(colName, err)
+				}
+				image.Position = n
+			case keyVariantImage:
+				variantImage = imageSrc(row[i], dir)
+			}
+		}
+		if image.Src != "" {
+			if image.Position == 0 {
+				image.Position = len(images.Images) + 1
+			}
+			images.Images = append(images.Images, image)
+		}
+		if variant != nil && variantImage != "" {
+			images.Variants = append(images.Variants, memdb.VariantImage{Variant: *variant, Src: variantImage})
+		}
+		if len(images.Images) > 0 || len(images.Variants) > 0 {
+			products.Set(product.Title, images)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return products.Slice(), nil
+}
+
+// imageSrc returns src unchanged if it is a URL, and otherwise the path of the
+// local file src relative to dir.
+func imageSrc(src string, dir string) string {
+	if src == "" || strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") || filepath.IsAbs(src) {
+		return src
+	}
+	return filepath.Join(dir, src)
+}
+
+// isImageRow reports whether row only contains an image of a product, i.e.
+// whether it has an image but none of the columns that identify or price a
+// variant.
+func isImageRow(header []string, row []string) bool {
+	hasImage := false
+	for i, colName := range header {
+		switch colName {
+		case keyImageSrc:
+			hasImage = row[i] != ""
+		case keyVariantID, keySKU, keyBarcode, keyPrice, keyOption1Value, keyOption2Value, keyOption3Value:
+			if row[i] != "" {
+				return false
+			}
+		}
+	}
+	return hasImage
+}
+
+// boolFields maps the boolean columns to the JSON names of their variant
+// fields.
+var boolFields = map[string]string{
+	keyTaxable:          "taxable",
+	keyRequiresShipping: "requires_shipping",
+}
+
+// productStringFields maps the product columns that can be cleared to the JSON
+// names of their product fields, see [memdb.ClearableProductFields].
+var productStringFields = map[string]string{
+	keyBodyHTML:       "body_html",
+	keyTags:           "tags",
+	keySEOTitle:       "metafields_global_title_tag",
+	keySEODescription: "metafields_global_description_tag",
+}
+
+// forEachVariant calls fn with the variant of every row that is not marked for
+// deletion and that is not an image row. The variant only contains the columns
+// that identify it, and its product ID. The first row is expected to be the
+// header.
+func forEachVariant(rows [][]string, fn func(header []string, row []string, variant *goshopify.Variant) error) error {
+	return forEachRow(rows, func(header []string, row []string, _ *goshopify.Product, variant *goshopify.Variant) error {
+		if variant == nil {
+			return nil
+		}
+		return fn(header, row, variant)
+	})
+}
+
+// forEachRow calls fn with the product and variant of every row that is not
+// marked for deletion. The product only contains its ID, title and handle, and
+// the variant only contains the columns that identify it, and its product ID.
+// The variant is nil for image rows. The first row is expected to be the
+// header.
+func forEachRow(rows [][]string, fn func(header []string, row []string, product *goshopify.Product, variant *goshopify.Variant) error) error {
+	if len(rows) < 2 {
+		return nil
+	}
+	header := rows[0]
+	deleteColIndex := collection.IndexOf(header, keyDelete)
+	for i := 1; i < len(rows); i++ {
+		row := collection.PadSliceRight(rows[i], len(header))
+		if deleteColIndex >= 0 {
+			del, err := parseBool(row[deleteColIndex])
+			if err != nil {
+				return fmt.Errorf("row %v: %w", i, colError(keyDelete, err))
+			}
+			if del {
+				continue
+			}
+		}
+		product, identity, err := rowIdentity(header, row)
+		if err != nil {
+			return fmt.Errorf("row %v: %w", i, err)
+		}
+		if err := fn(header, row, product, identity); err != nil {
+			return fmt.Errorf("row %v: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// rowIdentity returns the product and variant of row with only the columns
+// that identify them. The variant is nil for image rows.
+func rowIdentity(header []string, row []string) (*goshopify.Product, *goshopify.Variant, error) {
+	if isImageRow(header, row) {
+		product := &goshopify.Product{}
+		for i, colName := range header {
+			switch colName {
+			case keyProductID:
+				if row[i] == "" {
+					continue
+				}
+				id, err := strconv.ParseInt(row[i], 10, 64)
+				if err != nil {
+					return nil, nil, colError(colName, err)
+				}
+				product.ID = id
+			case keyTitle:
+				product.Title = row[i]
+			case keyHandle:
+				product.Handle = row[i]
+			}
+		}
+		return product, nil, nil
+	}
+	// Metafields are irrelevant, so they are read without definitions.
+	p := &goshopify.Product{}
+	variant, err := attachVariantToProduct(p, header, row, config.MetafieldDefinitions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	product := &goshopify.Product{ID: variant.ProductID, Title: p.Title, Handle: p.Handle}
+	identity := &goshopify.Variant{
+		ID:        variant.ID,
+		ProductID: variant.ProductID,
+		Sku:       variant.Sku,
+		Barcode:   variant.Barcode,
+		Option1:   variant.Option1,
+		Option2:   variant.Option2,
+		Option3:   variant.Option3,
+	}
+	return product, identity, nil
+}
+
+// parseQuantity parses an absolute quantity such as "12", or a relative
+// quantity such as "+3" or "-2".
+func parseQuantity(s string) (memdb.InventoryQuantity, error) {
+	q := memdb.InventoryQuantity{}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return q, err
+	}
+	q.Available = n
+	q.Relative = strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-")
+	return q, nil
 }
 
 func readFile(filename string) ([][]string, error) {
@@ -29,42 +411,64 @@ func readFile(filename string) ([][]string, error) {
 }
 
 // groupVariants groups variants that have the same title into the same product.
-// The first row is expected to be the header.
-func groupVariants(rows [][]string) ([]goshopify.Product, error) {
+// Rows that are marked for deletion are grouped separately. The first row is
+// expected to be the header.
+func groupVariants(rows [][]string, defs config.MetafieldDefinitions) ([]goshopify.Product, []goshopify.Product, error) {
 	products := collection.NewOrderedMap[string, goshopify.Product]()
+	deleted := collection.NewOrderedMap[string, goshopify.Product]()
 
 	if len(rows) < 2 {
-		return products.Slice(), nil
+		return products.Slice(), deleted.Slice(), nil
 	}
 
 	header := rows[0]
 	titleColIndex := collection.IndexOf(header, keyTitle)
 	if titleColIndex < 0 {
-		return nil, fmt.Errorf("no %q column found", keyTitle)
+		return nil, nil, fmt.Errorf("no %q column found", keyTitle)
 	}
+	deleteColIndex := collection.IndexOf(header, keyDelete)
 
 	rowsLength := len(rows)
 	for i := 1; i < rowsLength; i++ {
 		row := collection.PadSliceRight(rows[i], len(header))
 		title := row[titleColIndex]
 		if title == "" {
-			return nil, fmt.Errorf("title in row %v can not be empty", i)
+			return nil, nil, fmt.Errorf("title in row %v can not be empty", i)
+		}
+		group := products
+		if deleteColIndex >= 0 {
+			del, err := parseBool(row[deleteColIndex])
+			if err != nil {
+				return nil, nil, fmt.Errorf("row %v: %w", i, colError(keyDelete, err))
+			}
+			if del {
+				group = deleted
+			}
+		}
+		// Image rows are read by parseImages.
+		if isImageRow(header, row) {
+			continue
 		}
-		product, exists := products.Get(title)
+		product, exists := group.Get(title)
 		if !exists {
 			product = goshopify.Product{}
 		}
-		variant, err := attachVariantToProduct(&product, header, row)
+		variant, err := attachVariantToProduct(&product, header, row, defs)
 		if err != nil {
-			return nil, fmt.Errorf("row %v: %w", i, err)
+			return nil, nil, fmt.Errorf("row %v: %w", i, err)
 		}
 		product.ID = variant.ProductID
-		products.Set(product.Title, product)
+		group.Set(product.Title, product)
 	}
-	return products.Slice(), nil
+	return products.Slice(), deleted.Slice(), nil
 }
 
-func attachVariantToProduct(product *goshopify.Product, header []string, record []string) (*goshopify.Variant, error) {
+func attachVariantToProduct(
+	product *goshopify.Product,
+	header []string,
+	record []string,
+	defs config.MetafieldDefinitions,
+) (*goshopify.Variant, error) {
 	variant := &goshopify.Variant{}
 	for i, v := range record {
 		colName := header[i]
@@ -91,6 +495,25 @@ func attachVariantToProduct(product *goshopify.Product, header []string, record
 			product.Vendor = v
 		case keyProductType:
 			product.ProductType = v
+		case keyBodyHTML:
+			product.BodyHTML = v
+		case keyTags:
+			product.Tags = v
+		case keyStatus:
+			if v != "" && collection.IndexOf(productStatuses, v) < 0 {
+				return nil, colError(colName, fmt.Errorf("invalid status %q, want one of %v", v, productStatuses))
+			}
+			product.Status = v
+		case keyHandle:
+			product.Handle = v
+		case keyTemplateSuffix:
+			product.TemplateSuffix = v
+		case keyPublishedScope:
+			product.PublishedScope = v
+		case keySEOTitle:
+			product.MetafieldsGlobalTitleTag = v
+		case keySEODescription:
+			product.MetafieldsGlobalDescriptionTag = v
 		case keyWeight:
 			dec, err := parseDecimal(v)
 			if err != nil {
@@ -105,6 +528,32 @@ func attachVariantToProduct(product *goshopify.Product, header []string, record
 				return nil, colError(colName, err)
 			}
 			variant.Price = dec
+		case keyCompareAtPrice:
+			// An empty compare-at price clears it, see parseClearedFields.
+			if v == "" {
+				continue
+			}
+			dec, err := parseDecimal(v)
+			if err != nil {
+				return nil, colError(colName, err)
+			}
+			variant.CompareAtPrice = dec
+		case keyInventoryPolicy:
+			variant.InventoryPolicy = v
+		case keyTaxable:
+			b, err := parseBool(v)
+			if err != nil {
+				return nil, colError(colName, err)
+			}
+			variant.Taxable = b
+		case keyRequiresShipping:
+			b, err := parseBool(v)
+			if err != nil {
+				return nil, colError(colName, err)
+			}
+			variant.RequireShipping = b
+		case keyFulfillmentService:
+			variant.FulfillmentService = v
 		case keyOption1Name:
 			attachOptionToProduct(product, 0, v)
 		case keyOption2Name:
@@ -117,6 +566,25 @@ func attachVariantToProduct(product *goshopify.Product, header []string, record
 			variant.Option2 = v
 		case keyOption3Value:
 			variant.Option3 = v
+		default:
+			owner, namespace, key, ok := parseMetafieldKey(colName)
+			if !ok || v == "" {
+				continue
+			}
+			switch owner {
+			case metafieldOwnerProduct:
+				m, err := parseMetafield(defs.Product, namespace, key, v)
+				if err != nil {
+					return nil, colError(colName, err)
+				}
+				product.Metafields = setMetafield(product.Metafields, *m)
+			case metafieldOwnerVariant:
+				m, err := parseMetafield(defs.Variant, namespace, key, v)
+				if err != nil {
+					return nil, colError(colName, err)
+				}
+				variant.Metafields = setMetafield(variant.Metafields, *m)
+			}
 		}
 	}
 	product.Variants = append(product.Variants, *variant)
@@ -130,10 +598,90 @@ func attachOptionToProduct(p *goshopify.Product, index int, name string) {
 	}
 }
 
+// parseMetafield returns a metafield for the given namespace and key with s
+// converted to the type of the matching definition. If no definition exists,
+// then the value is kept as a string and the type is left empty.
+func parseMetafield(defs []config.MetafieldDefinition, namespace string, key string, s string) (*goshopify.Metafield, error) {
+	m := &goshopify.Metafield{
+		Namespace: namespace,
+		Key:       key,
+		Value:     s,
+	}
+	def := config.FindMetafieldDefinition(defs, namespace, key)
+	if def == nil {
+		return m, nil
+	}
+	m.Type = def.Type
+	v, err := parseMetafieldValue(def.Type, s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v value: %w", def.Type, err)
+	}
+	m.Value = v
+	return m, nil
+}
+
+// measurement is the JSON value of dimension, volume and weight metafields.
+type measurement struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+// parseMetafieldValue converts s to the Go type that matches the metafield
+// type t. Decimals are validated but kept as strings to preserve precision.
+// https://shopify.dev/apps/metafields/types
+func parseMetafieldValue(t string, s string) (any, error) {
+	switch t {
+	case "number_integer":
+		return strconv.ParseInt(s, 10, 64)
+	case "number_decimal":
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return nil, err
+		}
+		return s, nil
+	case "boolean":
+		return strconv.ParseBool(s)
+	case "dimension", "volume", "weight":
+		m := measurement{}
+		if err := json.Unmarshal([]byte(s), &m); err != nil {
+			return nil, err
+		}
+		return s, nil
+	case "json", "money", "rating":
+		if !json.Valid([]byte(s)) {
+			return nil, fmt.Errorf("%q is not valid JSON", s)
+		}
+		return s, nil
+	}
+	if strings.HasPrefix(t, "list.") && !json.Valid([]byte(s)) {
+		return nil, fmt.Errorf("%q is not valid JSON", s)
+	}
+	return s, nil
+}
+
+// setMetafield replaces the metafield with the same namespace and key as m in
+// metafields, or appends m if no such metafield exists.
+func setMetafield(metafields []goshopify.Metafield, m goshopify.Metafield) []goshopify.Metafield {
+	for i := range metafields {
+		if metafields[i].Namespace == m.Namespace && metafields[i].Key == m.Key {
+			metafields[i] = m
+			return metafields
+		}
+	}
+	return append(metafields, m)
+}
+
 func colError(colName string, err error) error {
 	return fmt.Errorf("column %q: %w", colName, err)
 }
 
+// parseBool is like [strconv.ParseBool] except that an empty string is false.
+func parseBool(s string) (bool, error) {
+	if s == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(s)
+}
+
 func parseDecimal(s string) (*decimal.Decimal, error) {
 	v, err := strconv.ParseFloat(s, 64)
 	if err != nil {
@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/samherrmann/merchant/config"
+	"github.com/samherrmann/merchant/memdb"
 	"github.com/shopspring/decimal"
 )
 
@@ -111,6 +113,19 @@ func Test_groupVariants(t *testing.T) {
 					keyOption2Value,
 					keyOption3Name,
 					keyOption3Value,
+					keyCompareAtPrice,
+					keyInventoryPolicy,
+					keyTaxable,
+					keyRequiresShipping,
+					keyFulfillmentService,
+					keyBodyHTML,
+					keyTags,
+					keyStatus,
+					keyHandle,
+					keyTemplateSuffix,
+					keyPublishedScope,
+					keySEOTitle,
+					keySEODescription,
 				},
 				{
 					"123",
@@ -129,6 +144,19 @@ func Test_groupVariants(t *testing.T) {
 					"myOption2Value",
 					"myOption3Name",
 					"myOption3Value",
+					"9.99",
+					"continue",
+					"true",
+					"true",
+					"manual",
+					"<p>myBody</p>",
+					"a, b",
+					"draft",
+					"my-handle",
+					"myTemplate",
+					"web",
+					"mySEOTitle",
+					"mySEODescription",
 				},
 			},
 			want: func() []goshopify.Product {
@@ -137,12 +165,22 @@ func Test_groupVariants(t *testing.T) {
 				var variantID int64 = 456
 				weight := decimal.NewFromFloat(123.456)
 				price := decimal.NewFromFloat(7.89)
+				compareAtPrice := decimal.NewFromFloat(9.99)
 
 				p := goshopify.Product{
 					ID:          productID,
 					Title:       "myTitle",
 					Vendor:      "myVendor",
 					ProductType: "myProductType",
+					BodyHTML:    "<p>myBody</p>",
+					Tags:        "a, b",
+					Status:      "draft",
+					Handle:      "my-handle",
+
+					TemplateSuffix:                 "myTemplate",
+					PublishedScope:                 "web",
+					MetafieldsGlobalTitleTag:       "mySEOTitle",
+					MetafieldsGlobalDescriptionTag: "mySEODescription",
 					Options: []goshopify.ProductOption{
 						{Name: "myOption1Name"},
 						{Name: "myOption2Name"},
@@ -159,15 +197,85 @@ func Test_groupVariants(t *testing.T) {
 						Option1:    "myOption1Value",
 						Option2:    "myOption2Value",
 						Option3:    "myOption3Value",
+
+						CompareAtPrice:     &compareAtPrice,
+						InventoryPolicy:    "continue",
+						Taxable:            true,
+						RequireShipping:    true,
+						FulfillmentService: "manual",
 					}},
 				}
 				return []goshopify.Product{p}
 			}(),
 		},
+		{
+			name: "metafields",
+			rows: [][]string{
+				{
+					keyTitle,
+					"product.metafields.custom.material",
+					"variant.metafields.custom.pack_size",
+					"variant.metafields.custom.notes",
+				},
+				{"foo", "wool", "12", "soft"},
+				{"foo", "wool", "", ""},
+			},
+			want: func() []goshopify.Product {
+				p := goshopify.Product{
+					Title: "foo",
+					Metafields: []goshopify.Metafield{{
+						Namespace: "custom",
+						Key:       "material",
+						Value:     "wool",
+						Type:      "single_line_text_field",
+					}},
+				}
+				p.Variants = append(p.Variants, goshopify.Variant{
+					Metafields: []goshopify.Metafield{
+						{
+							Namespace: "custom",
+							Key:       "pack_size",
+							Value:     int64(12),
+							Type:      "number_integer",
+						},
+						{
+							Namespace: "custom",
+							Key:       "notes",
+							Value:     "soft",
+						},
+					},
+				}, goshopify.Variant{})
+				return []goshopify.Product{p}
+			}(),
+		},
+		{
+			name: "invalid status",
+			rows: [][]string{
+				{keyTitle, keyStatus},
+				{"foo", "hidden"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid metafield value",
+			rows: [][]string{
+				{keyTitle, "variant.metafields.custom.pack_size"},
+				{"foo", "twelve"},
+			},
+			wantErr: true,
+		},
+	}
+	defs := config.MetafieldDefinitions{
+		Product: []config.MetafieldDefinition{
+			{Namespace: "custom", Key: "material", Type: "single_line_text_field"},
+		},
+		Variant: []config.MetafieldDefinition{
+			{Namespace: "custom", Key: "pack_size", Type: "number_integer"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := groupVariants(tt.rows)
+			got, _, err := groupVariants(tt.rows, defs)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("got error %q, wantErr %v", err, tt.wantErr)
 				return
@@ -178,3 +286,251 @@ func Test_groupVariants(t *testing.T) {
 		})
 	}
 }
+
+func Test_groupVariants_delete(t *testing.T) {
+	rows := [][]string{
+		{keyTitle, keySKU, keyDelete},
+		{"foo", "a", ""},
+		{"foo", "b", "TRUE"},
+		{"bar", "c", "true"},
+	}
+	products, deleted, err := groupVariants(rows, config.MetafieldDefinitions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantProducts := []goshopify.Product{
+		{Title: "foo", Variants: []goshopify.Variant{{Sku: "a"}}},
+	}
+	if !reflect.DeepEqual(products, wantProducts) {
+		t.Fatalf("\ngot: %+v\nwant: %+v", products, wantProducts)
+	}
+	wantDeleted := []goshopify.Product{
+		{Title: "foo", Variants: []goshopify.Variant{{Sku: "b"}}},
+		{Title: "bar", Variants: []goshopify.Variant{{Sku: "c"}}},
+	}
+	if !reflect.DeepEqual(deleted, wantDeleted) {
+		t.Fatalf("\ngot: %+v\nwant: %+v", deleted, wantDeleted)
+	}
+
+	t.Run("returns error for invalid marker", func(t *testing.T) {
+		rows := [][]string{
+			{keyTitle, keyDelete},
+			{"foo", "maybe"},
+		}
+		if _, _, err := groupVariants(rows, config.MetafieldDefinitions{}); err == nil {
+			t.Fatal("expected error but didn't get one")
+		}
+	})
+}
+
+func Test_parseInventory(t *testing.T) {
+	tests := []struct {
+		name      string
+		rows      [][]string
+		want      []memdb.InventoryQuantity
+		wantCosts []memdb.InventoryCost
+		wantErr   bool
+	}{
+		{
+			name: "no inventory columns",
+			rows: [][]string{
+				{keyTitle, keySKU},
+				{"foo", "a"},
+			},
+			want:      []memdb.InventoryQuantity{},
+			wantCosts: []memdb.InventoryCost{},
+		},
+		{
+			name: "absolute and relative quantities",
+			rows: [][]string{
+				{keyTitle, keySKU, inventoryKey("Warehouse"), inventoryKey("Shop"), keyDelete},
+				{"foo", "a", "12", "", ""},
+				{"foo", "b", "+3", "-2", ""},
+				{"foo", "c", "5", "5", "true"},
+			},
+			want: []memdb.InventoryQuantity{
+				{Variant: goshopify.Variant{Sku: "a"}, Location: "Warehouse", Available: 12},
+				{Variant: goshopify.Variant{Sku: "b"}, Location: "Warehouse", Available: 3, Relative: true},
+				{Variant: goshopify.Variant{Sku: "b"}, Location: "Shop", Available: -2, Relative: true},
+			},
+			wantCosts: []memdb.InventoryCost{},
+		},
+		{
+			name: "costs",
+			rows: [][]string{
+				{keyTitle, keySKU, keyCost},
+				{"foo", "a", "2.5"},
+				{"foo", "b", ""},
+			},
+			want: []memdb.InventoryQuantity{},
+			wantCosts: []memdb.InventoryCost{
+				{Variant: goshopify.Variant{Sku: "a"}, Cost: decimal.NewFromFloat(2.5)},
+			},
+		},
+		{
+			name: "invalid quantity",
+			rows: [][]string{
+				{keyTitle, keySKU, inventoryKey("Warehouse")},
+				{"foo", "a", "many"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotCosts, err := parseInventory(tt.rows)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("\ngot: %+v\nwant: %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(gotCosts, tt.wantCosts) {
+				t.Fatalf("\ngot costs: %+v\nwant: %+v", gotCosts, tt.wantCosts)
+			}
+		})
+	}
+}
+
+func Test_parseClearedFields(t *testing.T) {
+	rows := [][]string{
+		{keyTitle, keySKU, keyCompareAtPrice, keyTaxable, keyRequiresShipping},
+		{"foo", "a", "", "false", ""},
+		{"foo", "b", "10", "true", "FALSE"},
+		{"foo", "c", "10", "", "true"},
+	}
+	got, err := parseClearedFields(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []memdb.ClearedFields{
+		{Variant: goshopify.Variant{Sku: "a"}, Fields: []string{"compare_at_price", "taxable"}},
+		{Variant: goshopify.Variant{Sku: "b"}, Fields: []string{"requires_shipping"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("\ngot: %+v\nwant: %+v", got, want)
+	}
+}
+
+func Test_parseClearedProductFields(t *testing.T) {
+	rows := [][]string{
+		{keyProductID, keyTitle, keySKU, keyTags, keyBodyHTML, keySEOTitle, keyImageSrc},
+		{"1", "foo", "a", "", "<p>foo</p>", "", ""},
+		{"1", "foo", "b", "", "", "", ""},
+		{"1", "foo", "", "", "", "", "https://cdn/a.png"},
+		{"2", "bar", "c", "sale", "<p>bar</p>", "Bar", ""},
+	}
+	got, err := parseClearedProductFields(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []memdb.ClearedProductFields{{
+		Product: goshopify.Product{ID: 1, Title: "foo"},
+		Fields:  []string{"tags", "metafields_global_title_tag"},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("\ngot: %+v\nwant: %+v", got, want)
+	}
+}
+
+func Test_parseImages(t *testing.T) {
+	rows := [][]string{
+		{keyTitle, keySKU, keyPrice, keyImageSrc, keyImageAlt, keyImagePosition, keyVariantImage},
+		{"foo", "a", "10", "https://cdn/a.png", "front", "2", "images/b.png"},
+		{"foo", "b", "10", "", "", "", ""},
+		{"foo", "", "", "images/b.png", "", "", ""},
+		{"bar", "c", "10", "", "", "", ""},
+	}
+	got, err := parseImages(rows, "/data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []memdb.ProductImages{{
+		Product: goshopify.Product{Title: "foo"},
+		Images: []memdb.Image{
+			{Src: "https://cdn/a.png", Alt: "front", Position: 2},
+			{Src: "/data/images/b.png", Position: 2},
+		},
+		Variants: []memdb.VariantImage{
+			{Variant: goshopify.Variant{Sku: "a"}, Src: "/data/images/b.png"},
+		},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("\ngot: %+v\nwant: %+v", got, want)
+	}
+
+	t.Run("image rows are not variants", func(t *testing.T) {
+		products, _, err := groupVariants(rows, config.MetafieldDefinitions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := len(products[0].Variants); n != 2 {
+			t.Fatalf("got %v variants, want 2", n)
+		}
+	})
+}
+
+func Test_parseMetafieldKey(t *testing.T) {
+	tests := []struct {
+		colName   string
+		owner     string
+		namespace string
+		key       string
+		ok        bool
+	}{
+		{"product.metafields.custom.material", "product", "custom", "material", true},
+		{"variant.metafields.custom.pack_size", "variant", "custom", "pack_size", true},
+		{"order.metafields.custom.material", "", "", "", false},
+		{"product.metafields.custom", "", "", "", false},
+		{keyTitle, "", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.colName, func(t *testing.T) {
+			owner, namespace, key, ok := parseMetafieldKey(tt.colName)
+			if owner != tt.owner || namespace != tt.namespace || key != tt.key || ok != tt.ok {
+				t.Fatalf(
+					"got (%q, %q, %q, %v), want (%q, %q, %q, %v)",
+					owner, namespace, key, ok,
+					tt.owner, tt.namespace, tt.key, tt.ok,
+				)
+			}
+		})
+	}
+}
+
+func Test_parseMetafieldValue(t *testing.T) {
+	tests := []struct {
+		typ     string
+		s       string
+		want    any
+		wantErr bool
+	}{
+		{typ: "single_line_text_field", s: "foo", want: "foo"},
+		{typ: "number_integer", s: "42", want: int64(42)},
+		{typ: "number_integer", s: "4.2", wantErr: true},
+		{typ: "number_decimal", s: "4.20", want: "4.20"},
+		{typ: "number_decimal", s: "foo", wantErr: true},
+		{typ: "boolean", s: "true", want: true},
+		{typ: "boolean", s: "foo", wantErr: true},
+		{typ: "weight", s: `{"value":1.5,"unit":"kg"}`, want: `{"value":1.5,"unit":"kg"}`},
+		{typ: "weight", s: "1.5 kg", wantErr: true},
+		{typ: "json", s: `{"foo":"bar"}`, want: `{"foo":"bar"}`},
+		{typ: "json", s: `{foo`, wantErr: true},
+		{typ: "list.single_line_text_field", s: `["a","b"]`, want: `["a","b"]`},
+		{typ: "list.single_line_text_field", s: `a,b`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typ+"/"+tt.s, func(t *testing.T) {
+			got, err := parseMetafieldValue(tt.typ, tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,24 +4,33 @@ import (
 	"encoding/csv"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
 
 	goshopify "github.com/bold-commerce/go-shopify/v3"
 	"github.com/samherrmann/merchant/collection"
+	"github.com/samherrmann/merchant/memdb"
 )
 
 const (
 	ProductsFilename = "products.csv"
 )
 
-func WriteProductsFile(products []goshopify.Product) error {
-	rows, err := makeRowsFromProducts(products)
+// WriteProductsFile writes the products to ProductsFilename. If stock is not
+// nil, then the cost of every variant and its available quantity at every
+// active location are written as well. The images of a product are written to
+// its rows in the order of their position, with additional rows that only
+// contain an image if the product has more images than variants. alts are the
+// alt texts of the images.
+func WriteProductsFile(products []goshopify.Product, stock *memdb.Stock, alts memdb.ImageAlts) error {
+	rows, err := makeRowsFromProducts(products, stock, alts)
 	if err != nil {
 		return err
 	}
 	return writeFile(ProductsFilename, rows)
 }
 
-func makeRowsFromProducts(products []goshopify.Product) ([][]string, error) {
+func makeRowsFromProducts(products []goshopify.Product, stock *memdb.Stock, alts memdb.ImageAlts) ([][]string, error) {
 	colIndexes := make(map[string]int)
 	colIndexes[keyProductID] = len(colIndexes)
 	colIndexes[keyVariantID] = len(colIndexes)
@@ -30,9 +39,27 @@ func makeRowsFromProducts(products []goshopify.Product) ([][]string, error) {
 	colIndexes[keyTitle] = len(colIndexes)
 	colIndexes[keyVendor] = len(colIndexes)
 	colIndexes[keyProductType] = len(colIndexes)
+	colIndexes[keyBodyHTML] = len(colIndexes)
+	colIndexes[keyTags] = len(colIndexes)
+	colIndexes[keyStatus] = len(colIndexes)
+	colIndexes[keyHandle] = len(colIndexes)
+	colIndexes[keyTemplateSuffix] = len(colIndexes)
+	colIndexes[keyPublishedScope] = len(colIndexes)
+	colIndexes[keySEOTitle] = len(colIndexes)
+	colIndexes[keySEODescription] = len(colIndexes)
 	colIndexes[keyWeight] = len(colIndexes)
 	colIndexes[keyWeightUnit] = len(colIndexes)
 	colIndexes[keyPrice] = len(colIndexes)
+	colIndexes[keyCompareAtPrice] = len(colIndexes)
+	colIndexes[keyCost] = len(colIndexes)
+	colIndexes[keyInventoryPolicy] = len(colIndexes)
+	colIndexes[keyTaxable] = len(colIndexes)
+	colIndexes[keyRequiresShipping] = len(colIndexes)
+	colIndexes[keyFulfillmentService] = len(colIndexes)
+	colIndexes[keyImageSrc] = len(colIndexes)
+	colIndexes[keyImageAlt] = len(colIndexes)
+	colIndexes[keyImagePosition] = len(colIndexes)
+	colIndexes[keyVariantImage] = len(colIndexes)
 	colIndexes[keyOption1Name] = len(colIndexes)
 	colIndexes[keyOption1Value] = len(colIndexes)
 	colIndexes[keyOption2Name] = len(colIndexes)
@@ -40,10 +67,22 @@ func makeRowsFromProducts(products []goshopify.Product) ([][]string, error) {
 	colIndexes[keyOption3Name] = len(colIndexes)
 	colIndexes[keyOption3Value] = len(colIndexes)
 
+	locations := []goshopify.Location{}
+	if stock != nil {
+		for _, l := range stock.Locations {
+			if l.Active {
+				locations = append(locations, l)
+				colIndexes[inventoryKey(l.Name)] = len(colIndexes)
+			}
+		}
+	}
+
 	// Initialize rows with one row for the heading. We will come back at the end
 	// to populate it with all the columns.
 	rows := [][]string{{}}
 	for _, p := range products {
+		images := sortedImages(p.Images)
+		first := len(rows)
 
 		for _, v := range p.Variants {
 			row := make([]string, len(colIndexes))
@@ -56,9 +95,42 @@ func makeRowsFromProducts(products []goshopify.Product) ([][]string, error) {
 			row[colIndexes[keyTitle]] = p.Title
 			row[colIndexes[keyVendor]] = p.Vendor
 			row[colIndexes[keyProductType]] = p.ProductType
+			row[colIndexes[keyBodyHTML]] = p.BodyHTML
+			row[colIndexes[keyTags]] = p.Tags
+			row[colIndexes[keyStatus]] = p.Status
+			row[colIndexes[keyHandle]] = p.Handle
+			row[colIndexes[keyTemplateSuffix]] = p.TemplateSuffix
+			row[colIndexes[keyPublishedScope]] = p.PublishedScope
+			row[colIndexes[keySEOTitle]] = p.MetafieldsGlobalTitleTag
+			row[colIndexes[keySEODescription]] = p.MetafieldsGlobalDescriptionTag
 			row[colIndexes[keyWeight]] = fmt.Sprintf("%v", weight)
 			row[colIndexes[keyWeightUnit]] = v.WeightUnit
 			row[colIndexes[keyPrice]] = fmt.Sprintf("%v", price)
+			if v.CompareAtPrice != nil {
+				compareAtPrice, _ := v.CompareAtPrice.Float64()
+				row[colIndexes[keyCompareAtPrice]] = fmt.Sprintf("%v", compareAtPrice)
+			}
+			if stock != nil {
+				if item, ok := stock.Item(v.InventoryItemId); ok && item.Cost != nil {
+					cost, _ := item.Cost.Float64()
+					row[colIndexes[keyCost]] = fmt.Sprintf("%v", cost)
+				}
+			}
+			row[colIndexes[keyInventoryPolicy]] = v.InventoryPolicy
+			row[colIndexes[keyTaxable]] = strconv.FormatBool(v.Taxable)
+			row[colIndexes[keyRequiresShipping]] = strconv.FormatBool(v.RequireShipping)
+			row[colIndexes[keyFulfillmentService]] = v.FulfillmentService
+			for _, img := range images {
+				if img.ID == v.ImageID {
+					row[colIndexes[keyVariantImage]] = img.Src
+				}
+			}
+			for _, l := range locations {
+				// Items that are not stocked at a location are left empty.
+				if level, ok := stock.Level(v.InventoryItemId, l.ID); ok {
+					row[colIndexes[inventoryKey(l.Name)]] = strconv.Itoa(level.Available)
+				}
+			}
 
 			if len(p.Options) > 0 {
 				if p.Options[0].Name != "Title" {
@@ -78,7 +150,7 @@ func makeRowsFromProducts(products []goshopify.Product) ([][]string, error) {
 			}
 
 			attachMetafield := func(metaType string, m goshopify.Metafield) {
-				key := fmt.Sprintf("%s.metafields.%s.%s", metaType, m.Namespace, m.Key)
+				key := metafieldKey(metaType, m.Namespace, m.Key)
 				index, exists := colIndexes[key]
 				// If this is the first time encountering this metafield, then add it to
 				// the colPositions map and grow the row slice.
@@ -87,17 +159,30 @@ func makeRowsFromProducts(products []goshopify.Product) ([][]string, error) {
 					colIndexes[key] = index
 					row = append(row, "")
 				}
-				row[index] = fmt.Sprintf("%v", m.Value)
+				row[index] = memdb.MetafieldValue(m.Value)
 			}
 
 			for _, m := range p.Metafields {
-				attachMetafield("product", m)
+				attachMetafield(metafieldOwnerProduct, m)
 			}
 			for _, m := range v.Metafields {
-				attachMetafield("variant", m)
+				attachMetafield(metafieldOwnerVariant, m)
 			}
 			rows = append(rows, row)
 		}
+
+		for i, img := range images {
+			if first+i == len(rows) {
+				row := make([]string, len(colIndexes))
+				row[colIndexes[keyProductID]] = fmt.Sprintf("%v", p.ID)
+				row[colIndexes[keyTitle]] = p.Title
+				rows = append(rows, row)
+			}
+			row := rows[first+i]
+			row[colIndexes[keyImageSrc]] = img.Src
+			row[colIndexes[keyImageAlt]] = alts[img.ID]
+			row[colIndexes[keyImagePosition]] = strconv.Itoa(img.Position)
+		}
 	}
 	// Populate the first row with all column names.
 	rows[0] = make([]string, len(colIndexes))
@@ -107,6 +192,15 @@ func makeRowsFromProducts(products []goshopify.Product) ([][]string, error) {
 	return padRows(rows), nil
 }
 
+// sortedImages returns a copy of images ordered by position.
+func sortedImages(images []goshopify.Image) []goshopify.Image {
+	sorted := append([]goshopify.Image{}, images...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Position < sorted[j].Position
+	})
+	return sorted
+}
+
 func writeFile(filename string, rows [][]string) error {
 	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
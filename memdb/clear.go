@@ -0,0 +1,201 @@
+package memdb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+)
+
+// ClearableVariantFields are the JSON names of the variant fields that can be
+// cleared, i.e. set to false, an empty string or null. Variant updates can't
+// clear fields because zero values are omitted from their JSON encoding.
+var ClearableVariantFields = []string{"compare_at_price", "barcode", "taxable", "requires_shipping"}
+
+// ClearableProductFields are the JSON names of the product fields that can be
+// cleared, i.e. set to an empty string. Product updates can't clear fields
+// because empty strings are omitted from their JSON encoding.
+var ClearableProductFields = []string{
+	"body_html",
+	"tags",
+	"metafields_global_title_tag",
+	"metafields_global_description_tag",
+}
+
+// ClearedFields are the fields of an incoming variant that are empty or false.
+type ClearedFields struct {
+	// Variant identifies the variant the same way as the variants of incoming
+	// products.
+	Variant goshopify.Variant
+	// Fields are the JSON names of the fields, see ClearableVariantFields.
+	Fields []string
+}
+
+// ClearedProductFields are the fields of an incoming product that are empty.
+type ClearedProductFields struct {
+	// Product identifies the product the same way as incoming products, i.e.
+	// by ID, handle or title.
+	Product goshopify.Product
+	// Fields are the JSON names of the fields, see ClearableProductFields.
+	Fields []string
+}
+
+// ProductReset sets fields of an existing product to an empty string. Tags are
+// cleared by a [TagUpdate] instead, so that they are removed the same way as
+// other tags.
+type ProductReset struct {
+	ID int64
+	// Fields maps the JSON names of the fields to their new value.
+	Fields map[string]any
+}
+
+// VariantReset sets fields of an existing variant to false, an empty string or
+// null.
+type VariantReset struct {
+	ID        int64
+	ProductID int64
+	// Fields maps the JSON names of the fields to their new value.
+	Fields map[string]any
+}
+
+// ClearOperations appends a reset to operations for every existing variant in
+// cleared of which any of the fields is currently set. The changed fields are
+// added to the diff of the variant. New variants are skipped because the fields
+// can't be cleared until the variants exist.
+func (db *MemoryDB) ClearOperations(operations *Operations, cleared []ClearedFields) error {
+	for _, c := range cleared {
+		v := c.Variant
+		err := db.Variants().PatchID(&v)
+		if errors.Is(err, ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		current, _ := db.Variants().GetByID(v.ID)
+		reset := VariantReset{ID: current.ID, ProductID: current.ProductID, Fields: map[string]any{}}
+		fields := []FieldDiff{}
+		for _, name := range c.Fields {
+			f, ok := clearableField(current, ClearableVariantFields, name)
+			if !ok {
+				return fmt.Errorf("variant field %q cannot be cleared", name)
+			}
+			if f.IsZero() {
+				continue
+			}
+			var value any
+			switch f.Kind() {
+			case reflect.Bool:
+				value = false
+			case reflect.String:
+				value = ""
+			}
+			reset.Fields[name] = value
+			fields = append(fields, FieldDiff{
+				Name:   name,
+				Before: formatValue(f),
+				After:  formatValue(reflect.Zero(f.Type())),
+			})
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		operations.ResetVariant(reset)
+		operations.addVariantDiff(current, db.productTitle(current), fields)
+	}
+	db.setBaseline(operations)
+	return nil
+}
+
+// ClearProductOperations appends a reset or a tag update to operations for
+// every existing product in cleared of which any of the fields is currently
+// set. The changed fields are added to the diff of the product. New products
+// are skipped because they are created without the fields anyway.
+func (db *MemoryDB) ClearProductOperations(operations *Operations, cleared []ClearedProductFields) error {
+	for _, c := range cleared {
+		p := c.Product
+		if err := db.Products().PatchID(&p); err != nil {
+			return err
+		}
+		if p.ID == 0 {
+			continue
+		}
+		current, _ := db.Products().GetByID(p.ID)
+		reset := ProductReset{ID: current.ID, Fields: map[string]any{}}
+		fields := []FieldDiff{}
+		for _, name := range c.Fields {
+			f, ok := clearableField(current, ClearableProductFields, name)
+			if !ok {
+				return fmt.Errorf("product field %q cannot be cleared", name)
+			}
+			if f.IsZero() {
+				continue
+			}
+			fields = append(fields, FieldDiff{Name: name, Before: formatValue(f)})
+			if name == "tags" {
+				operations.UpdateTags(TagUpdate{ProductID: current.ID, Remove: SplitTags(current.Tags)})
+				continue
+			}
+			reset.Fields[name] = ""
+		}
+		if len(reset.Fields) > 0 {
+			operations.ResetProduct(reset)
+		}
+		if len(fields) > 0 {
+			operations.addProductDiff(current, fields)
+		}
+	}
+	db.setBaseline(operations)
+	return nil
+}
+
+// clearableField returns the field of v, a pointer to a struct, with the given
+// JSON name if it is one of clearable.
+func clearableField(v any, clearable []string, name string) (reflect.Value, bool) {
+	found := false
+	for _, f := range clearable {
+		found = found || f == name
+	}
+	if !found {
+		return reflect.Value{}, false
+	}
+	rv := reflect.ValueOf(v).Elem()
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if jsonName(t.Field(i)) == name {
+			return rv.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// addVariantDiff adds fields to the diff of variant v. If v has no diff yet,
+// then one is added and v is no longer counted as unchanged.
+func (s *Operations) addVariantDiff(v *goshopify.Variant, productTitle string, fields []FieldDiff) {
+	for i := range s.Diffs {
+		d := &s.Diffs[i]
+		if d.Resource == ResourceVariant && d.ID == v.ID {
+			d.Fields = append(d.Fields, fields...)
+			return
+		}
+	}
+	if s.UnchangedVariants > 0 {
+		s.UnchangedVariants--
+	}
+	s.Diffs = append(s.Diffs, Diff{
+		Resource: ResourceVariant,
+		ID:       v.ID,
+		Title:    fmt.Sprintf("%v (%v)", productTitle, v.Title),
+		Fields:   fields,
+	})
+}
+
+// productTitle returns the title of the product of v, or an empty string if
+// the product is not in the database.
+func (db *MemoryDB) productTitle(v *goshopify.Variant) string {
+	if p, exists := db.Products().GetByID(v.ProductID); exists {
+		return p.Title
+	}
+	return ""
+}
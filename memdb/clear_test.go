@@ -0,0 +1,135 @@
+package memdb
+
+import (
+	"reflect"
+	"testing"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/shopspring/decimal"
+)
+
+func TestMemoryDB_ClearOperations(t *testing.T) {
+	compareAtPrice := decimal.NewFromInt(20)
+	db, err := New([]goshopify.Product{{
+		ID:    1,
+		Title: "foo",
+		Variants: []goshopify.Variant{
+			{ID: 11, ProductID: 1, Sku: "foo-1", Title: "S", Option1: "S", CompareAtPrice: &compareAtPrice, Taxable: true},
+			{ID: 12, ProductID: 1, Sku: "foo-2", Title: "M", Option1: "M", RequireShipping: true},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := &Operations{
+		UnchangedVariants: 1,
+		Diffs: []Diff{{
+			Resource: ResourceVariant,
+			ID:       12,
+			Title:    "foo (M)",
+			Fields:   []FieldDiff{{Name: "sku", Before: "foo-2", After: "foo-m"}},
+		}},
+	}
+	cleared := []ClearedFields{
+		{Variant: goshopify.Variant{Sku: "foo-1"}, Fields: []string{"compare_at_price", "taxable", "requires_shipping"}},
+		{Variant: goshopify.Variant{ID: 12}, Fields: []string{"requires_shipping"}},
+		{Variant: goshopify.Variant{Sku: "foo-3"}, Fields: []string{"taxable"}},
+	}
+	if err := db.ClearOperations(ops, cleared); err != nil {
+		t.Fatal(err)
+	}
+
+	wantResets := []VariantReset{
+		{ID: 11, ProductID: 1, Fields: map[string]any{"compare_at_price": nil, "taxable": false}},
+		{ID: 12, ProductID: 1, Fields: map[string]any{"requires_shipping": false}},
+	}
+	if !reflect.DeepEqual(ops.VariantResets, wantResets) {
+		t.Fatalf("got resets %+v, want %+v", ops.VariantResets, wantResets)
+	}
+	wantDiffs := []Diff{
+		{
+			Resource: ResourceVariant,
+			ID:       12,
+			Title:    "foo (M)",
+			Fields: []FieldDiff{
+				{Name: "sku", Before: "foo-2", After: "foo-m"},
+				{Name: "requires_shipping", Before: "true", After: "false"},
+			},
+		},
+		{
+			Resource: ResourceVariant,
+			ID:       11,
+			Title:    "foo (S)",
+			Fields: []FieldDiff{
+				{Name: "compare_at_price", Before: "20", After: ""},
+				{Name: "taxable", Before: "true", After: "false"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(ops.Diffs, wantDiffs) {
+		t.Fatalf("got diffs %+v, want %+v", ops.Diffs, wantDiffs)
+	}
+	if ops.UnchangedVariants != 0 {
+		t.Fatalf("got %v unchanged variants, want 0", ops.UnchangedVariants)
+	}
+
+	t.Run("returns error for field that cannot be cleared", func(t *testing.T) {
+		cleared := []ClearedFields{{Variant: goshopify.Variant{Sku: "foo-1"}, Fields: []string{"price"}}}
+		if err := db.ClearOperations(&Operations{}, cleared); err == nil {
+			t.Fatal("expected error but didn't get one")
+		}
+	})
+}
+
+func TestMemoryDB_ClearProductOperations(t *testing.T) {
+	db, err := New([]goshopify.Product{
+		{ID: 1, Title: "foo", Tags: "sale, cotton", BodyHTML: "<p>foo</p>", MetafieldsGlobalTitleTag: "Foo"},
+		{ID: 2, Title: "bar"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := &Operations{UnchangedProducts: 2}
+	cleared := []ClearedProductFields{
+		{Product: goshopify.Product{Title: "foo"}, Fields: []string{"body_html", "tags", "metafields_global_title_tag"}},
+		{Product: goshopify.Product{ID: 2}, Fields: []string{"tags", "body_html"}},
+		{Product: goshopify.Product{Title: "baz"}, Fields: []string{"tags"}},
+	}
+	if err := db.ClearProductOperations(ops, cleared); err != nil {
+		t.Fatal(err)
+	}
+
+	wantResets := []ProductReset{
+		{ID: 1, Fields: map[string]any{"body_html": "", "metafields_global_title_tag": ""}},
+	}
+	if !reflect.DeepEqual(ops.ProductResets, wantResets) {
+		t.Fatalf("got resets %+v, want %+v", ops.ProductResets, wantResets)
+	}
+	wantTags := []TagUpdate{{ProductID: 1, Remove: []string{"sale", "cotton"}}}
+	if !reflect.DeepEqual(ops.TagUpdates, wantTags) {
+		t.Fatalf("got tag updates %+v, want %+v", ops.TagUpdates, wantTags)
+	}
+	wantDiffs := []Diff{{
+		Resource: ResourceProduct,
+		ID:       1,
+		Title:    "foo",
+		Fields: []FieldDiff{
+			{Name: "body_html", Before: "<p>foo</p>"},
+			{Name: "tags", Before: "sale, cotton"},
+			{Name: "metafields_global_title_tag", Before: "Foo"},
+		},
+	}}
+	if !reflect.DeepEqual(ops.Diffs, wantDiffs) {
+		t.Fatalf("got diffs %+v, want %+v", ops.Diffs, wantDiffs)
+	}
+	if ops.UnchangedProducts != 1 {
+		t.Fatalf("got %v unchanged products, want 1", ops.UnchangedProducts)
+	}
+
+	t.Run("returns error for field that cannot be cleared", func(t *testing.T) {
+		cleared := []ClearedProductFields{{Product: goshopify.Product{ID: 1}, Fields: []string{"title"}}}
+		if err := db.ClearProductOperations(&Operations{}, cleared); err == nil {
+			t.Fatal("expected error but didn't get one")
+		}
+	})
+}
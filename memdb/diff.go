@@ -0,0 +1,292 @@
+package memdb
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/shopspring/decimal"
+)
+
+// Diff describes the changed fields of a product or variant.
+type Diff struct {
+	// Resource is the type of the changed resource, i.e. "product" or "variant".
+	Resource string
+	// ID is the ID of the changed resource.
+	ID int64
+	// Title is a human readable name of the changed resource.
+	Title string
+	// Fields are the changed fields.
+	Fields []FieldDiff
+}
+
+// FieldDiff describes the change of a single field.
+type FieldDiff struct {
+	// Name is the JSON name of the field as used by the Shopify API.
+	Name string
+	// Before is the current value of the field.
+	Before string
+	// After is the incoming value of the field.
+	After string
+}
+
+// productSkipFields are the product fields that are not diffed generically.
+var productSkipFields = map[string]bool{
+	"ID":                true,
+	"CreatedAt":         true,
+	"UpdatedAt":         true,
+	"PublishedAt":       true,
+	"Options":           true,
+	"Variants":          true,
+	"Image":             true,
+	"Images":            true,
+	"Metafields":        true,
+	"AdminGraphqlAPIID": true,
+}
+
+// variantSkipFields are the variant fields that are not diffed generically.
+var variantSkipFields = map[string]bool{
+	"ID":                   true,
+	"ProductID":            true,
+	"Title":                true,
+	"Position":             true,
+	"Grams":                true,
+	"InventoryItemId":      true,
+	"InventoryQuantity":    true,
+	"OldInventoryQuantity": true,
+	"CreatedAt":            true,
+	"UpdatedAt":            true,
+	"Metafields":           true,
+	"AdminGraphqlAPIID":    true,
+}
+
+// diffProduct compares incoming against the current product and appends the
+// required product, tag and metafield operations. Products without any changes
+// are counted as unchanged.
+func (s *Operations) diffProduct(current *goshopify.Product, incoming *goshopify.Product) error {
+	patch, fields := productPatch(current, incoming)
+	tags, tagField := diffTags(current, incoming)
+	metafields, err := s.diffMetafields(MetafieldOwnerProduct, current.ID, current.Metafields, incoming.Metafields)
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 && tags == nil && len(metafields) == 0 {
+		s.UnchangedProducts++
+		return nil
+	}
+	if len(fields) > 0 {
+		s.UpdateProduct(*patch)
+	}
+	if tags != nil {
+		s.UpdateTags(*tags)
+		fields = append(fields, *tagField)
+	}
+	s.Diffs = append(s.Diffs, Diff{
+		Resource: ResourceProduct,
+		ID:       current.ID,
+		Title:    current.Title,
+		Fields:   append(fields, metafields...),
+	})
+	return nil
+}
+
+// diffVariant compares incoming against the current variant and appends the
+// required variant and metafield operations. Variants without any changes are
+// counted as unchanged. productTitle is used to name the variant in the diff.
+func (s *Operations) diffVariant(current *goshopify.Variant, incoming *goshopify.Variant, productTitle string) error {
+	patch, fields := variantPatch(current, incoming)
+	metafields, err := s.diffMetafields(MetafieldOwnerVariant, current.ID, current.Metafields, incoming.Metafields)
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 && len(metafields) == 0 {
+		s.UnchangedVariants++
+		return nil
+	}
+	if len(fields) > 0 {
+		s.UpdateVariant(*patch)
+	}
+	s.Diffs = append(s.Diffs, Diff{
+		Resource: ResourceVariant,
+		ID:       current.ID,
+		Title:    fmt.Sprintf("%v (%v)", productTitle, current.Title),
+		Fields:   append(fields, metafields...),
+	})
+	return nil
+}
+
+// diffMetafields compares the incoming metafields of an owner against its
+// current metafields and appends the required create and update operations.
+// Unchanged metafields are skipped.
+func (s *Operations) diffMetafields(
+	owner string,
+	ownerID int64,
+	current []goshopify.Metafield,
+	incoming []goshopify.Metafield,
+) ([]FieldDiff, error) {
+	fields := []FieldDiff{}
+	for _, m := range incoming {
+		m.OwnerResource = owner
+		m.OwnerId = ownerID
+		name := fmt.Sprintf("metafields.%v.%v", m.Namespace, m.Key)
+		c := findMetafield(current, m.Namespace, m.Key)
+		if c == nil {
+			if m.Type == "" {
+				return nil, fmt.Errorf(
+					"%v %v: cannot create metafield %v.%v without a metafield definition",
+					owner,
+					ownerID,
+					m.Namespace,
+					m.Key,
+				)
+			}
+			s.CreateMetafield(m)
+			fields = append(fields, FieldDiff{Name: name, After: MetafieldValue(m.Value)})
+			continue
+		}
+		// Values are compared by the type of the current metafield, because
+		// incoming values from CSV files are always text.
+		if EqualMetafieldValues(c.Type, c.Value, m.Value) {
+			continue
+		}
+		m.ID = c.ID
+		if m.Type == "" {
+			m.Type = c.Type
+		}
+		s.UpdateMetafield(m)
+		fields = append(fields, FieldDiff{
+			Name:   name,
+			Before: MetafieldValue(c.Value),
+			After:  MetafieldValue(m.Value),
+		})
+	}
+	return fields, nil
+}
+
+// productPatch returns a product that contains the ID of current and all
+// fields of incoming that differ from current, together with a description of
+// those fields.
+func productPatch(current *goshopify.Product, incoming *goshopify.Product) (*goshopify.Product, []FieldDiff) {
+	patch := &goshopify.Product{ID: current.ID}
+	// Tags are compared as sets by diffTags.
+	untagged := *incoming
+	untagged.Tags = ""
+	fields := diffFields(current, &untagged, patch, productSkipFields)
+
+	// Options are compared by name only because the incoming options don't
+	// contain any values.
+	if len(incoming.Options) > 0 {
+		before := optionNames(current.Options)
+		after := optionNames(incoming.Options)
+		if before != after {
+			patch.Options = make([]goshopify.ProductOption, len(incoming.Options))
+			for i, o := range incoming.Options {
+				if i < len(current.Options) {
+					o.ID = current.Options[i].ID
+				}
+				patch.Options[i] = o
+			}
+			fields = append(fields, FieldDiff{Name: "options", Before: before, After: after})
+		}
+	}
+	return patch, fields
+}
+
+// variantPatch returns a variant that contains the IDs of current and all
+// fields of incoming that differ from current, together with a description of
+// those fields.
+func variantPatch(current *goshopify.Variant, incoming *goshopify.Variant) (*goshopify.Variant, []FieldDiff) {
+	patch := &goshopify.Variant{ID: current.ID, ProductID: current.ProductID}
+	fields := diffFields(current, incoming, patch, variantSkipFields)
+	return patch, fields
+}
+
+// diffFields compares the fields of the structs that current and incoming
+// point to. Fields that are set on incoming and differ from current are copied
+// to patch and returned. Fields that are zero on incoming are not compared
+// because the Shopify API omits empty values. Fields in skip are ignored.
+func diffFields(current any, incoming any, patch any, skip map[string]bool) []FieldDiff {
+	cv := reflect.ValueOf(current).Elem()
+	iv := reflect.ValueOf(incoming).Elem()
+	pv := reflect.ValueOf(patch).Elem()
+	t := iv.Type()
+
+	fields := []FieldDiff{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if skip[f.Name] || !f.IsExported() {
+			continue
+		}
+		after := iv.Field(i)
+		before := cv.Field(i)
+		if after.IsZero() || equalValues(before, after) {
+			continue
+		}
+		pv.Field(i).Set(after)
+		fields = append(fields, FieldDiff{
+			Name:   jsonName(f),
+			Before: formatValue(before),
+			After:  formatValue(after),
+		})
+	}
+	return fields
+}
+
+var decimalPtrType = reflect.TypeOf(&decimal.Decimal{})
+
+// equalValues reports whether a and b are equal. Decimals are compared by
+// numeric value so that for example 1.5 and 1.50 are equal.
+func equalValues(a reflect.Value, b reflect.Value) bool {
+	if a.Type() == decimalPtrType {
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return a.Interface().(*decimal.Decimal).Equal(*b.Interface().(*decimal.Decimal))
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// formatValue returns the string representation of v. Nil pointers are
+// represented by an empty string.
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		if v.Type() == decimalPtrType {
+			return v.Interface().(*decimal.Decimal).String()
+		}
+		v = v.Elem()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// jsonName returns the name of the field as used in its JSON encoding.
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// optionNames returns the names of the given options as a single string.
+func optionNames(options []goshopify.ProductOption) string {
+	names := make([]string, len(options))
+	for i, o := range options {
+		names[i] = o.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// findMetafield returns the metafield with the given namespace and key. Nil is
+// returned if no such metafield exists.
+func findMetafield(metafields []goshopify.Metafield, namespace string, key string) *goshopify.Metafield {
+	for i := range metafields {
+		if metafields[i].Namespace == namespace && metafields[i].Key == key {
+			return &metafields[i]
+		}
+	}
+	return nil
+}
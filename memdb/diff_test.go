@@ -0,0 +1,168 @@
+package memdb
+
+import (
+	"reflect"
+	"testing"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/shopspring/decimal"
+)
+
+func TestOperations_diffMetafields(t *testing.T) {
+	current := []goshopify.Metafield{
+		{ID: 1, Namespace: "custom", Key: "material", Value: "wool", Type: "single_line_text_field"},
+		{ID: 2, Namespace: "custom", Key: "pack_size", Value: "12", Type: "number_integer"},
+	}
+
+	t.Run("skips unchanged metafields", func(t *testing.T) {
+		ops := &Operations{}
+		incoming := []goshopify.Metafield{
+			{Namespace: "custom", Key: "material", Value: "wool"},
+			{Namespace: "custom", Key: "pack_size", Value: int64(12), Type: "number_integer"},
+		}
+		if _, err := ops.diffMetafields(MetafieldOwnerProduct, 10, current, incoming); err != nil {
+			t.Fatal(err)
+		}
+		if len(ops.NewMetafields) != 0 || len(ops.MetafieldUpdates) != 0 {
+			t.Fatalf("got %+v, want no operations", ops)
+		}
+	})
+
+	t.Run("compares values by type", func(t *testing.T) {
+		ops := &Operations{}
+		current := []goshopify.Metafield{
+			{ID: 3, Namespace: "custom", Key: "stock", Value: float64(1000000), Type: "number_integer"},
+			{ID: 4, Namespace: "custom", Key: "ratio", Value: "1.50", Type: "number_decimal"},
+		}
+		incoming := []goshopify.Metafield{
+			{Namespace: "custom", Key: "stock", Value: "1000000"},
+			{Namespace: "custom", Key: "ratio", Value: "1.5"},
+		}
+		if _, err := ops.diffMetafields(MetafieldOwnerProduct, 10, current, incoming); err != nil {
+			t.Fatal(err)
+		}
+		if len(ops.MetafieldUpdates) != 0 {
+			t.Fatalf("got updates %+v, want none", ops.MetafieldUpdates)
+		}
+	})
+
+	t.Run("updates changed metafields", func(t *testing.T) {
+		ops := &Operations{}
+		incoming := []goshopify.Metafield{
+			{Namespace: "custom", Key: "material", Value: "cotton"},
+		}
+		if _, err := ops.diffMetafields(MetafieldOwnerVariant, 10, current, incoming); err != nil {
+			t.Fatal(err)
+		}
+		if len(ops.MetafieldUpdates) != 1 {
+			t.Fatalf("got %v updates, want 1", len(ops.MetafieldUpdates))
+		}
+		got := ops.MetafieldUpdates[0]
+		if got.ID != 1 || got.OwnerId != 10 || got.OwnerResource != MetafieldOwnerVariant {
+			t.Fatalf("got %+v", got)
+		}
+		if got.Type != "single_line_text_field" {
+			t.Fatalf("got type %q, want type of current metafield", got.Type)
+		}
+	})
+
+	t.Run("creates new metafields", func(t *testing.T) {
+		ops := &Operations{}
+		incoming := []goshopify.Metafield{
+			{Namespace: "custom", Key: "color", Value: "red", Type: "single_line_text_field"},
+		}
+		if _, err := ops.diffMetafields(MetafieldOwnerProduct, 10, current, incoming); err != nil {
+			t.Fatal(err)
+		}
+		if len(ops.NewMetafields) != 1 {
+			t.Fatalf("got %v new metafields, want 1", len(ops.NewMetafields))
+		}
+	})
+
+	t.Run("returns error for new metafield without type", func(t *testing.T) {
+		ops := &Operations{}
+		incoming := []goshopify.Metafield{
+			{Namespace: "custom", Key: "color", Value: "red"},
+		}
+		if _, err := ops.diffMetafields(MetafieldOwnerProduct, 10, current, incoming); err == nil {
+			t.Fatal("expected error but didn't get one")
+		}
+	})
+}
+
+func Test_productPatch(t *testing.T) {
+	current := &goshopify.Product{
+		ID:          1,
+		Title:       "foo",
+		Vendor:      "acme",
+		ProductType: "shirt",
+		Options:     []goshopify.ProductOption{{ID: 7, Name: "Size", Values: []string{"S"}}},
+	}
+
+	t.Run("returns no fields for unchanged product", func(t *testing.T) {
+		incoming := &goshopify.Product{
+			Title:   "foo",
+			Vendor:  "acme",
+			Options: []goshopify.ProductOption{{Name: "Size"}},
+		}
+		_, fields := productPatch(current, incoming)
+		if len(fields) != 0 {
+			t.Fatalf("got %+v, want no fields", fields)
+		}
+	})
+
+	t.Run("returns changed fields only", func(t *testing.T) {
+		incoming := &goshopify.Product{
+			Title:   "foo",
+			Vendor:  "globex",
+			Options: []goshopify.ProductOption{{Name: "Colour"}},
+		}
+		patch, fields := productPatch(current, incoming)
+		want := &goshopify.Product{
+			ID:      1,
+			Vendor:  "globex",
+			Options: []goshopify.ProductOption{{ID: 7, Name: "Colour"}},
+		}
+		if !reflect.DeepEqual(patch, want) {
+			t.Fatalf("\ngot: %+v\nwant: %+v", patch, want)
+		}
+		wantFields := []FieldDiff{
+			{Name: "vendor", Before: "acme", After: "globex"},
+			{Name: "options", Before: "Size", After: "Colour"},
+		}
+		if !reflect.DeepEqual(fields, wantFields) {
+			t.Fatalf("\ngot: %+v\nwant: %+v", fields, wantFields)
+		}
+	})
+}
+
+func Test_variantPatch(t *testing.T) {
+	price := decimal.RequireFromString("7.50")
+	current := &goshopify.Variant{ID: 2, ProductID: 1, Sku: "foo", Price: &price}
+
+	t.Run("compares decimals by value", func(t *testing.T) {
+		incomingPrice := decimal.NewFromFloat(7.5)
+		incoming := &goshopify.Variant{Sku: "foo", Price: &incomingPrice}
+		_, fields := variantPatch(current, incoming)
+		if len(fields) != 0 {
+			t.Fatalf("got %+v, want no fields", fields)
+		}
+	})
+
+	t.Run("returns changed fields only", func(t *testing.T) {
+		incomingPrice := decimal.NewFromFloat(8)
+		incoming := &goshopify.Variant{Sku: "foo", Barcode: "123", Price: &incomingPrice}
+		patch, fields := variantPatch(current, incoming)
+		want := &goshopify.Variant{ID: 2, ProductID: 1, Barcode: "123", Price: &incomingPrice}
+		if !reflect.DeepEqual(patch, want) {
+			t.Fatalf("\ngot: %+v\nwant: %+v", patch, want)
+		}
+		wantFields := []FieldDiff{
+			{Name: "price", Before: "7.5", After: "8"},
+			{Name: "barcode", Before: "", After: "123"},
+		}
+		if !reflect.DeepEqual(fields, wantFields) {
+			t.Fatalf("\ngot: %+v\nwant: %+v", fields, wantFields)
+		}
+	})
+}
@@ -0,0 +1,269 @@
+package memdb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+)
+
+// ImageAlts maps the IDs of product images to their alt text. The alt text is
+// kept separately from the products because [goshopify.Image] doesn't have it.
+type ImageAlts map[int64]string
+
+// CreatedImages maps the sources of incoming images to the IDs of the images
+// that earlier pushes created from them. The store replaces the source of the
+// images that it creates with a URL of its own, so an incoming image that has
+// already been created only matches its image through CreatedImages.
+type CreatedImages map[string]int64
+
+// Image is an incoming image of a product.
+type Image struct {
+	// Src is the URL of the image, or the path of a local file for images that
+	// are not uploaded yet.
+	Src      string
+	Alt      string
+	Position int
+}
+
+// VariantImage assigns the image with the given source to a variant.
+type VariantImage struct {
+	// Variant identifies the variant the same way as the variants of incoming
+	// products.
+	Variant goshopify.Variant
+	Src     string
+}
+
+// ProductImages are the incoming images of a product and the images of its
+// variants.
+type ProductImages struct {
+	// Product identifies the product the same way as incoming products, i.e.
+	// by ID, handle or title.
+	Product  goshopify.Product
+	Images   []Image
+	Variants []VariantImage
+}
+
+// ImageChange creates, updates or deletes an image of a product.
+type ImageChange struct {
+	// ID is zero for new images.
+	ID        int64 `json:",omitempty"`
+	ProductID int64
+	// Title names the product in reports.
+	Title string
+	Src   string `json:",omitempty"`
+	// Alt is nil if the alt text doesn't change.
+	Alt      *string `json:",omitempty"`
+	Position int     `json:",omitempty"`
+	// VariantIDs is nil if the variants of the image don't change. It is not
+	// omitted from the JSON encoding because an empty list removes the image
+	// from all variants.
+	VariantIDs []int64
+}
+
+// ImageOperations appends the image changes to operations that turn the images
+// of the existing products into the incoming images. Images are matched by
+// their source, or by the image that has been created from the source
+// according to created. If the incoming images of a product are not empty,
+// then images of the product that are not among them are deleted. Variant
+// images only change the variants that are listed. Products and variants are
+// matched the same way as in [MemoryDB.Operations], but they must already
+// exist.
+func (db *MemoryDB) ImageOperations(operations *Operations, incoming []ProductImages, alts ImageAlts, created CreatedImages) error {
+	for _, in := range incoming {
+		p := in.Product
+		if err := db.Products().PatchID(&p); err != nil {
+			return err
+		}
+		if p.ID == 0 {
+			return fmt.Errorf("cannot set images of new product %q, push the product first", p.Title)
+		}
+		current, _ := db.Products().GetByID(p.ID)
+		in = resolveCreatedImages(in, current, created)
+		if err := db.imageOperations(operations, current, in, alts); err != nil {
+			return fmt.Errorf("product %q: %w", current.Title, err)
+		}
+	}
+	db.setBaseline(operations)
+	return nil
+}
+
+func (db *MemoryDB) imageOperations(operations *Operations, current *goshopify.Product, in ProductImages, alts ImageAlts) error {
+	// variants maps the source of every current and incoming image to the IDs
+	// of its variants.
+	variants := map[string][]int64{}
+	existing := map[string]*goshopify.Image{}
+	for i := range current.Images {
+		img := &current.Images[i]
+		existing[img.Src] = img
+		variants[img.Src] = append([]int64{}, img.VariantIds...)
+	}
+	for _, vi := range in.Variants {
+		v, _, err := db.existingVariant(vi.Variant, "image")
+		if err != nil {
+			return err
+		}
+		if v.ProductID != current.ID {
+			return fmt.Errorf("variant %v belongs to another product", v.ID)
+		}
+		if _, ok := existing[vi.Src]; !ok && !hasImage(in.Images, vi.Src) {
+			return fmt.Errorf("image %q of variant %v is not an image of the product", vi.Src, v.ID)
+		}
+		// A variant has at most one image.
+		for src, ids := range variants {
+			variants[src] = removeID(ids, v.ID)
+		}
+		variants[vi.Src] = append(variants[vi.Src], v.ID)
+	}
+
+	fields := []FieldDiff{}
+	for _, img := range in.Images {
+		c, ok := existing[img.Src]
+		if !ok {
+			alt := img.Alt
+			operations.CreateImage(ImageChange{
+				ProductID:  current.ID,
+				Title:      current.Title,
+				Src:        img.Src,
+				Alt:        &alt,
+				Position:   img.Position,
+				VariantIDs: sortedIDs(variants[img.Src]),
+			})
+			fields = append(fields, FieldDiff{Name: "images", After: img.Src})
+			continue
+		}
+		change := ImageChange{ID: c.ID, ProductID: current.ID, Title: current.Title}
+		name := fmt.Sprintf("images.%v", c.ID)
+		if img.Alt != alts[c.ID] {
+			alt := img.Alt
+			change.Alt = &alt
+			fields = append(fields, FieldDiff{Name: name + ".alt", Before: alts[c.ID], After: img.Alt})
+		}
+		if img.Position != 0 && img.Position != c.Position {
+			change.Position = img.Position
+			fields = append(fields, FieldDiff{
+				Name:   name + ".position",
+				Before: fmt.Sprint(c.Position),
+				After:  fmt.Sprint(img.Position),
+			})
+		}
+		variantsChanged := changeVariantIDs(&change, c, variants[c.Src], name, &fields)
+		if variantsChanged || change.Alt != nil || change.Position != 0 {
+			operations.UpdateImage(change)
+		}
+	}
+	for i := range current.Images {
+		c := &current.Images[i]
+		if hasImage(in.Images, c.Src) {
+			continue
+		}
+		if len(in.Images) > 0 {
+			operations.DeleteImage(ImageChange{ID: c.ID, ProductID: current.ID, Title: current.Title, Src: c.Src})
+			fields = append(fields, FieldDiff{Name: "images", Before: c.Src})
+			continue
+		}
+		// Without incoming images, only the variants of the images change.
+		change := ImageChange{ID: c.ID, ProductID: current.ID, Title: current.Title}
+		if changeVariantIDs(&change, c, variants[c.Src], fmt.Sprintf("images.%v", c.ID), &fields) {
+			operations.UpdateImage(change)
+		}
+	}
+	if len(fields) > 0 {
+		operations.addProductDiff(current, fields)
+	}
+	return nil
+}
+
+// resolveCreatedImages returns in with the sources of the images that have
+// been created from them replaced by the sources of the images of current.
+func resolveCreatedImages(in ProductImages, current *goshopify.Product, created CreatedImages) ProductImages {
+	srcs := map[int64]string{}
+	for _, img := range current.Images {
+		srcs[img.ID] = img.Src
+	}
+	resolve := func(src string) string {
+		if s, ok := srcs[created[src]]; ok {
+			return s
+		}
+		return src
+	}
+	resolved := ProductImages{Product: in.Product}
+	for _, img := range in.Images {
+		img.Src = resolve(img.Src)
+		resolved.Images = append(resolved.Images, img)
+	}
+	for _, vi := range in.Variants {
+		vi.Src = resolve(vi.Src)
+		resolved.Variants = append(resolved.Variants, vi)
+	}
+	return resolved
+}
+
+// changeVariantIDs sets the variant IDs of change to ids if they differ from
+// the variant IDs of c, and appends the change to fields. It reports whether
+// the IDs differ.
+func changeVariantIDs(change *ImageChange, c *goshopify.Image, ids []int64, name string, fields *[]FieldDiff) bool {
+	before := sortedIDs(c.VariantIds)
+	after := sortedIDs(ids)
+	if joinIDs(before) == joinIDs(after) {
+		return false
+	}
+	change.VariantIDs = after
+	*fields = append(*fields, FieldDiff{Name: name + ".variant_ids", Before: joinIDs(before), After: joinIDs(after)})
+	return true
+}
+
+// addProductDiff adds fields to the diff of product p. If p has no diff yet,
+// then one is added and p is no longer counted as unchanged.
+func (s *Operations) addProductDiff(p *goshopify.Product, fields []FieldDiff) {
+	for i := range s.Diffs {
+		d := &s.Diffs[i]
+		if d.Resource == ResourceProduct && d.ID == p.ID {
+			d.Fields = append(d.Fields, fields...)
+			return
+		}
+	}
+	if s.UnchangedProducts > 0 {
+		s.UnchangedProducts--
+	}
+	s.Diffs = append(s.Diffs, Diff{
+		Resource: ResourceProduct,
+		ID:       p.ID,
+		Title:    p.Title,
+		Fields:   fields,
+	})
+}
+
+func hasImage(images []Image, src string) bool {
+	for _, img := range images {
+		if img.Src == src {
+			return true
+		}
+	}
+	return false
+}
+
+func removeID(ids []int64, id int64) []int64 {
+	kept := []int64{}
+	for _, other := range ids {
+		if other != id {
+			kept = append(kept, other)
+		}
+	}
+	return kept
+}
+
+func sortedIDs(ids []int64) []int64 {
+	sorted := append([]int64{}, ids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+func joinIDs(ids []int64) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = fmt.Sprint(id)
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,136 @@
+package memdb
+
+import (
+	"reflect"
+	"testing"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+)
+
+func TestMemoryDB_ImageOperations(t *testing.T) {
+	newDB := func(t *testing.T) *MemoryDB {
+		t.Helper()
+		db, err := New([]goshopify.Product{{
+			ID:    1,
+			Title: "foo",
+			Images: []goshopify.Image{
+				{ID: 101, ProductID: 1, Position: 1, Src: "https://cdn/a.png", VariantIds: []int64{11}},
+				{ID: 102, ProductID: 1, Position: 2, Src: "https://cdn/b.png"},
+			},
+			Variants: []goshopify.Variant{
+				{ID: 11, ProductID: 1, Sku: "foo-1", Title: "S", Option1: "S"},
+				{ID: 12, ProductID: 1, Sku: "foo-2", Title: "M", Option1: "M"},
+			},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+	alts := ImageAlts{101: "front"}
+	alt := func(s string) *string { return &s }
+
+	t.Run("skips unchanged images", func(t *testing.T) {
+		ops := &Operations{UnchangedProducts: 1}
+		incoming := []ProductImages{{
+			Product: goshopify.Product{Title: "foo"},
+			Images: []Image{
+				{Src: "https://cdn/a.png", Alt: "front", Position: 1},
+				{Src: "https://cdn/b.png", Position: 2},
+			},
+			Variants: []VariantImage{{Variant: goshopify.Variant{Sku: "foo-1"}, Src: "https://cdn/a.png"}},
+		}}
+		if err := newDB(t).ImageOperations(ops, incoming, alts, nil); err != nil {
+			t.Fatal(err)
+		}
+		if len(ops.NewImages)+len(ops.ImageUpdates)+len(ops.DeletedImages) != 0 || ops.UnchangedProducts != 1 {
+			t.Fatalf("got %+v, want no operations", ops)
+		}
+	})
+
+	t.Run("creates, updates and deletes images", func(t *testing.T) {
+		ops := &Operations{UnchangedProducts: 1}
+		incoming := []ProductImages{{
+			Product: goshopify.Product{ID: 1},
+			Images: []Image{
+				{Src: "/tmp/c.png", Alt: "back", Position: 1},
+				{Src: "https://cdn/a.png", Alt: "side", Position: 2},
+			},
+			Variants: []VariantImage{{Variant: goshopify.Variant{Sku: "foo-1"}, Src: "/tmp/c.png"}},
+		}}
+		if err := newDB(t).ImageOperations(ops, incoming, alts, nil); err != nil {
+			t.Fatal(err)
+		}
+		want := &Operations{
+			NewImages: []ImageChange{
+				{ProductID: 1, Title: "foo", Src: "/tmp/c.png", Alt: alt("back"), Position: 1, VariantIDs: []int64{11}},
+			},
+			ImageUpdates: []ImageChange{
+				{ID: 101, ProductID: 1, Title: "foo", Alt: alt("side"), Position: 2, VariantIDs: []int64{}},
+			},
+			DeletedImages: []ImageChange{
+				{ID: 102, ProductID: 1, Title: "foo", Src: "https://cdn/b.png"},
+			},
+			Diffs: []Diff{{
+				Resource: ResourceProduct,
+				ID:       1,
+				Title:    "foo",
+				Fields: []FieldDiff{
+					{Name: "images", After: "/tmp/c.png"},
+					{Name: "images.101.alt", Before: "front", After: "side"},
+					{Name: "images.101.position", Before: "1", After: "2"},
+					{Name: "images.101.variant_ids", Before: "11", After: ""},
+					{Name: "images", Before: "https://cdn/b.png"},
+				},
+			}},
+		}
+		if !reflect.DeepEqual(ops, want) {
+			t.Fatalf("got %+v, want %+v", ops, want)
+		}
+	})
+
+	t.Run("only changes variant images without incoming images", func(t *testing.T) {
+		ops := &Operations{}
+		incoming := []ProductImages{{
+			Product:  goshopify.Product{Title: "foo"},
+			Variants: []VariantImage{{Variant: goshopify.Variant{ID: 12}, Src: "https://cdn/b.png"}},
+		}}
+		if err := newDB(t).ImageOperations(ops, incoming, alts, nil); err != nil {
+			t.Fatal(err)
+		}
+		if len(ops.DeletedImages) != 0 || len(ops.ImageUpdates) != 1 {
+			t.Fatalf("got %+v", ops)
+		}
+		if got := ops.ImageUpdates[0]; got.ID != 102 || !reflect.DeepEqual(got.VariantIDs, []int64{12}) {
+			t.Fatalf("got update %+v", got)
+		}
+	})
+
+	t.Run("matches images created from incoming sources", func(t *testing.T) {
+		ops := &Operations{}
+		incoming := []ProductImages{{
+			Product: goshopify.Product{Title: "foo"},
+			Images: []Image{
+				{Src: "https://cdn/a.png", Alt: "front", Position: 1},
+				{Src: "/tmp/b.png", Position: 2},
+			},
+		}}
+		created := CreatedImages{"/tmp/b.png": 102, "/tmp/c.png": 103}
+		if err := newDB(t).ImageOperations(ops, incoming, alts, created); err != nil {
+			t.Fatal(err)
+		}
+		if len(ops.NewImages) != 0 || len(ops.DeletedImages) != 0 || len(ops.ImageUpdates) != 0 {
+			t.Fatalf("got %+v", ops)
+		}
+	})
+
+	t.Run("returns error for images of new product", func(t *testing.T) {
+		incoming := []ProductImages{{
+			Product: goshopify.Product{Title: "bar"},
+			Images:  []Image{{Src: "/tmp/c.png"}},
+		}}
+		if err := newDB(t).ImageOperations(&Operations{}, incoming, alts, nil); err == nil {
+			t.Fatal("expected error but didn't get one")
+		}
+	})
+}
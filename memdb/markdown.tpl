@@ -0,0 +1,26 @@
+# Push Plan
+
+|           | Products | Variants | Metafields |
+| --------- | -------: | -------: | ---------: |
+| New       | {{len .NewProducts}} | {{len .NewVariants}} | {{len .NewMetafields}} |
+| Updated   | {{len .ProductUpdates}} | {{len .VariantUpdates}} | {{len .MetafieldUpdates}} |
+| Unchanged | {{.UnchangedProducts}} | {{.UnchangedVariants}} | |
+| Deleted   | {{len .DeletedProducts}} | {{len .DeletedVariants}} | {{len .DeletedMetafields}} |
+{{- if or .NewImages .ImageUpdates .DeletedImages}}
+
+Images: {{len .NewImages}} new, {{len .ImageUpdates}} updated, {{len .DeletedImages}} deleted
+{{- end}}
+{{- if or .InventoryUpdates .InventoryItemUpdates}}
+
+Inventory updates: {{len .InventoryUpdates}}, cost updates: {{len .InventoryItemUpdates}}
+{{- end}}
+{{- range .Entries}}
+
+## {{cell .Header}}
+
+| Field | Before | After |
+| ----- | ------ | ----- |
+{{- range .Fields}}
+| `{{.Name}}` | {{cell .Before}} | {{cell .After}} |
+{{- end}}
+{{- end}}
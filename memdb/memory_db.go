@@ -5,12 +5,17 @@ package memdb
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/samherrmann/merchant/collection"
 )
 
 var (
 	ErrNotExist = errors.New("does not exist")
+	// ErrChanged is returned if an edited value has also changed in the store
+	// since it was checked out.
+	ErrChanged = errors.New("has changed in the store since checkout, check out the products again")
 )
 
 // New returns a new in-memory database.
@@ -51,9 +56,14 @@ func (db *MemoryDB) Products() *ProductDB {
 }
 
 // Operations groups the given changes by the type of operation needed to apply
-// them to the database.
-func (db *MemoryDB) Operations(changes []goshopify.Product) (*Operations, error) {
+// them to the database. The variants in deletions are matched against the
+// database the same way as changes, and products for which all variants are
+// deleted are deleted entirely.
+func (db *MemoryDB) Operations(changes []goshopify.Product, deletions []goshopify.Product) (*Operations, error) {
 	operations := &Operations{}
+	if err := db.deleteOperations(operations, deletions); err != nil {
+		return nil, err
+	}
 outerLoop:
 	for i := range changes {
 		p := changes[i]
@@ -62,7 +72,10 @@ outerLoop:
 			return nil, err
 		}
 		if p.ID != 0 {
-			operations.UpdateProduct(p)
+			current, _ := db.Products().GetByID(p.ID)
+			if err := operations.diffProduct(current, &p); err != nil {
+				return nil, err
+			}
 		}
 		for i := range p.Variants {
 			v := p.Variants[i]
@@ -78,12 +91,110 @@ outerLoop:
 				operations.CreateVariant(v)
 				continue
 			}
-			operations.UpdateVariant(v)
+			current, _ := db.Variants().GetByID(v.ID)
+			if err := operations.diffVariant(current, &v, p.Title); err != nil {
+				return nil, err
+			}
 		}
 	}
+	db.setBaseline(operations)
 	return operations, nil
 }
 
+// setBaseline records the updated_at timestamp of every existing product that
+// is affected by operations.
+func (db *MemoryDB) setBaseline(operations *Operations) {
+	ids := []int64{}
+	for _, p := range operations.ProductUpdates {
+		ids = append(ids, p.ID)
+	}
+	for _, u := range operations.TagUpdates {
+		ids = append(ids, u.ProductID)
+	}
+	for _, r := range operations.ProductResets {
+		ids = append(ids, r.ID)
+	}
+	for _, images := range [][]ImageChange{
+		operations.NewImages,
+		operations.ImageUpdates,
+		operations.DeletedImages,
+	} {
+		for _, c := range images {
+			ids = append(ids, c.ProductID)
+		}
+	}
+	for _, p := range operations.DeletedProducts {
+		ids = append(ids, p.ID)
+	}
+	for _, variants := range [][]goshopify.Variant{
+		operations.NewVariants,
+		operations.VariantUpdates,
+		operations.DeletedVariants,
+	} {
+		for _, v := range variants {
+			ids = append(ids, v.ProductID)
+		}
+	}
+	for _, r := range operations.VariantResets {
+		ids = append(ids, r.ProductID)
+	}
+	for _, metafields := range [][]goshopify.Metafield{
+		operations.NewMetafields,
+		operations.MetafieldUpdates,
+	} {
+		for _, m := range metafields {
+			if m.OwnerResource == MetafieldOwnerProduct {
+				ids = append(ids, m.OwnerId)
+				continue
+			}
+			v, _ := db.Variants().GetByID(m.OwnerId)
+			ids = append(ids, v.ProductID)
+		}
+	}
+	for _, id := range ids {
+		p, exists := db.Products().GetByID(id)
+		if !exists || p.UpdatedAt == nil {
+			continue
+		}
+		if operations.Baseline == nil {
+			operations.Baseline = make(map[int64]time.Time)
+		}
+		operations.Baseline[id] = *p.UpdatedAt
+	}
+}
+
+// deleteOperations appends the delete operations for the given deletions to
+// operations.
+func (db *MemoryDB) deleteOperations(operations *Operations, deletions []goshopify.Product) error {
+	for i := range deletions {
+		p := deletions[i]
+		if err := db.Products().PatchID(&p); err != nil {
+			return err
+		}
+		if p.ID == 0 {
+			return fmt.Errorf("cannot delete product %q: %w", p.Title, ErrNotExist)
+		}
+		current, _ := db.Products().GetByID(p.ID)
+		variants := collection.NewOrderedMap[int64, goshopify.Variant]()
+		for j := range p.Variants {
+			v := p.Variants[j]
+			if err := db.Variants().PatchID(&v); err != nil {
+				return fmt.Errorf("cannot delete variant of product %q: %w", p.Title, err)
+			}
+			dbv, _ := db.Variants().GetByID(v.ID)
+			variants.Set(v.ID, *dbv)
+		}
+		if len(variants.Raw()) == len(current.Variants) {
+			operations.DeleteProduct(*current)
+			continue
+		}
+		for _, v := range variants.Slice() {
+			operations.DeleteVariant(v)
+		}
+	}
+	return nil
+}
+
 func newInMemoryDBError(format string, a ...any) error {
 	return fmt.Errorf("in-memory database: %v", fmt.Errorf(format, a...))
 }
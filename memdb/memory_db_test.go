@@ -0,0 +1,142 @@
+package memdb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+)
+
+func TestMemoryDB_Operations_deletions(t *testing.T) {
+	db, err := New([]goshopify.Product{
+		{
+			ID:    1,
+			Title: "foo",
+			Variants: []goshopify.Variant{
+				{ID: 11, ProductID: 1, Sku: "foo-1", Option1: "S"},
+				{ID: 12, ProductID: 1, Sku: "foo-2", Option1: "M"},
+			},
+		},
+		{
+			ID:    2,
+			Title: "bar",
+			Variants: []goshopify.Variant{
+				{ID: 21, ProductID: 2, Sku: "bar-1"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("deletes variants and products", func(t *testing.T) {
+		deletions := []goshopify.Product{
+			{Title: "foo", Variants: []goshopify.Variant{{Sku: "foo-2"}}},
+			{Title: "bar", Variants: []goshopify.Variant{{Sku: "bar-1"}}},
+		}
+		ops, err := db.Operations(nil, deletions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ops.DeletedVariants) != 1 || ops.DeletedVariants[0].ID != 12 {
+			t.Fatalf("got deleted variants %+v, want variant 12", ops.DeletedVariants)
+		}
+		if len(ops.DeletedProducts) != 1 || ops.DeletedProducts[0].ID != 2 {
+			t.Fatalf("got deleted products %+v, want product 2", ops.DeletedProducts)
+		}
+	})
+
+	t.Run("returns error for unknown product", func(t *testing.T) {
+		deletions := []goshopify.Product{
+			{Title: "baz", Variants: []goshopify.Variant{{Sku: "baz-1"}}},
+		}
+		if _, err := db.Operations(nil, deletions); !errors.Is(err, ErrNotExist) {
+			t.Fatalf("got %v, want %v", err, ErrNotExist)
+		}
+	})
+
+	t.Run("returns error for unknown variant", func(t *testing.T) {
+		deletions := []goshopify.Product{
+			{Title: "foo", Variants: []goshopify.Variant{{Sku: "foo-3", Option1: "L"}}},
+		}
+		if _, err := db.Operations(nil, deletions); err == nil {
+			t.Fatal("expected error but didn't get one")
+		}
+	})
+}
+
+func TestMemoryDB_Operations_unchanged(t *testing.T) {
+	db, err := New([]goshopify.Product{
+		{
+			ID:     1,
+			Title:  "foo",
+			Vendor: "acme",
+			Variants: []goshopify.Variant{
+				{ID: 11, ProductID: 1, Sku: "foo-1", Barcode: "111", Option1: "S"},
+				{ID: 12, ProductID: 1, Sku: "foo-2", Barcode: "222", Option1: "M"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := []goshopify.Product{
+		{
+			ID:     1,
+			Title:  "foo",
+			Vendor: "acme",
+			Variants: []goshopify.Variant{
+				{ID: 11, ProductID: 1, Sku: "foo-1", Barcode: "111", Option1: "S"},
+				{ID: 12, ProductID: 1, Sku: "foo-2", Barcode: "333"},
+			},
+		},
+	}
+	ops, err := db.Operations(changes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops.ProductUpdates) != 0 || ops.UnchangedProducts != 1 {
+		t.Fatalf("got %v product updates and %v unchanged, want 0 and 1", len(ops.ProductUpdates), ops.UnchangedProducts)
+	}
+	if len(ops.VariantUpdates) != 1 || ops.UnchangedVariants != 1 {
+		t.Fatalf("got %v variant updates and %v unchanged, want 1 and 1", len(ops.VariantUpdates), ops.UnchangedVariants)
+	}
+	if len(ops.Diffs) != 1 || ops.Diffs[0].ID != 12 {
+		t.Fatalf("got diffs %+v, want diff for variant 12", ops.Diffs)
+	}
+}
+
+func TestMemoryDB_Operations_baseline(t *testing.T) {
+	updatedAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	db, err := New([]goshopify.Product{
+		{
+			ID:        1,
+			Title:     "foo",
+			UpdatedAt: &updatedAt,
+			Variants:  []goshopify.Variant{{ID: 11, ProductID: 1, Sku: "foo-1"}},
+		},
+		{
+			ID:        2,
+			Title:     "bar",
+			UpdatedAt: &updatedAt,
+			Variants:  []goshopify.Variant{{ID: 21, ProductID: 2, Sku: "bar-1"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := []goshopify.Product{
+		{Title: "foo", Variants: []goshopify.Variant{{Sku: "foo-1", Barcode: "123"}}},
+		{Title: "bar", Variants: []goshopify.Variant{{Sku: "bar-1"}}},
+	}
+	ops, err := db.Operations(changes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int64]time.Time{1: updatedAt}
+	if !reflect.DeepEqual(ops.Baseline, want) {
+		t.Fatalf("got %v, want %v", ops.Baseline, want)
+	}
+}
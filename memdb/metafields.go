@@ -0,0 +1,58 @@
+package memdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// MetafieldValue returns the text of a metafield value. The API returns most
+// values as strings, but values that are decoded from JSON numbers are
+// float64, which fmt would print in exponent notation, e.g. 1e+06.
+func MetafieldValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	case map[string]any, []any:
+		b, err := json.Marshal(v)
+		if err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+// EqualMetafieldValues reports whether a and b are the same value of a
+// metafield of the given type. Numbers are compared by value, booleans
+// case-insensitively and JSON regardless of its whitespace. Other values are
+// compared as text.
+func EqualMetafieldValues(metafieldType string, a any, b any) bool {
+	x, y := MetafieldValue(a), MetafieldValue(b)
+	if x == y {
+		return true
+	}
+	switch metafieldType {
+	case "number_integer", "number_decimal":
+		dx, errX := decimal.NewFromString(strings.TrimSpace(x))
+		dy, errY := decimal.NewFromString(strings.TrimSpace(y))
+		return errX == nil && errY == nil && dx.Equal(dy)
+	case "boolean":
+		bx, errX := strconv.ParseBool(strings.TrimSpace(x))
+		by, errY := strconv.ParseBool(strings.TrimSpace(y))
+		return errX == nil && errY == nil && bx == by
+	case "json":
+		cx, cy := &bytes.Buffer{}, &bytes.Buffer{}
+		return json.Compact(cx, []byte(x)) == nil && json.Compact(cy, []byte(y)) == nil && cx.String() == cy.String()
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package memdb
+
+import "testing"
+
+func TestMetafieldValue(t *testing.T) {
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{value: nil, want: ""},
+		{value: "wool", want: "wool"},
+		{value: float64(1000000), want: "1000000"},
+		{value: 1.25, want: "1.25"},
+		{value: true, want: "true"},
+		{value: map[string]any{"a": float64(1)}, want: `{"a":1}`},
+	}
+	for _, tt := range tests {
+		if got := MetafieldValue(tt.value); got != tt.want {
+			t.Errorf("MetafieldValue(%#v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestEqualMetafieldValues(t *testing.T) {
+	tests := []struct {
+		name          string
+		metafieldType string
+		a             any
+		b             any
+		want          bool
+	}{
+		{name: "should compare text exactly", metafieldType: "single_line_text_field", a: "Wool", b: "wool", want: false},
+		{name: "should compare integers by value", metafieldType: "number_integer", a: float64(1000000), b: "1000000", want: true},
+		{name: "should compare decimals by value", metafieldType: "number_decimal", a: "1.50", b: "1.5", want: true},
+		{name: "should detect changed numbers", metafieldType: "number_decimal", a: "1.5", b: "1.6", want: false},
+		{name: "should compare booleans", metafieldType: "boolean", a: true, b: "TRUE", want: true},
+		{name: "should ignore JSON whitespace", metafieldType: "json", a: `{"a": 1}`, b: `{"a":1}`, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EqualMetafieldValues(tt.metafieldType, tt.a, tt.b); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
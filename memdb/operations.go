@@ -5,23 +5,93 @@ import (
 	"encoding/json"
 	"io"
 	"text/template"
+	"time"
 
 	goshopify "github.com/bold-commerce/go-shopify/v3"
 )
 
-//go:embed summary.tpl
+//go:embed *.tpl
 var embeddedFS embed.FS
 
+// Metafield owner resources as used in the OwnerResource field of
+// [goshopify.Metafield].
+const (
+	MetafieldOwnerProduct = "product"
+	MetafieldOwnerVariant = "variant"
+)
+
+// Resource types as used in [Diff] and in the keys of applied steps. The
+// values are recorded in journals and must not change.
+const (
+	ResourceProduct = "product"
+	ResourceVariant = "variant"
+)
+
 type Operations struct {
 	tmpl *template.Template
+	// Store is the name of the store against which the operations were
+	// computed. Operations must only be applied to that store.
+	Store string `json:",omitempty"`
 	// NewProducts is a list of new products.
 	NewProducts []goshopify.Product `json:",omitempty"`
 	// ProductUpdates is a list of product updates.
 	ProductUpdates []goshopify.Product `json:",omitempty"`
+	// TagUpdates is a list of tags to add to and remove from existing products.
+	TagUpdates []TagUpdate `json:",omitempty"`
 	// NewVariants is a list of new variants.
 	NewVariants []goshopify.Variant `json:",omitempty"`
 	// VariantUpdates is a list of variant updates.
 	VariantUpdates []goshopify.Variant `json:",omitempty"`
+	// ProductResets is a list of products of which fields are cleared.
+	ProductResets []ProductReset `json:",omitempty"`
+	// VariantResets is a list of variants of which fields are cleared.
+	VariantResets []VariantReset `json:",omitempty"`
+	// NewMetafields is a list of new metafields for existing products and
+	// variants. Metafields of new products and variants are created together
+	// with their owner.
+	NewMetafields []goshopify.Metafield `json:",omitempty"`
+	// MetafieldUpdates is a list of metafield updates.
+	MetafieldUpdates []goshopify.Metafield `json:",omitempty"`
+	// DeletedProducts is a list of products to delete, including all their
+	// variants.
+	DeletedProducts []goshopify.Product `json:",omitempty"`
+	// DeletedVariants is a list of variants to delete from products that are
+	// otherwise kept.
+	DeletedVariants []goshopify.Variant `json:",omitempty"`
+	// DeletedMetafields is a list of metafields to delete from products and
+	// variants that are otherwise kept.
+	DeletedMetafields []goshopify.Metafield `json:",omitempty"`
+	// NewImages is a list of new images of existing products.
+	NewImages []ImageChange `json:",omitempty"`
+	// ImageUpdates is a list of changes of the alt text, position or variants
+	// of images.
+	ImageUpdates []ImageChange `json:",omitempty"`
+	// DeletedImages is a list of images to delete.
+	DeletedImages []ImageChange `json:",omitempty"`
+	// InventoryUpdates is a list of changes of available quantities.
+	InventoryUpdates []InventoryUpdate `json:",omitempty"`
+	// InventoryItemUpdates is a list of changes of inventory item costs.
+	InventoryItemUpdates []InventoryItemUpdate `json:",omitempty"`
+	// UnchangedProducts is the number of existing products without changes.
+	UnchangedProducts int
+	// UnchangedVariants is the number of existing variants without changes.
+	UnchangedVariants int
+	// Diffs lists the changed fields of all updated products and variants.
+	Diffs []Diff `json:",omitempty"`
+	// Baseline maps the IDs of all existing products that are affected by the
+	// operations to their updated_at timestamp at the time the operations were
+	// computed. It allows to verify that the store has not changed since.
+	Baseline map[int64]time.Time `json:",omitempty"`
+}
+
+// DecodeOperations decodes Operations from the JSON encoding in r, as written
+// by [Operations.PrintJSON].
+func DecodeOperations(r io.Reader) (*Operations, error) {
+	s := &Operations{}
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
 // CreateProduct appends p to the NewProducts slice.
@@ -31,11 +101,17 @@ func (s *Operations) CreateProduct(p goshopify.Product) {
 
 // UpdateProduct appends p to the ProductUpdates slice.
 func (s *Operations) UpdateProduct(p goshopify.Product) {
-	// Remove variants because they are updated separately.
+	// Remove variants and metafields because they are updated separately.
 	p.Variants = nil
+	p.Metafields = nil
 	s.ProductUpdates = append(s.ProductUpdates, p)
 }
 
+// UpdateTags appends u to the TagUpdates slice.
+func (s *Operations) UpdateTags(u TagUpdate) {
+	s.TagUpdates = append(s.TagUpdates, u)
+}
+
 // CreateVariant appends v to the NewVariants slice.
 func (s *Operations) CreateVariant(v goshopify.Variant) {
 	s.NewVariants = append(s.NewVariants, v)
@@ -43,9 +119,71 @@ func (s *Operations) CreateVariant(v goshopify.Variant) {
 
 // UpdateVariant appends v to the VariantUpdates slice.
 func (s *Operations) UpdateVariant(v goshopify.Variant) {
+	// Remove metafields because they are updated separately.
+	v.Metafields = nil
 	s.VariantUpdates = append(s.VariantUpdates, v)
 }
 
+// ResetProduct appends r to the ProductResets slice.
+func (s *Operations) ResetProduct(r ProductReset) {
+	s.ProductResets = append(s.ProductResets, r)
+}
+
+// ResetVariant appends r to the VariantResets slice.
+func (s *Operations) ResetVariant(r VariantReset) {
+	s.VariantResets = append(s.VariantResets, r)
+}
+
+// CreateMetafield appends m to the NewMetafields slice.
+func (s *Operations) CreateMetafield(m goshopify.Metafield) {
+	s.NewMetafields = append(s.NewMetafields, m)
+}
+
+// UpdateMetafield appends m to the MetafieldUpdates slice.
+func (s *Operations) UpdateMetafield(m goshopify.Metafield) {
+	s.MetafieldUpdates = append(s.MetafieldUpdates, m)
+}
+
+// UpdateInventory appends u to the InventoryUpdates slice.
+func (s *Operations) UpdateInventory(u InventoryUpdate) {
+	s.InventoryUpdates = append(s.InventoryUpdates, u)
+}
+
+// CreateImage appends c to the NewImages slice.
+func (s *Operations) CreateImage(c ImageChange) {
+	s.NewImages = append(s.NewImages, c)
+}
+
+// UpdateImage appends c to the ImageUpdates slice.
+func (s *Operations) UpdateImage(c ImageChange) {
+	s.ImageUpdates = append(s.ImageUpdates, c)
+}
+
+// DeleteImage appends c to the DeletedImages slice.
+func (s *Operations) DeleteImage(c ImageChange) {
+	s.DeletedImages = append(s.DeletedImages, c)
+}
+
+// UpdateInventoryItem appends u to the InventoryItemUpdates slice.
+func (s *Operations) UpdateInventoryItem(u InventoryItemUpdate) {
+	s.InventoryItemUpdates = append(s.InventoryItemUpdates, u)
+}
+
+// DeleteProduct appends p to the DeletedProducts slice.
+func (s *Operations) DeleteProduct(p goshopify.Product) {
+	s.DeletedProducts = append(s.DeletedProducts, p)
+}
+
+// DeleteVariant appends v to the DeletedVariants slice.
+func (s *Operations) DeleteVariant(v goshopify.Variant) {
+	s.DeletedVariants = append(s.DeletedVariants, v)
+}
+
+// DeleteMetafield appends m to the DeletedMetafields slice.
+func (s *Operations) DeleteMetafield(m goshopify.Metafield) {
+	s.DeletedMetafields = append(s.DeletedMetafields, m)
+}
+
 // PrintJSON prints the JSON encoding of Operations to w.
 func (s *Operations) PrintJSON(w io.Writer) error {
 	encoder := json.NewEncoder(w)
@@ -55,12 +193,23 @@ func (s *Operations) PrintJSON(w io.Writer) error {
 
 // PrintSummary prints a summary of Operations to w.
 func (s *Operations) PrintSummary(w io.Writer) error {
+	tmpl, err := s.template()
+	if err != nil {
+		return err
+	}
+	return tmpl.ExecuteTemplate(w, "summary.tpl", s)
+}
+
+// template returns the parsed report templates.
+func (s *Operations) template() (*template.Template, error) {
 	if s.tmpl == nil {
-		tmpl, err := template.New("summary").ParseFS(embeddedFS, "*")
+		tmpl, err := template.New("operations").
+			Funcs(template.FuncMap{"cell": markdownCell}).
+			ParseFS(embeddedFS, "*.tpl")
 		if err != nil {
-			return err
+			return nil, err
 		}
 		s.tmpl = tmpl
 	}
-	return s.tmpl.ExecuteTemplate(w, "summary.tpl", s)
+	return s.tmpl, nil
 }
@@ -0,0 +1,32 @@
+package memdb
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+)
+
+func TestDecodeOperations(t *testing.T) {
+	want := &Operations{
+		ProductUpdates:    []goshopify.Product{{ID: 1, Vendor: "acme"}},
+		DeletedVariants:   []goshopify.Variant{{ID: 2, ProductID: 1}},
+		UnchangedVariants: 3,
+		Baseline: map[int64]time.Time{
+			1: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	}
+	b := &bytes.Buffer{}
+	if err := want.PrintJSON(b); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeOperations(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("\ngot: %+v\nwant: %+v", got, want)
+	}
+}
@@ -20,19 +20,23 @@ type ProductDB struct {
 	handles map[string]goshopify.Product
 }
 
+// Add adds p to the database. p is not added if its ID, title or handle is
+// already taken by another product.
 func (db *ProductDB) Add(p *goshopify.Product) error {
 	if _, exists := db.ids[p.ID]; exists {
-		return newInMemoryDBError("product id %q already exists", p.ID)
+		return newInMemoryDBError("product id %v already exists", p.ID)
 	}
-	db.ids[p.ID] = *p
 	if _, exists := db.titles[p.Title]; exists {
 		return newInMemoryDBError("product title %q already exists", p.Title)
 	}
-	db.titles[p.Title] = *p
-	if _, exists := db.titles[p.Handle]; exists {
+	if _, exists := db.handles[p.Handle]; exists && p.Handle != "" {
 		return newInMemoryDBError("product handle %q already exists", p.Handle)
 	}
-	db.handles[p.Handle] = *p
+	db.ids[p.ID] = *p
+	db.titles[p.Title] = *p
+	if p.Handle != "" {
+		db.handles[p.Handle] = *p
+	}
 	return nil
 }
 
@@ -41,12 +45,14 @@ func (db *ProductDB) Get(p *goshopify.Product) (*goshopify.Product, bool) {
 		return db.GetByID(p.ID)
 	}
 	if p.Handle != "" {
-		return db.GetByHandle(p.Title)
+		if current, exists := db.GetByHandle(p.Handle); exists {
+			return current, true
+		}
 	}
 	if p.Title != "" {
 		return db.GetByTitle(p.Title)
 	}
-	return nil, false
+	return &goshopify.Product{}, false
 }
 
 func (db *ProductDB) GetByID(id int64) (*goshopify.Product, bool) {
@@ -70,7 +76,7 @@ func (db *ProductDB) GetByHandle(handle string) (*goshopify.Product, bool) {
 func (db *ProductDB) PatchID(p *goshopify.Product) error {
 	current, exists := db.Get(p)
 	if !exists && p.ID != 0 {
-		return fmt.Errorf("product ID %q does not exist", p.ID)
+		return fmt.Errorf("product ID %v does not exist", p.ID)
 	}
 	patchProductID(p, current.ID)
 	return nil
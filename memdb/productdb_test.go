@@ -0,0 +1,92 @@
+package memdb
+
+import (
+	"strings"
+	"testing"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+)
+
+func TestProductDB_Get(t *testing.T) {
+	db := NewProductDB()
+	for _, p := range []goshopify.Product{
+		{ID: 1, Title: "Foo", Handle: "foo"},
+		{ID: 2, Title: "Bar", Handle: "bar"},
+		{ID: 3, Title: "Baz"},
+	} {
+		p := p
+		if err := db.Add(&p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name    string
+		product goshopify.Product
+		wantID  int64
+		exists  bool
+	}{
+		{name: "should match by ID", product: goshopify.Product{ID: 2}, wantID: 2, exists: true},
+		{name: "should match by handle", product: goshopify.Product{Title: "Renamed", Handle: "foo"}, wantID: 1, exists: true},
+		{name: "should match by title if handle is unknown", product: goshopify.Product{Title: "Bar", Handle: "qux"}, wantID: 2, exists: true},
+		{name: "should match by title", product: goshopify.Product{Title: "Baz"}, wantID: 3, exists: true},
+		{name: "should not match unknown product", product: goshopify.Product{Title: "Qux"}, exists: false},
+		{name: "should not match empty product", product: goshopify.Product{}, exists: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, exists := db.Get(&tt.product)
+			if exists != tt.exists {
+				t.Fatalf("got exists = %v, want %v", exists, tt.exists)
+			}
+			if exists && got.ID != tt.wantID {
+				t.Fatalf("got ID %v, want %v", got.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestProductDB_Add(t *testing.T) {
+	t.Run("should reject duplicate handle", func(t *testing.T) {
+		db := NewProductDB()
+		if err := db.Add(&goshopify.Product{ID: 1, Title: "Foo", Handle: "foo"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Add(&goshopify.Product{ID: 2, Title: "Bar", Handle: "foo"}); err == nil {
+			t.Fatal("expected error but didn't get one")
+		}
+	})
+
+	t.Run("should name duplicate ID", func(t *testing.T) {
+		db := NewProductDB()
+		if err := db.Add(&goshopify.Product{ID: 123, Title: "Foo"}); err != nil {
+			t.Fatal(err)
+		}
+		err := db.Add(&goshopify.Product{ID: 123, Title: "Bar"})
+		if err == nil || !strings.Contains(err.Error(), "product id 123 ") {
+			t.Fatalf("got error %v, want duplicate ID 123", err)
+		}
+	})
+
+	t.Run("should not add rejected product", func(t *testing.T) {
+		db := NewProductDB()
+		if err := db.Add(&goshopify.Product{ID: 1, Title: "Foo", Handle: "foo"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Add(&goshopify.Product{ID: 2, Title: "Bar", Handle: "foo"}); err == nil {
+			t.Fatal("expected error but didn't get one")
+		}
+		if _, exists := db.GetByTitle("Bar"); exists {
+			t.Fatal("rejected product has been added")
+		}
+	})
+
+	t.Run("should allow multiple products without handle", func(t *testing.T) {
+		db := NewProductDB()
+		if err := db.Add(&goshopify.Product{ID: 1, Title: "Foo"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Add(&goshopify.Product{ID: 2, Title: "Bar"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,263 @@
+package memdb
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+)
+
+// Actions as shown in reports.
+const (
+	actionCreate = "create"
+	actionUpdate = "update"
+	actionDelete = "delete"
+)
+
+// resourceInventory is the name of inventory levels in reports.
+const resourceInventory = "inventory"
+
+// ANSI escape codes used to colourise the diff.
+const (
+	ansiReset = "\x1b[0m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiCyan  = "\x1b[36m"
+)
+
+// reportEntry is a Diff together with the action that causes it.
+type reportEntry struct {
+	Action string
+	Diff
+}
+
+// Header returns a one-line description of the entry.
+func (e reportEntry) Header() string {
+	if e.ID == 0 {
+		return fmt.Sprintf("%v %v %v", e.Action, e.Resource, e.Title)
+	}
+	return fmt.Sprintf("%v %v %v %v", e.Action, e.Resource, e.ID, e.Title)
+}
+
+// PrintDiff prints a git-style diff of Operations to w. Removed values are
+// prefixed with "-" and added values with "+". If color is true, then the diff
+// is colourised with ANSI escape codes.
+func (s *Operations) PrintDiff(w io.Writer, color bool) error {
+	paint := func(code string, str string) string {
+		if !color {
+			return str
+		}
+		return code + str + ansiReset
+	}
+	for i, e := range s.reportEntries() {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w, paint(ansiCyan, "@@ "+e.Header()+" @@")); err != nil {
+			return err
+		}
+		for _, f := range e.Fields {
+			if f.Before != "" {
+				line := fmt.Sprintf("- %v: %v", f.Name, f.Before)
+				if _, err := fmt.Fprintln(w, paint(ansiRed, line)); err != nil {
+					return err
+				}
+			}
+			if f.After != "" {
+				line := fmt.Sprintf("+ %v: %v", f.Name, f.After)
+				if _, err := fmt.Fprintln(w, paint(ansiGreen, line)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// PrintMarkdown prints a Markdown report of Operations to w.
+func (s *Operations) PrintMarkdown(w io.Writer) error {
+	tmpl, err := s.template()
+	if err != nil {
+		return err
+	}
+	data := struct {
+		*Operations
+		Entries []reportEntry
+	}{
+		Operations: s,
+		Entries:    s.reportEntries(),
+	}
+	return tmpl.ExecuteTemplate(w, "markdown.tpl", data)
+}
+
+// reportEntries returns an entry for every product, variant and metafield that
+// is deleted, every product and variant that is created or updated, and every
+// inventory and inventory item update, in that order.
+func (s *Operations) reportEntries() []reportEntry {
+	entries := []reportEntry{}
+	for i := range s.DeletedProducts {
+		p := &s.DeletedProducts[i]
+		entries = append(entries, reportEntry{
+			Action: actionDelete,
+			Diff: Diff{
+				Resource: ResourceProduct,
+				ID:       p.ID,
+				Title:    p.Title,
+				Fields:   removedFields(fieldsOf(p, productSkipFields)),
+			},
+		})
+	}
+	for i := range s.DeletedVariants {
+		v := &s.DeletedVariants[i]
+		entries = append(entries, reportEntry{
+			Action: actionDelete,
+			Diff: Diff{
+				Resource: ResourceVariant,
+				ID:       v.ID,
+				Title:    v.Title,
+				Fields:   removedFields(fieldsOf(v, variantSkipFields)),
+			},
+		})
+	}
+	for _, m := range s.DeletedMetafields {
+		entries = append(entries, reportEntry{
+			Action: actionDelete,
+			Diff: Diff{
+				Resource: "metafield",
+				ID:       m.ID,
+				Title:    fmt.Sprintf("%v %v", m.OwnerResource, m.OwnerId),
+				Fields:   removedFields(metafieldFields([]goshopify.Metafield{m})),
+			},
+		})
+	}
+	for i := range s.NewProducts {
+		p := &s.NewProducts[i]
+		fields := fieldsOf(p, productSkipFields)
+		if names := optionNames(p.Options); names != "" {
+			fields = append(fields, FieldDiff{Name: "options", After: names})
+		}
+		entries = append(entries, reportEntry{
+			Action: actionCreate,
+			Diff: Diff{
+				Resource: ResourceProduct,
+				Title:    p.Title,
+				Fields:   append(fields, metafieldFields(p.Metafields)...),
+			},
+		})
+		for j := range p.Variants {
+			entries = append(entries, newVariantEntry(&p.Variants[j], p.Title))
+		}
+	}
+	for i := range s.NewVariants {
+		v := &s.NewVariants[i]
+		entries = append(entries, newVariantEntry(v, fmt.Sprintf("product %v", v.ProductID)))
+	}
+	for _, d := range s.Diffs {
+		entries = append(entries, reportEntry{Action: actionUpdate, Diff: d})
+	}
+	for _, u := range s.InventoryUpdates {
+		entries = append(entries, reportEntry{Action: actionUpdate, Diff: inventoryDiff(u)})
+	}
+	for _, u := range s.InventoryItemUpdates {
+		field := FieldDiff{Name: "cost", After: u.Cost.String()}
+		if u.Before != nil {
+			field.Before = u.Before.String()
+		}
+		entries = append(entries, reportEntry{
+			Action: actionUpdate,
+			Diff: Diff{
+				Resource: resourceInventory,
+				ID:       u.ID,
+				Title:    u.Title,
+				Fields:   []FieldDiff{field},
+			},
+		})
+	}
+	return entries
+}
+
+// inventoryDiff returns the diff of an inventory update. Adjustments are shown
+// with their sign.
+func inventoryDiff(u InventoryUpdate) Diff {
+	field := FieldDiff{Name: "available"}
+	if u.Before != nil {
+		field.Before = fmt.Sprint(*u.Before)
+	}
+	if u.Available != nil {
+		field.After = fmt.Sprint(*u.Available)
+	} else {
+		field.After = fmt.Sprintf("%+d", u.Adjustment)
+	}
+	return Diff{
+		Resource: resourceInventory,
+		ID:       u.InventoryItemID,
+		Title:    fmt.Sprintf("%v at %v", u.Title, u.Location),
+		Fields:   []FieldDiff{field},
+	}
+}
+
+// newVariantEntry returns the report entry for a new variant. productTitle
+// names the product that the variant belongs to.
+func newVariantEntry(v *goshopify.Variant, productTitle string) reportEntry {
+	return reportEntry{
+		Action: actionCreate,
+		Diff: Diff{
+			Resource: ResourceVariant,
+			Title:    fmt.Sprintf("%v (%v)", productTitle, optionValues(v)),
+			Fields:   append(fieldsOf(v, variantSkipFields), metafieldFields(v.Metafields)...),
+		},
+	}
+}
+
+// fieldsOf returns all non-empty fields of the struct that v points to as
+// added fields.
+func fieldsOf(v any, skip map[string]bool) []FieldDiff {
+	t := reflect.TypeOf(v).Elem()
+	zero := reflect.New(t).Interface()
+	patch := reflect.New(t).Interface()
+	return diffFields(zero, v, patch, skip)
+}
+
+// removedFields turns added fields into removed fields.
+func removedFields(fields []FieldDiff) []FieldDiff {
+	for i := range fields {
+		fields[i].Before, fields[i].After = fields[i].After, fields[i].Before
+	}
+	return fields
+}
+
+// metafieldFields returns the given metafields as added fields.
+func metafieldFields(metafields []goshopify.Metafield) []FieldDiff {
+	fields := []FieldDiff{}
+	for _, m := range metafields {
+		fields = append(fields, FieldDiff{
+			Name:  fmt.Sprintf("metafields.%v.%v", m.Namespace, m.Key),
+			After: MetafieldValue(m.Value),
+		})
+	}
+	return fields
+}
+
+// optionValues returns the non-empty option values of v as a single string.
+func optionValues(v *goshopify.Variant) string {
+	values := []string{}
+	for _, o := range []string{v.Option1, v.Option2, v.Option3} {
+		if o != "" {
+			values = append(values, o)
+		}
+	}
+	if len(values) == 0 {
+		return defaultOptionValue
+	}
+	return strings.Join(values, " / ")
+}
+
+// markdownCell escapes s for use in a Markdown table cell.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
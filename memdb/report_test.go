@@ -0,0 +1,101 @@
+package memdb
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+)
+
+func newTestOperations() *Operations {
+	before := 3
+	return &Operations{
+		NewProducts: []goshopify.Product{{
+			Title:    "foo",
+			Vendor:   "acme",
+			Variants: []goshopify.Variant{{Sku: "foo-1", Option1: "S"}},
+		}},
+		DeletedVariants: []goshopify.Variant{{ID: 21, ProductID: 2, Title: "M", Sku: "bar-2"}},
+		Diffs: []Diff{{
+			Resource: ResourceVariant,
+			ID:       11,
+			Title:    "bar (S)",
+			Fields: []FieldDiff{
+				{Name: "barcode", Before: "", After: "123"},
+				{Name: "sku", Before: "bar-1", After: "bar-s"},
+			},
+		}},
+		InventoryUpdates: []InventoryUpdate{{
+			InventoryItemID: 111,
+			LocationID:      5,
+			Title:           "bar (S)",
+			Location:        "Warehouse",
+			Before:          &before,
+			Adjustment:      -2,
+		}},
+	}
+}
+
+func TestOperations_PrintDiff(t *testing.T) {
+	t.Run("prints plain diff", func(t *testing.T) {
+		b := &bytes.Buffer{}
+		if err := newTestOperations().PrintDiff(b, false); err != nil {
+			t.Fatal(err)
+		}
+		want := strings.Join([]string{
+			"@@ delete variant 21 M @@",
+			"- sku: bar-2",
+			"",
+			"@@ create product foo @@",
+			"+ title: foo",
+			"+ vendor: acme",
+			"",
+			"@@ create variant foo (S) @@",
+			"+ sku: foo-1",
+			"+ option1: S",
+			"",
+			"@@ update variant 11 bar (S) @@",
+			"+ barcode: 123",
+			"- sku: bar-1",
+			"+ sku: bar-s",
+			"",
+			"@@ update inventory 111 bar (S) at Warehouse @@",
+			"- available: 3",
+			"+ available: -2",
+			"",
+		}, "\n")
+		if got := b.String(); got != want {
+			t.Fatalf("\ngot:\n%v\nwant:\n%v", got, want)
+		}
+	})
+
+	t.Run("prints colourised diff", func(t *testing.T) {
+		b := &bytes.Buffer{}
+		if err := newTestOperations().PrintDiff(b, true); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(b.String(), ansiGreen+"+ barcode: 123"+ansiReset) {
+			t.Fatalf("expected colourised output, got:\n%v", b.String())
+		}
+	})
+}
+
+func TestOperations_PrintMarkdown(t *testing.T) {
+	b := &bytes.Buffer{}
+	if err := newTestOperations().PrintMarkdown(b); err != nil {
+		t.Fatal(err)
+	}
+	want := "| `sku` | bar-1 | bar-s |"
+	if !strings.Contains(b.String(), want) {
+		t.Fatalf("expected output to contain %q, got:\n%v", want, b.String())
+	}
+}
+
+func Test_markdownCell(t *testing.T) {
+	got := markdownCell("a|b\nc")
+	want := `a\|b<br>c`
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
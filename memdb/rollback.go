@@ -0,0 +1,156 @@
+package memdb
+
+import (
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+)
+
+// Rollback returns the operations that revert the products in the database to
+// their state in snapshot, and that delete the products, variants, metafields
+// and images in created. The database is expected to hold the current state of
+// all products in snapshot and created.
+//
+// Products and variants in snapshot that no longer exist are recreated and
+// therefore get new IDs. Empty fields are never part of an update, so the
+// [ClearableProductFields] and [ClearableVariantFields] that are empty in
+// snapshot are cleared instead. Other fields that are empty in snapshot are not
+// reverted.
+func (db *MemoryDB) Rollback(snapshot []goshopify.Product, created *Operations) (*Operations, error) {
+	restore := make([]goshopify.Product, 0, len(snapshot))
+	clearedProducts := []ClearedProductFields{}
+	clearedVariants := []ClearedFields{}
+	for _, p := range snapshot {
+		current, exists := db.Products().GetByID(p.ID)
+		if !exists {
+			restore = append(restore, Detach(p))
+			continue
+		}
+		if fields := emptyFields(&p, ClearableProductFields); len(fields) > 0 {
+			clearedProducts = append(clearedProducts, ClearedProductFields{
+				Product: goshopify.Product{ID: p.ID},
+				Fields:  fields,
+			})
+		}
+		existing := map[int64]bool{}
+		for _, v := range current.Variants {
+			existing[v.ID] = true
+		}
+		variants := make([]goshopify.Variant, len(p.Variants))
+		for i, v := range p.Variants {
+			if !existing[v.ID] {
+				v = recreatedVariant(v)
+				v.ProductID = p.ID
+			} else if fields := emptyFields(&v, ClearableVariantFields); len(fields) > 0 {
+				clearedVariants = append(clearedVariants, ClearedFields{
+					Variant: goshopify.Variant{ID: v.ID, ProductID: p.ID},
+					Fields:  fields,
+				})
+			}
+			variants[i] = v
+		}
+		p.Variants = variants
+		restore = append(restore, p)
+	}
+	operations, err := db.Operations(restore, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.ClearProductOperations(operations, clearedProducts); err != nil {
+		return nil, err
+	}
+	if err := db.ClearOperations(operations, clearedVariants); err != nil {
+		return nil, err
+	}
+	for _, p := range created.NewProducts {
+		if current, exists := db.Products().GetByID(p.ID); exists {
+			p = *current
+		}
+		operations.DeleteProduct(p)
+	}
+	for _, v := range created.NewVariants {
+		if current, exists := db.Variants().GetByID(v.ID); exists {
+			v = *current
+		}
+		operations.DeleteVariant(v)
+	}
+	for _, m := range created.NewMetafields {
+		operations.DeleteMetafield(m)
+	}
+	for _, c := range created.NewImages {
+		operations.DeleteImage(c)
+	}
+	return operations, nil
+}
+
+// emptyFields returns the fields of v, a pointer to a product or variant, that
+// are one of clearable and empty.
+func emptyFields(v any, clearable []string) []string {
+	fields := []string{}
+	for _, name := range clearable {
+		if f, ok := clearableField(v, clearable, name); ok && f.IsZero() {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// Detach returns a copy of p without the properties that are assigned by the
+// store when p is created, such that p can be matched against or created in any
+// store.
+func Detach(p goshopify.Product) goshopify.Product {
+	p.ID = 0
+	p.CreatedAt = nil
+	p.UpdatedAt = nil
+	p.AdminGraphqlAPIID = ""
+	p.Image = goshopify.Image{}
+	options := make([]goshopify.ProductOption, len(p.Options))
+	for i, o := range p.Options {
+		o.ID = 0
+		o.ProductID = 0
+		options[i] = o
+	}
+	p.Options = options
+	images := make([]goshopify.Image, len(p.Images))
+	for i, img := range p.Images {
+		images[i] = goshopify.Image{Position: img.Position, Src: img.Src}
+	}
+	p.Images = images
+	variants := make([]goshopify.Variant, len(p.Variants))
+	for i, v := range p.Variants {
+		variants[i] = recreatedVariant(v)
+	}
+	p.Variants = variants
+	p.Metafields = recreatedMetafields(p.Metafields)
+	return p
+}
+
+// recreatedVariant returns a copy of v without the properties that are
+// assigned by the store when v is created.
+func recreatedVariant(v goshopify.Variant) goshopify.Variant {
+	v.ID = 0
+	v.ProductID = 0
+	v.InventoryItemId = 0
+	v.ImageID = 0
+	v.CreatedAt = nil
+	v.UpdatedAt = nil
+	v.AdminGraphqlAPIID = ""
+	v.Metafields = recreatedMetafields(v.Metafields)
+	return v
+}
+
+// recreatedMetafields returns a copy of metafields without the properties
+// that are assigned by the store when the metafields are created.
+func recreatedMetafields(metafields []goshopify.Metafield) []goshopify.Metafield {
+	if metafields == nil {
+		return nil
+	}
+	recreated := make([]goshopify.Metafield, len(metafields))
+	for i, m := range metafields {
+		recreated[i] = goshopify.Metafield{
+			Namespace: m.Namespace,
+			Key:       m.Key,
+			Value:     m.Value,
+			Type:      m.Type,
+		}
+	}
+	return recreated
+}
@@ -0,0 +1,206 @@
+package memdb
+
+import (
+	"reflect"
+	"testing"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/shopspring/decimal"
+)
+
+func TestMemoryDB_Rollback(t *testing.T) {
+	// State of the store after a push that renamed product 1, deleted variant 12,
+	// deleted product 2, created variant 13 and product 3, and created a
+	// metafield on product 1.
+	db, err := New([]goshopify.Product{
+		{
+			ID:    1,
+			Title: "foo (renamed)",
+			Variants: []goshopify.Variant{
+				{ID: 11, ProductID: 1, Sku: "foo-1", Option1: "S"},
+				{ID: 13, ProductID: 1, Sku: "foo-3", Option1: "L"},
+			},
+		},
+		{
+			ID:    3,
+			Title: "baz",
+			Variants: []goshopify.Variant{
+				{ID: 31, ProductID: 3, Sku: "baz-1"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := []goshopify.Product{
+		{
+			ID:    1,
+			Title: "foo",
+			Variants: []goshopify.Variant{
+				{ID: 11, ProductID: 1, Sku: "foo-1", Option1: "S"},
+				{ID: 12, ProductID: 1, Sku: "foo-2", Option1: "M", InventoryItemId: 120},
+			},
+		},
+		{
+			ID:    2,
+			Title: "bar",
+			Variants: []goshopify.Variant{
+				{ID: 21, ProductID: 2, Sku: "bar-1"},
+			},
+		},
+	}
+	created := &Operations{
+		NewProducts: []goshopify.Product{{ID: 3, Title: "baz"}},
+		NewVariants: []goshopify.Variant{{ID: 13, ProductID: 1}},
+		NewMetafields: []goshopify.Metafield{
+			{ID: 100, OwnerId: 1, OwnerResource: ResourceProduct, Namespace: "ns", Key: "k"},
+		},
+	}
+
+	ops, err := db.Rollback(snapshot, created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ops.ProductUpdates) != 1 || ops.ProductUpdates[0].Title != "foo" {
+		t.Fatalf("got product updates %+v, want title of product 1 reverted", ops.ProductUpdates)
+	}
+	if len(ops.NewVariants) != 1 {
+		t.Fatalf("got new variants %+v, want variant foo-2 recreated", ops.NewVariants)
+	}
+	if v := ops.NewVariants[0]; v.ID != 0 || v.ProductID != 1 || v.Sku != "foo-2" || v.InventoryItemId != 0 {
+		t.Fatalf("got new variant %+v, want variant foo-2 of product 1 without IDs", v)
+	}
+	if len(ops.NewProducts) != 1 {
+		t.Fatalf("got new products %+v, want product bar recreated", ops.NewProducts)
+	}
+	if p := ops.NewProducts[0]; p.ID != 0 || p.Title != "bar" || p.Variants[0].ID != 0 || p.Variants[0].ProductID != 0 {
+		t.Fatalf("got new product %+v, want product bar without IDs", p)
+	}
+	if len(ops.DeletedProducts) != 1 || ops.DeletedProducts[0].ID != 3 || len(ops.DeletedProducts[0].Variants) != 1 {
+		t.Fatalf("got deleted products %+v, want product 3 with its variants", ops.DeletedProducts)
+	}
+	if len(ops.DeletedVariants) != 1 || ops.DeletedVariants[0].ID != 13 || ops.DeletedVariants[0].Sku != "foo-3" {
+		t.Fatalf("got deleted variants %+v, want variant 13", ops.DeletedVariants)
+	}
+	if len(ops.DeletedMetafields) != 1 || ops.DeletedMetafields[0].ID != 100 {
+		t.Fatalf("got deleted metafields %+v, want metafield 100", ops.DeletedMetafields)
+	}
+}
+
+func TestDetach(t *testing.T) {
+	p := Detach(goshopify.Product{
+		ID:      1,
+		Title:   "foo",
+		Options: []goshopify.ProductOption{{ID: 2, ProductID: 1, Name: "Size"}},
+		Images:  []goshopify.Image{{ID: 3, ProductID: 1, Src: "https://example.com/foo.png"}},
+		Variants: []goshopify.Variant{
+			{ID: 11, ProductID: 1, InventoryItemId: 12, ImageID: 3, Sku: "foo-1"},
+		},
+		Metafields: []goshopify.Metafield{{ID: 4, OwnerId: 1, Namespace: "ns", Key: "k", Value: "v", Type: "single_line_text_field"}},
+	})
+	if p.ID != 0 || p.Title != "foo" {
+		t.Fatalf("got product %+v, want product foo without ID", p)
+	}
+	if o := p.Options[0]; o.ID != 0 || o.ProductID != 0 || o.Name != "Size" {
+		t.Fatalf("got option %+v, want option Size without IDs", o)
+	}
+	if img := p.Images[0]; img.ID != 0 || img.ProductID != 0 || img.Src == "" {
+		t.Fatalf("got image %+v, want image with source but without IDs", img)
+	}
+	if v := p.Variants[0]; v.ID != 0 || v.ProductID != 0 || v.InventoryItemId != 0 || v.ImageID != 0 || v.Sku != "foo-1" {
+		t.Fatalf("got variant %+v, want variant foo-1 without IDs", v)
+	}
+	if m := p.Metafields[0]; m.ID != 0 || m.OwnerId != 0 || m.Value != "v" || m.Type == "" {
+		t.Fatalf("got metafield %+v, want metafield with value and type but without IDs", m)
+	}
+}
+
+func TestMemoryDB_Operations_detached(t *testing.T) {
+	// Products from another store only match by their properties.
+	db, err := New([]goshopify.Product{
+		{
+			ID:     1,
+			Title:  "foo",
+			Handle: "foo",
+			Variants: []goshopify.Variant{
+				{ID: 11, ProductID: 1, Sku: "foo-1", Option1: "S"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	incoming := Detach(goshopify.Product{
+		ID:     100,
+		Title:  "foo (renamed)",
+		Handle: "foo",
+		Variants: []goshopify.Variant{
+			{ID: 110, ProductID: 100, Sku: "foo-1", Option1: "S"},
+			{ID: 120, ProductID: 100, Sku: "foo-2", Option1: "M"},
+		},
+	})
+	ops, err := db.Operations([]goshopify.Product{incoming}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops.ProductUpdates) != 1 || ops.ProductUpdates[0].ID != 1 {
+		t.Fatalf("got product updates %+v, want update of product 1", ops.ProductUpdates)
+	}
+	if len(ops.NewVariants) != 1 || ops.NewVariants[0].ProductID != 1 || ops.NewVariants[0].Sku != "foo-2" {
+		t.Fatalf("got new variants %+v, want variant foo-2 of product 1", ops.NewVariants)
+	}
+	if ops.UnchangedVariants != 1 {
+		t.Fatalf("got %v unchanged variants, want 1", ops.UnchangedVariants)
+	}
+}
+
+func TestMemoryDB_Rollback_emptyFields(t *testing.T) {
+	// State of the store after a push that set fields which were empty before.
+	price := decimal.NewFromInt(20)
+	db, err := New([]goshopify.Product{
+		{
+			ID:       1,
+			Title:    "foo",
+			BodyHTML: "<p>foo</p>",
+			Tags:     "new",
+			Variants: []goshopify.Variant{
+				{ID: 11, ProductID: 1, Sku: "foo-1", Barcode: "123", CompareAtPrice: &price, Taxable: true},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := []goshopify.Product{
+		{
+			ID:    1,
+			Title: "foo",
+			Variants: []goshopify.Variant{
+				{ID: 11, ProductID: 1, Sku: "foo-1"},
+			},
+		},
+	}
+
+	ops, err := db.Rollback(snapshot, &Operations{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantProduct := []ProductReset{{ID: 1, Fields: map[string]any{"body_html": ""}}}
+	if !reflect.DeepEqual(ops.ProductResets, wantProduct) {
+		t.Fatalf("got product resets %+v, want %+v", ops.ProductResets, wantProduct)
+	}
+	wantTags := []TagUpdate{{ProductID: 1, Remove: []string{"new"}}}
+	if !reflect.DeepEqual(ops.TagUpdates, wantTags) {
+		t.Fatalf("got tag updates %+v, want %+v", ops.TagUpdates, wantTags)
+	}
+	wantVariant := []VariantReset{{ID: 11, ProductID: 1, Fields: map[string]any{
+		"compare_at_price": nil,
+		"barcode":          "",
+		"taxable":          false,
+	}}}
+	if !reflect.DeepEqual(ops.VariantResets, wantVariant) {
+		t.Fatalf("got variant resets %+v, want %+v", ops.VariantResets, wantVariant)
+	}
+}
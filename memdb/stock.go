@@ -0,0 +1,269 @@
+package memdb
+
+import (
+	"errors"
+	"fmt"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/shopspring/decimal"
+)
+
+// Stock is the available quantity of the inventory items of a store at its
+// locations, together with the inventory items themselves. Levels and Items
+// are indexed by the first lookup, so they must not change afterwards.
+type Stock struct {
+	Locations []goshopify.Location
+	Levels    []goshopify.InventoryLevel
+	Items     []goshopify.InventoryItem
+
+	// levelIndex maps inventory items and locations to their index in Levels.
+	levelIndex map[levelKey]int
+	// itemIndex maps the IDs of inventory items to their index in Items.
+	itemIndex map[int64]int
+}
+
+// levelKey identifies an inventory level.
+type levelKey struct {
+	itemID     int64
+	locationID int64
+}
+
+// LocationByName returns the location with the given name.
+func (s *Stock) LocationByName(name string) (*goshopify.Location, bool) {
+	for i := range s.Locations {
+		if s.Locations[i].Name == name {
+			return &s.Locations[i], true
+		}
+	}
+	return nil, false
+}
+
+// Level returns the inventory level of the inventory item at the location.
+// ok is false if the item is not stocked at the location.
+func (s *Stock) Level(itemID int64, locationID int64) (level *goshopify.InventoryLevel, ok bool) {
+	if s.levelIndex == nil {
+		s.levelIndex = make(map[levelKey]int, len(s.Levels))
+		for i, l := range s.Levels {
+			s.levelIndex[levelKey{itemID: l.InventoryItemId, locationID: l.LocationId}] = i
+		}
+	}
+	i, ok := s.levelIndex[levelKey{itemID: itemID, locationID: locationID}]
+	if !ok {
+		return nil, false
+	}
+	return &s.Levels[i], true
+}
+
+// Item returns the inventory item with the given ID.
+func (s *Stock) Item(id int64) (*goshopify.InventoryItem, bool) {
+	if s.itemIndex == nil {
+		s.itemIndex = make(map[int64]int, len(s.Items))
+		for i, item := range s.Items {
+			s.itemIndex[item.ID] = i
+		}
+	}
+	i, ok := s.itemIndex[id]
+	if !ok {
+		return nil, false
+	}
+	return &s.Items[i], true
+}
+
+// InventoryQuantity is an incoming quantity of a variant at a location.
+type InventoryQuantity struct {
+	// Variant identifies the variant the same way as the variants of incoming
+	// products, i.e. by ID, barcode, SKU or options.
+	Variant goshopify.Variant
+	// Location is the name of the location.
+	Location string
+	// Available is the available quantity, or the change of the available
+	// quantity if Relative is true.
+	Available int
+	Relative  bool
+}
+
+// InventoryCost is an incoming cost of a variant, which is the cost of its
+// inventory item.
+type InventoryCost struct {
+	// Variant identifies the variant the same way as the variants of incoming
+	// products.
+	Variant goshopify.Variant
+	Cost    decimal.Decimal
+}
+
+// InventoryItemUpdate changes the cost of an inventory item.
+type InventoryItemUpdate struct {
+	ID int64
+	// Title names the variant of the inventory item in reports.
+	Title string
+	// Before is the cost at the time the update was planned. It is nil if the
+	// item had no cost.
+	Before *decimal.Decimal `json:",omitempty"`
+	Cost   decimal.Decimal
+}
+
+// InventoryUpdate changes the available quantity of an inventory item at a
+// location.
+type InventoryUpdate struct {
+	InventoryItemID int64
+	LocationID      int64
+	// Title and Location name the variant and location in reports.
+	Title    string
+	Location string
+	// Before is the available quantity at the time the update was planned. It
+	// is nil if the item was not stocked at the location.
+	Before *int `json:",omitempty"`
+	// Available is the new available quantity. If it is nil, then the available
+	// quantity is adjusted by Adjustment instead.
+	Available  *int `json:",omitempty"`
+	Adjustment int  `json:",omitempty"`
+}
+
+// InventoryOperations appends an update to operations for every quantity and
+// cost that has been edited since checkout, i.e. that differs from checkedOut,
+// the stock that the quantities and costs were written from. Updates are
+// planned against stock, the current stock of the store, so that sales and
+// restocks since checkout are kept. An error is returned if an edited quantity
+// or cost has also changed in the store since checkout. Quantities and costs
+// are matched to variants the same way as in [MemoryDB.Operations], but the
+// variants must already exist.
+func (db *MemoryDB) InventoryOperations(
+	operations *Operations,
+	quantities []InventoryQuantity,
+	costs []InventoryCost,
+	checkedOut *Stock,
+	stock *Stock,
+) error {
+	for _, q := range quantities {
+		location, ok := stock.LocationByName(q.Location)
+		if !ok {
+			return fmt.Errorf("location %q: %w", q.Location, ErrNotExist)
+		}
+		current, title, err := db.existingVariant(q.Variant, "inventory")
+		if err != nil {
+			return err
+		}
+		update := InventoryUpdate{
+			InventoryItemID: current.InventoryItemId,
+			LocationID:      location.ID,
+			Title:           title,
+			Location:        location.Name,
+		}
+		if level, ok := stock.Level(current.InventoryItemId, location.ID); ok {
+			before := level.Available
+			update.Before = &before
+		}
+		switch {
+		case q.Relative && q.Available == 0:
+			continue
+		case q.Relative && update.Before == nil:
+			return fmt.Errorf("cannot adjust inventory of %v at %v: not stocked at location", title, location.Name)
+		case q.Relative:
+			update.Adjustment = q.Available
+		default:
+			edited, err := levelEdited(checkedOut, update, q.Available)
+			if err != nil {
+				return err
+			}
+			if !edited {
+				continue
+			}
+			available := q.Available
+			update.Available = &available
+		}
+		operations.UpdateInventory(update)
+	}
+	for _, c := range costs {
+		current, title, err := db.existingVariant(c.Variant, "cost")
+		if err != nil {
+			return err
+		}
+		update := InventoryItemUpdate{
+			ID:    current.InventoryItemId,
+			Title: title,
+			Cost:  c.Cost,
+		}
+		if item, ok := stock.Item(current.InventoryItemId); ok && item.Cost != nil {
+			before := *item.Cost
+			update.Before = &before
+		}
+		edited, err := costEdited(checkedOut, update)
+		if err != nil {
+			return err
+		}
+		if edited {
+			operations.UpdateInventoryItem(update)
+		}
+	}
+	return nil
+}
+
+// levelEdited reports whether available needs to be set because it differs
+// from the checked out quantity of the inventory level of u as well as from the
+// quantity in the store, u.Before. An error is returned if available has been
+// edited and the quantity in the store differs from the checked out one.
+func levelEdited(checkedOut *Stock, u InventoryUpdate, available int) (bool, error) {
+	if u.Before != nil && *u.Before == available {
+		return false, nil
+	}
+	level, ok := checkedOut.Level(u.InventoryItemID, u.LocationID)
+	if !ok {
+		return true, nil
+	}
+	if level.Available == available {
+		return false, nil
+	}
+	if u.Before == nil || *u.Before != level.Available {
+		return false, fmt.Errorf("inventory of %v at %v %w", u.Title, u.Location, ErrChanged)
+	}
+	return true, nil
+}
+
+// costEdited reports whether the cost of u needs to be set because it differs
+// from the checked out cost of its inventory item as well as from the cost in
+// the store, u.Before. An error is returned if the cost has been edited and
+// the cost in the store differs from the checked out one.
+func costEdited(checkedOut *Stock, u InventoryItemUpdate) (bool, error) {
+	if u.Before != nil && u.Before.Equal(u.Cost) {
+		return false, nil
+	}
+	item, ok := checkedOut.Item(u.ID)
+	if !ok {
+		return true, nil
+	}
+	if item.Cost != nil && item.Cost.Equal(u.Cost) {
+		return false, nil
+	}
+	if !equalCosts(item.Cost, u.Before) {
+		return false, fmt.Errorf("cost of %v %w", u.Title, ErrChanged)
+	}
+	return true, nil
+}
+
+// equalCosts reports whether a and b are both nil or equal.
+func equalCosts(a *decimal.Decimal, b *decimal.Decimal) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+// existingVariant returns the variant in the database that matches v, together
+// with its title as shown in reports. An error is returned if there is no
+// match. property names what is set on the variant in the error.
+func (db *MemoryDB) existingVariant(v goshopify.Variant, property string) (*goshopify.Variant, string, error) {
+	err := db.Variants().PatchID(&v)
+	if errors.Is(err, ErrNotExist) {
+		return nil, "", fmt.Errorf(
+			"cannot set %v of new variant %v (%v), push the variant first",
+			property,
+			v.Sku,
+			optionValues(&v),
+		)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	current, _ := db.Variants().GetByID(v.ID)
+	return current, fmt.Sprintf("%v (%v)", db.productTitle(current), current.Title), nil
+}
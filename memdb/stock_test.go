@@ -0,0 +1,178 @@
+package memdb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/shopspring/decimal"
+)
+
+func TestMemoryDB_InventoryOperations(t *testing.T) {
+	db, err := New([]goshopify.Product{
+		{
+			ID:    1,
+			Title: "foo",
+			Variants: []goshopify.Variant{
+				{ID: 11, ProductID: 1, Sku: "foo-1", Title: "S", Option1: "S", InventoryItemId: 111},
+				{ID: 12, ProductID: 1, Sku: "foo-2", Title: "M", Option1: "M", InventoryItemId: 112},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cost := decimal.NewFromFloat(2.5)
+	stock := &Stock{
+		Locations: []goshopify.Location{{ID: 5, Name: "Warehouse"}, {ID: 6, Name: "Shop"}},
+		Levels: []goshopify.InventoryLevel{
+			{InventoryItemId: 111, LocationId: 5, Available: 3},
+			{InventoryItemId: 112, LocationId: 5, Available: 4},
+		},
+		Items: []goshopify.InventoryItem{
+			{ID: 111, Cost: &cost},
+		},
+	}
+	intPtr := func(i int) *int { return &i }
+
+	tests := []struct {
+		name       string
+		quantities []InventoryQuantity
+		// checkedOut is the stock that the quantities were written from. It
+		// defaults to the current stock.
+		checkedOut *Stock
+		want       []InventoryUpdate
+		wantErr    error
+	}{
+		{
+			name: "sets changed quantities",
+			quantities: []InventoryQuantity{
+				{Variant: goshopify.Variant{Sku: "foo-1"}, Location: "Warehouse", Available: 7},
+				{Variant: goshopify.Variant{Sku: "foo-2"}, Location: "Warehouse", Available: 4},
+				{Variant: goshopify.Variant{ID: 12}, Location: "Shop", Available: 1},
+			},
+			want: []InventoryUpdate{
+				{InventoryItemID: 111, LocationID: 5, Title: "foo (S)", Location: "Warehouse", Before: intPtr(3), Available: intPtr(7)},
+				{InventoryItemID: 112, LocationID: 6, Title: "foo (M)", Location: "Shop", Available: intPtr(1)},
+			},
+		},
+		{
+			name: "adjusts quantities",
+			quantities: []InventoryQuantity{
+				{Variant: goshopify.Variant{Sku: "foo-1"}, Location: "Warehouse", Available: -2, Relative: true},
+				{Variant: goshopify.Variant{Sku: "foo-2"}, Location: "Warehouse", Available: 0, Relative: true},
+			},
+			want: []InventoryUpdate{
+				{InventoryItemID: 111, LocationID: 5, Title: "foo (S)", Location: "Warehouse", Before: intPtr(3), Adjustment: -2},
+			},
+		},
+		{
+			name: "keeps quantities changed in the store since checkout",
+			quantities: []InventoryQuantity{
+				{Variant: goshopify.Variant{Sku: "foo-1"}, Location: "Warehouse", Available: 5},
+				{Variant: goshopify.Variant{Sku: "foo-2"}, Location: "Warehouse", Available: 9},
+			},
+			checkedOut: &Stock{Levels: []goshopify.InventoryLevel{
+				{InventoryItemId: 111, LocationId: 5, Available: 5},
+				{InventoryItemId: 112, LocationId: 5, Available: 4},
+			}},
+			want: []InventoryUpdate{
+				{InventoryItemID: 112, LocationID: 5, Title: "foo (M)", Location: "Warehouse", Before: intPtr(4), Available: intPtr(9)},
+			},
+		},
+		{
+			name: "returns error for edited quantity that changed in the store since checkout",
+			quantities: []InventoryQuantity{
+				{Variant: goshopify.Variant{Sku: "foo-1"}, Location: "Warehouse", Available: 7},
+			},
+			checkedOut: &Stock{Levels: []goshopify.InventoryLevel{
+				{InventoryItemId: 111, LocationId: 5, Available: 5},
+			}},
+			wantErr: ErrChanged,
+		},
+		{
+			name: "returns error for unknown location",
+			quantities: []InventoryQuantity{
+				{Variant: goshopify.Variant{Sku: "foo-1"}, Location: "Attic", Available: 1},
+			},
+			wantErr: ErrNotExist,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkedOut := tt.checkedOut
+			if checkedOut == nil {
+				checkedOut = stock
+			}
+			ops := &Operations{}
+			err := db.InventoryOperations(ops, tt.quantities, nil, checkedOut, stock)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ops.InventoryUpdates, tt.want) {
+				t.Fatalf("got %+v, want %+v", ops.InventoryUpdates, tt.want)
+			}
+		})
+	}
+
+	t.Run("returns error for adjustment at unstocked location", func(t *testing.T) {
+		quantities := []InventoryQuantity{
+			{Variant: goshopify.Variant{Sku: "foo-1"}, Location: "Shop", Available: 1, Relative: true},
+		}
+		if err := db.InventoryOperations(&Operations{}, quantities, nil, stock, stock); err == nil {
+			t.Fatal("expected error but didn't get one")
+		}
+	})
+
+	t.Run("returns error for new variant", func(t *testing.T) {
+		quantities := []InventoryQuantity{
+			{Variant: goshopify.Variant{Sku: "foo-3"}, Location: "Shop", Available: 1},
+		}
+		if err := db.InventoryOperations(&Operations{}, quantities, nil, stock, stock); err == nil {
+			t.Fatal("expected error but didn't get one")
+		}
+	})
+}
+
+func TestMemoryDB_InventoryOperations_costs(t *testing.T) {
+	db, err := New([]goshopify.Product{{
+		ID:    1,
+		Title: "foo",
+		Variants: []goshopify.Variant{
+			{ID: 11, ProductID: 1, Sku: "foo-1", Title: "S", Option1: "S", InventoryItemId: 111},
+			{ID: 12, ProductID: 1, Sku: "foo-2", Title: "M", Option1: "M", InventoryItemId: 112},
+			{ID: 13, ProductID: 1, Sku: "foo-3", Title: "L", Option1: "L", InventoryItemId: 113},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := decimal.NewFromFloat(2.5)
+	stock := &Stock{Items: []goshopify.InventoryItem{
+		{ID: 111, Cost: &before},
+		{ID: 112, Cost: &before},
+	}}
+	costs := []InventoryCost{
+		{Variant: goshopify.Variant{Sku: "foo-1"}, Cost: decimal.NewFromFloat(2.50)},
+		{Variant: goshopify.Variant{Sku: "foo-2"}, Cost: decimal.NewFromInt(3)},
+		{Variant: goshopify.Variant{Sku: "foo-3"}, Cost: decimal.NewFromInt(1)},
+	}
+	ops := &Operations{}
+	if err := db.InventoryOperations(ops, nil, costs, stock, stock); err != nil {
+		t.Fatal(err)
+	}
+	want := []InventoryItemUpdate{
+		{ID: 112, Title: "foo (M)", Before: &before, Cost: decimal.NewFromInt(3)},
+		{ID: 113, Title: "foo (L)", Cost: decimal.NewFromInt(1)},
+	}
+	if !reflect.DeepEqual(ops.InventoryItemUpdates, want) {
+		t.Fatalf("got %+v, want %+v", ops.InventoryItemUpdates, want)
+	}
+}
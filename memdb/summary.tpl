@@ -1,4 +1,53 @@
-New Products:    {{len .NewProducts}}
-Product Updates: {{len .ProductUpdates}}
-New Variants:    {{len .NewVariants}}
-Variant Updates: {{len .VariantUpdates}}
+New Products:       {{len .NewProducts}}
+Product Updates:    {{len .ProductUpdates}}
+Tag Updates:        {{len .TagUpdates}}
+Unchanged Products: {{.UnchangedProducts}}
+New Variants:       {{len .NewVariants}}
+Variant Updates:    {{len .VariantUpdates}}
+Unchanged Variants: {{.UnchangedVariants}}
+New Metafields:     {{len .NewMetafields}}
+Metafield Updates:  {{len .MetafieldUpdates}}
+New Images:         {{len .NewImages}}
+Image Updates:      {{len .ImageUpdates}}
+Inventory Updates:  {{len .InventoryUpdates}}
+Cost Updates:       {{len .InventoryItemUpdates}}
+Deleted Products:   {{len .DeletedProducts}}
+{{- range .DeletedProducts}}
+  - {{.Title}} (ID {{.ID}})
+{{- end}}
+Deleted Variants:   {{len .DeletedVariants}}
+{{- range .DeletedVariants}}
+  - {{.Title}} (ID {{.ID}}, SKU {{.Sku}})
+{{- end}}
+Deleted Metafields: {{len .DeletedMetafields}}
+{{- range .DeletedMetafields}}
+  - {{.Namespace}}.{{.Key}} (ID {{.ID}}, {{.OwnerResource}} {{.OwnerId}})
+{{- end}}
+Deleted Images:     {{len .DeletedImages}}
+{{- range .DeletedImages}}
+  - {{.Src}} (ID {{.ID}}, product {{.ProductID}})
+{{- end}}
+{{- if .Diffs}}
+
+Changes:
+{{- range .Diffs}}
+  {{.Resource}} {{.ID}} {{.Title}}
+  {{- range .Fields}}
+    {{.Name}}: {{printf "%q" .Before}} -> {{printf "%q" .After}}
+  {{- end}}
+{{- end}}
+{{- end}}
+{{- if .InventoryUpdates}}
+
+Inventory:
+{{- range .InventoryUpdates}}
+  {{.Title}} at {{.Location}}: {{with .Available}}{{.}}{{else}}{{printf "%+d" .Adjustment}}{{end}}
+{{- end}}
+{{- end}}
+{{- if .InventoryItemUpdates}}
+
+Costs:
+{{- range .InventoryItemUpdates}}
+  {{.Title}}: {{with .Before}}{{.}}{{else}}none{{end}} -> {{.Cost}}
+{{- end}}
+{{- end}}
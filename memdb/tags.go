@@ -0,0 +1,82 @@
+package memdb
+
+import (
+	"strings"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+)
+
+// TagUpdate adds tags to and removes tags from an existing product. Tags that
+// are not in Add or Remove are kept, including tags that have been added to
+// the product since the update was computed.
+type TagUpdate struct {
+	ProductID int64
+	Add       []string `json:",omitempty"`
+	Remove    []string `json:",omitempty"`
+}
+
+// SplitTags returns the tags in the comma separated list s, with surrounding
+// whitespace and empty tags removed.
+func SplitTags(s string) []string {
+	tags := []string{}
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// ApplyTags returns the comma separated list of tags s with the tags in add
+// appended and the tags in remove removed. Tags that are already in s are not
+// added again.
+func ApplyTags(s string, add []string, remove []string) string {
+	tags := []string{}
+	for _, t := range append(SplitTags(s), add...) {
+		if !containsTag(tags, t) && !containsTag(remove, t) {
+			tags = append(tags, t)
+		}
+	}
+	return strings.Join(tags, ", ")
+}
+
+// diffTags compares the tags of incoming against the tags of current as sets,
+// ignoring case like the store does. It returns nil if incoming has no tags,
+// because the Shopify API omits empty values, or if both have the same tags.
+// Tags are cleared by [MemoryDB.ClearProductOperations] instead.
+func diffTags(current *goshopify.Product, incoming *goshopify.Product) (*TagUpdate, *FieldDiff) {
+	if incoming.Tags == "" {
+		return nil, nil
+	}
+	before := SplitTags(current.Tags)
+	after := SplitTags(incoming.Tags)
+	u := &TagUpdate{ProductID: current.ID}
+	for _, t := range after {
+		if !containsTag(before, t) && !containsTag(u.Add, t) {
+			u.Add = append(u.Add, t)
+		}
+	}
+	for _, t := range before {
+		if !containsTag(after, t) {
+			u.Remove = append(u.Remove, t)
+		}
+	}
+	if len(u.Add) == 0 && len(u.Remove) == 0 {
+		return nil, nil
+	}
+	// The diff lists only the removed and the added tags.
+	return u, &FieldDiff{
+		Name:   "tags",
+		Before: strings.Join(u.Remove, ", "),
+		After:  strings.Join(u.Add, ", "),
+	}
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package memdb
+
+import (
+	"reflect"
+	"testing"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+)
+
+func Test_diffTags(t *testing.T) {
+	current := &goshopify.Product{ID: 1, Tags: "cotton, summer, sale"}
+	tests := []struct {
+		name string
+		tags string
+		want *TagUpdate
+	}{
+		{name: "ignores empty tags", tags: ""},
+		{name: "ignores order and whitespace", tags: "sale,cotton ,  summer"},
+		{name: "ignores case", tags: "Cotton, SUMMER, sale"},
+		{
+			name: "adds and removes tags",
+			tags: "cotton, winter, summer, winter",
+			want: &TagUpdate{ProductID: 1, Add: []string{"winter"}, Remove: []string{"sale"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, field := diffTags(current, &goshopify.Product{Tags: tt.tags})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			if (field != nil) != (tt.want != nil) {
+				t.Fatalf("got field %+v", field)
+			}
+		})
+	}
+}
+
+func TestApplyTags(t *testing.T) {
+	got := ApplyTags("cotton, sale, new", []string{"winter", "cotton"}, []string{"sale"})
+	if want := "cotton, new, winter"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
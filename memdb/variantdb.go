@@ -163,7 +163,8 @@ func (db *VariantDB) PatchID(v *goshopify.Variant) error {
 }
 
 func validateProductID(current *goshopify.Variant, incoming *goshopify.Variant) error {
-	if current.ID != incoming.ID {
+	// If a variant ID is provided, then it must match the current variant ID.
+	if incoming.ID != 0 && incoming.ID != current.ID {
 		return fmt.Errorf(
 			"variant ID mismatch: current = %v, incoming = %v",
 			current.ID,
@@ -32,6 +32,12 @@ func Test_validateProductID(t *testing.T) {
 			incoming: &goshopify.Variant{ID: 1, ProductID: 2},
 			wantErr:  false,
 		},
+		{
+			name:     "incoming variant ID not set",
+			current:  &goshopify.Variant{ID: 1, ProductID: 2},
+			incoming: &goshopify.Variant{ID: 0, ProductID: 2},
+			wantErr:  false,
+		},
 		{
 			name:     "incoming product ID not set",
 			current:  &goshopify.Variant{ID: 1, ProductID: 2},
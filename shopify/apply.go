@@ -0,0 +1,371 @@
+package shopify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/samherrmann/merchant/memdb"
+)
+
+// resourceMetafield is the name of the metafield resource in step keys.
+const resourceMetafield = "metafield"
+
+// step is a single write request to the store.
+type step struct {
+	// key identifies the step within its operations. Steps for new resources
+	// are identified by their index because they don't have an ID yet.
+	key string
+	// apply applies the step and returns the ID of the resource that it
+	// created, or zero if it didn't create a resource.
+	apply func(s services) (int64, error)
+}
+
+// applyOperations applies the given operations to the store. The steps of the
+// operations are applied concurrently with one worker per set of services in
+// pool. Steps that the journal reports as completed are skipped and every
+// applied step is recorded in the journal, so that an interrupted push can be
+// resumed. Failed steps don't prevent the remaining steps from being applied.
+func applyOperations(ctx context.Context, pool []services, operations *memdb.Operations, journal Journal) error {
+	completed, err := journal.Completed()
+	if err != nil {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+	errs := []error{}
+	for _, phase := range phases(operations) {
+		pending := []step{}
+		for _, s := range phase {
+			if _, done := completed[s.key]; !done {
+				pending = append(pending, s)
+			}
+		}
+		failed, err := applySteps(ctx, pool, pending, journal)
+		errs = append(errs, failed...)
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// applySteps applies steps concurrently and returns the errors of the steps
+// that failed. The returned error is non-nil if the steps could not all be
+// attempted because ctx has been canceled or the journal failed.
+func applySteps(ctx context.Context, pool []services, steps []step, journal Journal) ([]error, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	jobs := make(chan step)
+	applied := make(chan appliedStep, len(pool))
+	recorded := make(chan struct{})
+	go func() {
+		defer close(recorded)
+		recordSteps(applied, journal, cancel)
+	}()
+
+	var mu sync.Mutex
+	failed := []error{}
+	wg := sync.WaitGroup{}
+	for _, s := range pool {
+		wg.Add(1)
+		go func(s services) {
+			defer wg.Done()
+			for st := range jobs {
+				id, err := st.apply(s)
+				if err != nil {
+					mu.Lock()
+					failed = append(failed, fmt.Errorf("%v: %w", st.key, err))
+					mu.Unlock()
+					continue
+				}
+				applied <- appliedStep{key: st.key, id: id}
+			}
+		}(s)
+	}
+dispatch:
+	for _, st := range steps {
+		select {
+		case jobs <- st:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	close(applied)
+	<-recorded
+	return failed, context.Cause(ctx)
+}
+
+// appliedStep is a step that has been applied to the store.
+type appliedStep struct {
+	key string
+	id  int64
+}
+
+// recordSteps records the steps received from applied in the journal until
+// applied is closed. Steps that are applied while the journal is being written
+// are recorded together in the next write, so that workers don't wait for the
+// journal. If the journal fails, cancel is called and no further steps are
+// recorded.
+func recordSteps(applied <-chan appliedStep, journal Journal, cancel context.CancelCauseFunc) {
+	var err error
+	for st := range applied {
+		completed := map[string]int64{st.key: st.id}
+	batch:
+		for {
+			select {
+			case st, ok := <-applied:
+				if !ok {
+					break batch
+				}
+				completed[st.key] = st.id
+			default:
+				break batch
+			}
+		}
+		if err != nil {
+			continue
+		}
+		if err = journal.Record(completed); err != nil {
+			cancel(fmt.Errorf("failed to record journal: %w", err))
+		}
+	}
+}
+
+// phases returns the steps of the given operations grouped in phases. The
+// steps within a phase are independent of each other and never write the same
+// resource, but a phase must be completed before the next one starts.
+func phases(operations *memdb.Operations) [][]step {
+	deletions := []step{}
+	for _, v := range operations.DeletedVariants {
+		v := v
+		deletions = append(deletions, step{
+			key: deleteKey(memdb.ResourceVariant, v.ID),
+			apply: func(s services) (int64, error) {
+				return 0, s.Variant.Delete(v.ProductID, v.ID)
+			},
+		})
+	}
+	for _, m := range operations.DeletedMetafields {
+		m := m
+		deletions = append(deletions, step{
+			key: deleteKey(resourceMetafield, m.ID),
+			apply: func(s services) (int64, error) {
+				return 0, deleteMetafield(s.Product, s.Variant, m)
+			},
+		})
+	}
+	for _, c := range operations.DeletedImages {
+		c := c
+		deletions = append(deletions, step{
+			key: deleteKey(resourceImage, c.ID),
+			apply: func(s services) (int64, error) {
+				return 0, deleteImage(s.Raw, c)
+			},
+		})
+	}
+	deletedProducts := []step{}
+	for _, p := range operations.DeletedProducts {
+		p := p
+		deletedProducts = append(deletedProducts, step{
+			key: deleteKey(memdb.ResourceProduct, p.ID),
+			apply: func(s services) (int64, error) {
+				return 0, s.Product.Delete(p.ID)
+			},
+		})
+	}
+	products := []step{}
+	for i, p := range operations.NewProducts {
+		p := p
+		products = append(products, step{
+			key: createKey(memdb.ResourceProduct, i),
+			apply: func(s services) (int64, error) {
+				created, err := s.Product.Create(p)
+				if err != nil {
+					return 0, err
+				}
+				return created.ID, nil
+			},
+		})
+	}
+	for _, p := range operations.ProductUpdates {
+		p := p
+		products = append(products, step{
+			key: updateKey(memdb.ResourceProduct, p.ID),
+			apply: func(s services) (int64, error) {
+				_, err := s.Product.Update(p)
+				return 0, err
+			},
+		})
+	}
+	// Tags are updated after the products because updateTags reads the tags
+	// before writing them.
+	tags := []step{}
+	for _, u := range operations.TagUpdates {
+		u := u
+		tags = append(tags, step{
+			key: tagsKey(u.ProductID),
+			apply: func(s services) (int64, error) {
+				return 0, updateTags(s.Product, s.Raw, u)
+			},
+		})
+	}
+	variants := []step{}
+	for i, v := range operations.NewVariants {
+		v := v
+		variants = append(variants, step{
+			key: createKey(memdb.ResourceVariant, i),
+			apply: func(s services) (int64, error) {
+				created, err := s.Variant.Create(v.ProductID, v)
+				if err != nil {
+					return 0, err
+				}
+				return created.ID, nil
+			},
+		})
+	}
+	for _, v := range operations.VariantUpdates {
+		v := v
+		variants = append(variants, step{
+			key: updateKey(memdb.ResourceVariant, v.ID),
+			apply: func(s services) (int64, error) {
+				_, err := s.Variant.Update(v)
+				return 0, err
+			},
+		})
+	}
+	// Resets are applied after the updates of the same resources, so that the
+	// two requests don't overwrite each other.
+	resets := []step{}
+	for _, r := range operations.ProductResets {
+		r := r
+		resets = append(resets, step{
+			key: resetKey(memdb.ResourceProduct, r.ID),
+			apply: func(s services) (int64, error) {
+				return 0, resetProduct(s.Raw, r)
+			},
+		})
+	}
+	for _, r := range operations.VariantResets {
+		r := r
+		resets = append(resets, step{
+			key: resetKey(memdb.ResourceVariant, r.ID),
+			apply: func(s services) (int64, error) {
+				return 0, resetVariant(s.Raw, r)
+			},
+		})
+	}
+	// Images are applied after the variants because they may be assigned to
+	// them.
+	images := []step{}
+	for i, c := range operations.NewImages {
+		c := c
+		images = append(images, step{
+			key: createKey(resourceImage, i),
+			apply: func(s services) (int64, error) {
+				return createImage(s.Raw, c)
+			},
+		})
+	}
+	for _, c := range operations.ImageUpdates {
+		c := c
+		images = append(images, step{
+			key: updateKey(resourceImage, c.ID),
+			apply: func(s services) (int64, error) {
+				return 0, updateImage(s.Raw, c)
+			},
+		})
+	}
+	metafields := []step{}
+	for i, m := range operations.NewMetafields {
+		m := m
+		metafields = append(metafields, step{
+			key: createKey(resourceMetafield, i),
+			apply: func(s services) (int64, error) {
+				return createMetafield(s.Product, s.Variant, m)
+			},
+		})
+	}
+	for _, m := range operations.MetafieldUpdates {
+		m := m
+		metafields = append(metafields, step{
+			key: updateKey(resourceMetafield, m.ID),
+			apply: func(s services) (int64, error) {
+				return 0, updateMetafield(s.Product, s.Variant, m)
+			},
+		})
+	}
+	inventory := []step{}
+	for _, u := range operations.InventoryUpdates {
+		u := u
+		inventory = append(inventory, step{
+			key: inventoryKey(u),
+			apply: func(s services) (int64, error) {
+				return 0, updateInventory(s.InventoryLevel, u)
+			},
+		})
+	}
+	for _, u := range operations.InventoryItemUpdates {
+		u := u
+		inventory = append(inventory, step{
+			key: updateKey(resourceInventoryItem, u.ID),
+			apply: func(s services) (int64, error) {
+				return 0, updateInventoryItem(s.InventoryItem, u)
+			},
+		})
+	}
+	// Deletions are applied first so that new variants can reuse the options of
+	// deleted variants.
+	return [][]step{deletions, deletedProducts, products, tags, variants, resets, images, metafields, inventory}
+}
+
+// CreatedResources returns the create operations of operations that the
+// journal has recorded as completed, with the IDs of the created resources
+// set.
+func CreatedResources(operations *memdb.Operations, completed map[string]int64) *memdb.Operations {
+	created := &memdb.Operations{}
+	for i, p := range operations.NewProducts {
+		if id := completed[createKey(memdb.ResourceProduct, i)]; id != 0 {
+			p.ID = id
+			created.CreateProduct(p)
+		}
+	}
+	for i, v := range operations.NewVariants {
+		if id := completed[createKey(memdb.ResourceVariant, i)]; id != 0 {
+			v.ID = id
+			created.CreateVariant(v)
+		}
+	}
+	for i, m := range operations.NewMetafields {
+		if id := completed[createKey(resourceMetafield, i)]; id != 0 {
+			m.ID = id
+			created.CreateMetafield(m)
+		}
+	}
+	for i, c := range operations.NewImages {
+		if id := completed[createKey(resourceImage, i)]; id != 0 {
+			c.ID = id
+			created.CreateImage(c)
+		}
+	}
+	return created
+}
+
+// createKey returns the key of the step that creates the i-th new resource.
+func createKey(resource string, i int) string {
+	return fmt.Sprintf("%v.create.%v", resource, i)
+}
+
+// updateKey returns the key of the step that updates the resource with the
+// given ID.
+func updateKey(resource string, id int64) string {
+	return fmt.Sprintf("%v.update.%v", resource, id)
+}
+
+// deleteKey returns the key of the step that deletes the resource with the
+// given ID.
+func deleteKey(resource string, id int64) string {
+	return fmt.Sprintf("%v.delete.%v", resource, id)
+}
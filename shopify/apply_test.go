@@ -0,0 +1,214 @@
+package shopify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/samherrmann/merchant/memdb"
+)
+
+func Test_applyOperations(t *testing.T) {
+	operations := &memdb.Operations{
+		ProductUpdates:  []Product{{ID: 1}, {ID: 2}},
+		VariantUpdates:  []Variant{{ID: 11}},
+		DeletedVariants: []Variant{{ID: 12, ProductID: 1}},
+	}
+
+	t.Run("records applied steps", func(t *testing.T) {
+		recorder := &fakeRecorder{}
+		journal := &fakeJournal{completed: map[string]int64{}}
+		pool := []services{recorder.services(nil), recorder.services(nil)}
+		if err := applyOperations(context.Background(), pool, operations, journal); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"product.update.1", "product.update.2", "variant.delete.12", "variant.update.11"}
+		if got := journal.recordedKeys(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("skips completed steps", func(t *testing.T) {
+		recorder := &fakeRecorder{}
+		journal := &fakeJournal{completed: map[string]int64{
+			"product.update.1":  0,
+			"variant.delete.12": 0,
+		}}
+		pool := []services{recorder.services(nil)}
+		if err := applyOperations(context.Background(), pool, operations, journal); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"product.update.2", "variant.update.11"}
+		if got := recorder.calls; !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("continues after failed steps", func(t *testing.T) {
+		errFake := errors.New("fake")
+		recorder := &fakeRecorder{}
+		journal := &fakeJournal{completed: map[string]int64{}}
+		pool := []services{recorder.services(map[int64]error{1: errFake})}
+		err := applyOperations(context.Background(), pool, operations, journal)
+		if !errors.Is(err, errFake) {
+			t.Fatalf("got %v, want %v", err, errFake)
+		}
+		want := []string{"product.update.2", "variant.delete.12", "variant.update.11"}
+		if got := journal.recordedKeys(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("stops if journal fails", func(t *testing.T) {
+		errFake := errors.New("fake")
+		recorder := &fakeRecorder{}
+		journal := &fakeJournal{completed: map[string]int64{}, err: errFake}
+		pool := []services{recorder.services(nil)}
+		err := applyOperations(context.Background(), pool, operations, journal)
+		if !errors.Is(err, errFake) {
+			t.Fatalf("got %v, want %v", err, errFake)
+		}
+		want := []string{"variant.delete.12"}
+		if got := recorder.calls; !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	})
+}
+
+func Test_phases(t *testing.T) {
+	operations := &memdb.Operations{
+		ProductUpdates: []Product{{ID: 1}},
+		TagUpdates:     []memdb.TagUpdate{{ProductID: 1}},
+		ProductResets:  []memdb.ProductReset{{ID: 1}},
+		VariantUpdates: []Variant{{ID: 11}},
+		VariantResets:  []memdb.VariantReset{{ID: 11}},
+	}
+	for i, phase := range phases(operations) {
+		written := map[string]string{}
+		for _, s := range phase {
+			parts := strings.Split(s.key, ".")
+			resource := parts[0] + "." + parts[len(parts)-1]
+			if other, ok := written[resource]; ok {
+				t.Fatalf("phase %v applies %v and %v concurrently", i, other, s.key)
+			}
+			written[resource] = s.key
+		}
+	}
+}
+
+func TestCreatedResources(t *testing.T) {
+	operations := &memdb.Operations{
+		NewProducts:   []Product{{Title: "foo"}, {Title: "bar"}},
+		NewVariants:   []Variant{{ProductID: 1, Sku: "baz"}},
+		NewMetafields: []Metafield{{OwnerId: 1, Key: "qux"}},
+	}
+	completed := map[string]int64{
+		"product.create.1":   2,
+		"variant.create.0":   21,
+		"product.update.3":   0,
+		"metafield.create.0": 31,
+	}
+	got := CreatedResources(operations, completed)
+	want := &memdb.Operations{
+		NewProducts:   []Product{{ID: 2, Title: "bar"}},
+		NewVariants:   []Variant{{ID: 21, ProductID: 1, Sku: "baz"}},
+		NewMetafields: []Metafield{{ID: 31, OwnerId: 1, Key: "qux"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+// fakeRecorder records the update and delete calls made through its services.
+type fakeRecorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+// services returns services that fail with the error in errs for the given
+// product or variant ID.
+func (r *fakeRecorder) services(errs map[int64]error) services {
+	return services{
+		Product: &fakeProductService{recorder: r, errs: errs},
+		Variant: &fakeVariantService{recorder: r, errs: errs},
+	}
+}
+
+func (r *fakeRecorder) record(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+type fakeProductService struct {
+	// ProductService is embedded so that only the methods under test need to be
+	// implemented.
+	ProductService
+	recorder *fakeRecorder
+	errs     map[int64]error
+}
+
+func (s *fakeProductService) Update(p Product) (*Product, error) {
+	if err := s.errs[p.ID]; err != nil {
+		return nil, err
+	}
+	s.recorder.record(fmt.Sprintf("product.update.%v", p.ID))
+	return &p, nil
+}
+
+type fakeVariantService struct {
+	// VariantService is embedded so that only the methods under test need to be
+	// implemented.
+	VariantService
+	recorder *fakeRecorder
+	errs     map[int64]error
+}
+
+func (s *fakeVariantService) Update(v Variant) (*Variant, error) {
+	if err := s.errs[v.ID]; err != nil {
+		return nil, err
+	}
+	s.recorder.record(fmt.Sprintf("variant.update.%v", v.ID))
+	return &v, nil
+}
+
+func (s *fakeVariantService) Delete(productID int64, variantID int64) error {
+	if err := s.errs[variantID]; err != nil {
+		return err
+	}
+	s.recorder.record(fmt.Sprintf("variant.delete.%v", variantID))
+	return nil
+}
+
+type fakeJournal struct {
+	completed map[string]int64
+	recorded  []string
+	err       error
+}
+
+func (j *fakeJournal) Completed() (map[string]int64, error) {
+	return j.completed, nil
+}
+
+func (j *fakeJournal) Record(completed map[string]int64) error {
+	if j.err != nil {
+		return j.err
+	}
+	for key := range completed {
+		j.recorded = append(j.recorded, key)
+	}
+	return nil
+}
+
+// recordedKeys returns the recorded keys in sorted order, because steps within
+// a phase are applied in no particular order.
+func (j *fakeJournal) recordedKeys() []string {
+	keys := append([]string{}, j.recorded...)
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,591 @@
+package shopify
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samherrmann/merchant/memdb"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// bulkAPIVersion is the Admin API version of bulk operations if no version
+	// is configured. It must be updated before Shopify retires it. The bulk
+	// query relies on the inventory item measurement, which is available since
+	// 2024-04.
+	bulkAPIVersion = "2026-07"
+	// bulkPollInterval is the time between two checks of the status of a bulk
+	// operation.
+	bulkPollInterval = 2 * time.Second
+)
+
+// Status values of a bulk operation.
+// https://shopify.dev/docs/api/admin-graphql/2026-07/enums/BulkOperationStatus
+const (
+	bulkStatusCompleted = "COMPLETED"
+	bulkStatusCanceled  = "CANCELED"
+	bulkStatusExpired   = "EXPIRED"
+	bulkStatusFailed    = "FAILED"
+)
+
+// bulkProductsQuery is the query of a bulk operation that exports products
+// together with their images, variants and metafields. The %s verb is replaced by the
+// arguments of the products connection. The published scope is not available
+// through GraphQL, see [Client.getBulkProducts].
+const bulkProductsQuery = `{
+  products%s {
+    edges {
+      node {
+        id
+        title
+        descriptionHtml
+        vendor
+        productType
+        handle
+        tags
+        status
+        templateSuffix
+        seo { title description }
+        createdAt
+        updatedAt
+        publishedAt
+        options { id name position values }
+        images {
+          edges { node { id url width height } }
+        }
+        metafields {
+          edges { node { id namespace key value type createdAt updatedAt } }
+        }
+        variants {
+          edges {
+            node {
+              id
+              title
+              sku
+              barcode
+              position
+              price
+              compareAtPrice
+              inventoryPolicy
+              inventoryQuantity
+              taxable
+              createdAt
+              updatedAt
+              selectedOptions { name value }
+              image { id }
+              inventoryItem {
+                id
+                tracked
+                requiresShipping
+                measurement { weight { value unit } }
+              }
+              metafields {
+                edges { node { id namespace key value type createdAt updatedAt } }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+const bulkRunMutation = `mutation bulkOperationRunQuery($query: String!) {
+  bulkOperationRunQuery(query: $query) {
+    bulkOperation { id status }
+    userErrors { field message }
+  }
+}`
+
+const bulkCurrentQuery = `{
+  currentBulkOperation { id status errorCode objectCount url }
+}`
+
+// weightUnits maps the GraphQL weight units to the ones of the REST API.
+var weightUnits = map[string]string{
+	"GRAMS":     "g",
+	"KILOGRAMS": "kg",
+	"OUNCES":    "oz",
+	"POUNDS":    "lb",
+}
+
+// gramsPerUnit maps the GraphQL weight units to their weight in grams, from
+// which the REST API derives the grams of a variant.
+var gramsPerUnit = map[string]float64{
+	"GRAMS":     1,
+	"KILOGRAMS": 1000,
+	"OUNCES":    28.349523125,
+	"POUNDS":    453.59237,
+}
+
+// inventoryManagementShopify is the inventory management of variants whose
+// inventory is tracked by Shopify.
+const inventoryManagementShopify = "shopify"
+
+// newBulkClient returns a bulkClient for the store of the given configuration.
+// The secrets of c are expected to be resolved.
+func newBulkClient(c *Configuration, httpClient *http.Client, transport http.RoundTripper) *bulkClient {
+	version := c.APIVersion
+	if version == "" {
+		version = bulkAPIVersion
+	}
+	// Private apps authenticate GraphQL requests with their password.
+	token := c.AccessToken
+	if token == "" {
+		token = c.Password
+	}
+	return &bulkClient{
+		httpClient: httpClient,
+		// The result is downloaded from a signed URL outside of the store, which
+		// isn't subject to the store's rate limits and may take longer than a
+		// regular request.
+		downloadClient: &http.Client{Transport: transport},
+		endpoint:       fmt.Sprintf("https://%s.myshopify.com/admin/api/%s/graphql.json", c.Name, version),
+		token:          token,
+		pollInterval:   bulkPollInterval,
+	}
+}
+
+// bulkClient fetches products through bulk operations of the GraphQL Admin
+// API. A bulk operation exports products, variants and metafields in a single
+// file, instead of one request per product and variant for the metafields.
+// https://shopify.dev/docs/api/usage/bulk-operations/queries
+type bulkClient struct {
+	httpClient     *http.Client
+	downloadClient *http.Client
+	endpoint       string
+	token          string
+	pollInterval   time.Duration
+}
+
+// bulkOperation is the state of a bulk operation.
+type bulkOperation struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"`
+	ErrorCode   string `json:"errorCode"`
+	ObjectCount string `json:"objectCount"`
+	URL         string `json:"url"`
+}
+
+// getProducts exports the products that match the search query together with
+// their metafields. All products are returned if search is empty.
+// https://shopify.dev/docs/api/usage/search-syntax
+func (c *bulkClient) getProducts(ctx context.Context, search string) ([]Product, error) {
+	args := ""
+	if search != "" {
+		args = fmt.Sprintf("(query: %s)", strconv.Quote(search))
+	}
+	op, err := c.run(ctx, fmt.Sprintf(bulkProductsQuery, args))
+	if err != nil {
+		return nil, err
+	}
+	op, err = c.wait(ctx, op.ID)
+	if err != nil {
+		return nil, err
+	}
+	// The URL is empty if the query did not match any objects.
+	if op.URL == "" {
+		return []Product{}, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, op.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.downloadClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download bulk operation result: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download bulk operation result: %v", resp.Status)
+	}
+	return decodeBulkProducts(resp.Body)
+}
+
+// run starts a bulk operation for the given query.
+func (c *bulkClient) run(ctx context.Context, query string) (*bulkOperation, error) {
+	data := struct {
+		BulkOperationRunQuery struct {
+			BulkOperation *bulkOperation `json:"bulkOperation"`
+			UserErrors    []struct {
+				Field   []string `json:"field"`
+				Message string   `json:"message"`
+			} `json:"userErrors"`
+		} `json:"bulkOperationRunQuery"`
+	}{}
+	vars := map[string]any{"query": query}
+	if err := c.do(ctx, bulkRunMutation, vars, &data); err != nil {
+		return nil, fmt.Errorf("failed to start bulk operation: %w", err)
+	}
+	result := data.BulkOperationRunQuery
+	if len(result.UserErrors) > 0 {
+		errs := []error{}
+		for _, e := range result.UserErrors {
+			errs = append(errs, errors.New(e.Message))
+		}
+		return nil, fmt.Errorf("failed to start bulk operation: %w", errors.Join(errs...))
+	}
+	if result.BulkOperation == nil {
+		return nil, errors.New("failed to start bulk operation: no operation returned")
+	}
+	return result.BulkOperation, nil
+}
+
+// wait polls the current bulk operation until the operation with the given ID
+// is finished.
+func (c *bulkClient) wait(ctx context.Context, id string) (*bulkOperation, error) {
+	for {
+		data := struct {
+			CurrentBulkOperation *bulkOperation `json:"currentBulkOperation"`
+		}{}
+		if err := c.do(ctx, bulkCurrentQuery, nil, &data); err != nil {
+			return nil, fmt.Errorf("failed to get bulk operation status: %w", err)
+		}
+		op := data.CurrentBulkOperation
+		if op == nil || op.ID != id {
+			return nil, fmt.Errorf("bulk operation %v is no longer the current one", id)
+		}
+		switch op.Status {
+		case bulkStatusCompleted:
+			return op, nil
+		case bulkStatusCanceled, bulkStatusExpired, bulkStatusFailed:
+			return nil, fmt.Errorf("bulk operation %v: status %v, error code %q", id, op.Status, op.ErrorCode)
+		}
+		select {
+		case <-time.After(c.pollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// do sends a GraphQL request and decodes the data of the response into v.
+func (c *bulkClient) do(ctx context.Context, query string, vars map[string]any, v any) error {
+	body, err := json.Marshal(map[string]any{"query": query, "variables": vars})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Shopify-Access-Token", c.token)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response status %v", resp.Status)
+	}
+	result := struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		errs := []error{}
+		for _, e := range result.Errors {
+			errs = append(errs, errors.New(e.Message))
+		}
+		return errors.Join(errs...)
+	}
+	return json.Unmarshal(result.Data, v)
+}
+
+// bulkObject is a line of the JSONL result of a bulk operation. Connections
+// are flattened, so that every product, image, variant and metafield is on its
+// own line and refers to its parent through the __parentId field. Parents
+// always appear before their children.
+type bulkObject struct {
+	ID             string   `json:"id"`
+	ParentID       string   `json:"__parentId"`
+	Title          string   `json:"title"`
+	BodyHTML       string   `json:"descriptionHtml"`
+	Vendor         string   `json:"vendor"`
+	ProductType    string   `json:"productType"`
+	Handle         string   `json:"handle"`
+	Tags           []string `json:"tags"`
+	Status         string   `json:"status"`
+	TemplateSuffix string   `json:"templateSuffix"`
+	SEO            *struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	} `json:"seo"`
+	CreatedAt       *time.Time       `json:"createdAt"`
+	UpdatedAt       *time.Time       `json:"updatedAt"`
+	PublishedAt     *time.Time       `json:"publishedAt"`
+	Options         []bulkOption     `json:"options"`
+	Sku             string           `json:"sku"`
+	Barcode         string           `json:"barcode"`
+	Position        int              `json:"position"`
+	Price           *decimal.Decimal `json:"price"`
+	CompareAtPrice  *decimal.Decimal `json:"compareAtPrice"`
+	InventoryPolicy string           `json:"inventoryPolicy"`
+	InventoryQty    int              `json:"inventoryQuantity"`
+	Taxable         bool             `json:"taxable"`
+	SelectedOptions []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"selectedOptions"`
+	Image *struct {
+		ID string `json:"id"`
+	} `json:"image"`
+	URL           string `json:"url"`
+	Width         int    `json:"width"`
+	Height        int    `json:"height"`
+	InventoryItem *struct {
+		ID               string `json:"id"`
+		Tracked          bool   `json:"tracked"`
+		RequiresShipping bool   `json:"requiresShipping"`
+		Measurement      *struct {
+			Weight *struct {
+				Value float64 `json:"value"`
+				Unit  string  `json:"unit"`
+			} `json:"weight"`
+		} `json:"measurement"`
+	} `json:"inventoryItem"`
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	Type      string `json:"type"`
+}
+
+type bulkOption struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Position int      `json:"position"`
+	Values   []string `json:"values"`
+}
+
+// decodeBulkProducts decodes the JSONL result of a bulk operation of
+// bulkProductsQuery.
+func decodeBulkProducts(r io.Reader) ([]Product, error) {
+	products := []Product{}
+	// productIndex maps the global IDs of products to their index in products.
+	productIndex := map[string]int{}
+	// variantIndex maps the global IDs of variants to their product and variant
+	// indexes.
+	variantIndex := map[string][2]int{}
+
+	scanner := bufio.NewScanner(r)
+	// Lines can be long because of product descriptions and JSON metafields.
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		obj := bulkObject{}
+		if err := json.Unmarshal(scanner.Bytes(), &obj); err != nil {
+			return nil, fmt.Errorf("line %v: %w", line, err)
+		}
+		id, err := legacyID(obj.ID)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %w", line, err)
+		}
+		switch resourceType(obj.ID) {
+		case "Product":
+			productIndex[obj.ID] = len(products)
+			products = append(products, bulkProduct(id, &obj))
+		case "ProductImage":
+			i, ok := productIndex[obj.ParentID]
+			if !ok {
+				return nil, fmt.Errorf("line %v: unknown parent %q", line, obj.ParentID)
+			}
+			p := &products[i]
+			p.Images = append(p.Images, bulkImage(id, p, &obj))
+		case "ProductVariant":
+			i, ok := productIndex[obj.ParentID]
+			if !ok {
+				return nil, fmt.Errorf("line %v: unknown parent %q", line, obj.ParentID)
+			}
+			p := &products[i]
+			variantIndex[obj.ID] = [2]int{i, len(p.Variants)}
+			p.Variants = append(p.Variants, bulkVariant(id, p, &obj))
+		case "Metafield":
+			if i, ok := productIndex[obj.ParentID]; ok {
+				p := &products[i]
+				p.Metafields = append(p.Metafields, bulkMetafield(id, memdb.MetafieldOwnerProduct, p.ID, &obj))
+				continue
+			}
+			if i, ok := variantIndex[obj.ParentID]; ok {
+				v := &products[i[0]].Variants[i[1]]
+				v.Metafields = append(v.Metafields, bulkMetafield(id, memdb.MetafieldOwnerVariant, v.ID, &obj))
+				continue
+			}
+			return nil, fmt.Errorf("line %v: unknown parent %q", line, obj.ParentID)
+		default:
+			return nil, fmt.Errorf("line %v: unexpected object %q", line, obj.ID)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for i := range products {
+		setImageVariants(&products[i])
+	}
+	return products, nil
+}
+
+// setImageVariants sets the variants of the images of p from the images of
+// its variants, and the main image of p to its first image, as the REST API
+// does.
+func setImageVariants(p *Product) {
+	for i := range p.Images {
+		img := &p.Images[i]
+		for _, v := range p.Variants {
+			if v.ImageID == img.ID {
+				img.VariantIds = append(img.VariantIds, v.ID)
+			}
+		}
+	}
+	if len(p.Images) > 0 {
+		p.Image = p.Images[0]
+	}
+}
+
+func bulkProduct(id int64, obj *bulkObject) Product {
+	p := Product{
+		ID:                id,
+		Title:             obj.Title,
+		BodyHTML:          obj.BodyHTML,
+		Vendor:            obj.Vendor,
+		ProductType:       obj.ProductType,
+		Handle:            obj.Handle,
+		Tags:              strings.Join(obj.Tags, ", "),
+		Status:            strings.ToLower(obj.Status),
+		TemplateSuffix:    obj.TemplateSuffix,
+		CreatedAt:         obj.CreatedAt,
+		UpdatedAt:         obj.UpdatedAt,
+		PublishedAt:       obj.PublishedAt,
+		AdminGraphqlAPIID: obj.ID,
+	}
+	if obj.SEO != nil {
+		p.MetafieldsGlobalTitleTag = obj.SEO.Title
+		p.MetafieldsGlobalDescriptionTag = obj.SEO.Description
+	}
+	for _, o := range obj.Options {
+		optionID, _ := legacyID(o.ID)
+		p.Options = append(p.Options, ProductOption{
+			ID:        optionID,
+			ProductID: id,
+			Name:      o.Name,
+			Position:  o.Position,
+			Values:    o.Values,
+		})
+	}
+	return p
+}
+
+func bulkImage(id int64, p *Product, obj *bulkObject) Image {
+	return Image{
+		ID:        id,
+		ProductID: p.ID,
+		// Images are exported in the order of their positions.
+		Position: len(p.Images) + 1,
+		Src:      obj.URL,
+		Width:    obj.Width,
+		Height:   obj.Height,
+	}
+}
+
+func bulkVariant(id int64, p *Product, obj *bulkObject) Variant {
+	v := Variant{
+		ID:                id,
+		ProductID:         p.ID,
+		Title:             obj.Title,
+		Sku:               obj.Sku,
+		Barcode:           obj.Barcode,
+		Position:          obj.Position,
+		Price:             obj.Price,
+		CompareAtPrice:    obj.CompareAtPrice,
+		InventoryPolicy:   strings.ToLower(obj.InventoryPolicy),
+		InventoryQuantity: obj.InventoryQty,
+		Taxable:           obj.Taxable,
+		CreatedAt:         obj.CreatedAt,
+		UpdatedAt:         obj.UpdatedAt,
+		AdminGraphqlAPIID: obj.ID,
+	}
+	if obj.Image != nil {
+		v.ImageID, _ = legacyID(obj.Image.ID)
+	}
+	if item := obj.InventoryItem; item != nil {
+		v.InventoryItemId, _ = legacyID(item.ID)
+		v.RequireShipping = item.RequiresShipping
+		if item.Tracked {
+			v.InventoryManagement = inventoryManagementShopify
+		}
+		if item.Measurement != nil && item.Measurement.Weight != nil {
+			weight := item.Measurement.Weight
+			w := decimal.NewFromFloat(weight.Value)
+			v.Weight = &w
+			v.WeightUnit = weightUnits[weight.Unit]
+			v.Grams = int(math.Round(weight.Value * gramsPerUnit[weight.Unit]))
+		}
+	}
+	// The REST API refers to option values by the position of the option.
+	for _, so := range obj.SelectedOptions {
+		for _, o := range p.Options {
+			if o.Name != so.Name {
+				continue
+			}
+			switch o.Position {
+			case 1:
+				v.Option1 = so.Value
+			case 2:
+				v.Option2 = so.Value
+			case 3:
+				v.Option3 = so.Value
+			}
+		}
+	}
+	return v
+}
+
+func bulkMetafield(id int64, ownerResource string, ownerID int64, obj *bulkObject) Metafield {
+	return Metafield{
+		ID:                id,
+		Namespace:         obj.Namespace,
+		Key:               obj.Key,
+		Value:             obj.Value,
+		Type:              obj.Type,
+		OwnerId:           ownerID,
+		OwnerResource:     ownerResource,
+		CreatedAt:         obj.CreatedAt,
+		UpdatedAt:         obj.UpdatedAt,
+		AdminGraphqlAPIID: obj.ID,
+	}
+}
+
+// legacyID returns the REST API ID of the resource with the given global ID,
+// e.g. 123 for "gid://shopify/Product/123".
+func legacyID(gid string) (int64, error) {
+	i := strings.LastIndex(gid, "/")
+	id, err := strconv.ParseInt(gid[i+1:], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid global ID %q", gid)
+	}
+	return id, nil
+}
+
+// resourceType returns the resource type of the given global ID, e.g.
+// "Product" for "gid://shopify/Product/123".
+func resourceType(gid string) string {
+	s := strings.TrimPrefix(gid, "gid://shopify/")
+	t, _, _ := strings.Cut(s, "/")
+	return t
+}
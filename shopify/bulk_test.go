@@ -0,0 +1,165 @@
+package shopify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const bulkResult = `{"id":"gid://shopify/Product/1","title":"Shirt","descriptionHtml":"<p>Soft</p>","handle":"shirt","tags":["a","b"],"status":"ACTIVE","options":[{"id":"gid://shopify/ProductOption/10","name":"Size","position":1,"values":["S","M"]}]}
+{"id":"gid://shopify/ProductImage/20","url":"https://cdn.shopify.com/front.jpg","width":800,"height":600,"__parentId":"gid://shopify/Product/1"}
+{"id":"gid://shopify/ProductImage/21","url":"https://cdn.shopify.com/back.jpg","__parentId":"gid://shopify/Product/1"}
+{"id":"gid://shopify/Metafield/100","namespace":"custom","key":"fabric","value":"cotton","type":"single_line_text_field","__parentId":"gid://shopify/Product/1"}
+{"id":"gid://shopify/ProductVariant/11","title":"S","sku":"SHIRT-S","price":"10.50","inventoryPolicy":"DENY","selectedOptions":[{"name":"Size","value":"S"}],"inventoryItem":{"id":"gid://shopify/InventoryItem/111","tracked":true,"requiresShipping":true,"measurement":{"weight":{"value":0.2,"unit":"KILOGRAMS"}}},"__parentId":"gid://shopify/Product/1"}
+{"id":"gid://shopify/Metafield/101","namespace":"custom","key":"fit","value":"slim","type":"single_line_text_field","__parentId":"gid://shopify/ProductVariant/11"}
+{"id":"gid://shopify/ProductVariant/12","title":"M","sku":"SHIRT-M","selectedOptions":[{"name":"Size","value":"M"}],"image":{"id":"gid://shopify/ProductImage/21"},"__parentId":"gid://shopify/Product/1"}
+{"id":"gid://shopify/Product/2","title":"Hat"}
+`
+
+func Test_decodeBulkProducts(t *testing.T) {
+	t.Run("nests variants and metafields", func(t *testing.T) {
+		products, err := decodeBulkProducts(strings.NewReader(bulkResult))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(products) != 2 {
+			t.Fatalf("got %v products, want 2", len(products))
+		}
+		p := products[0]
+		if p.ID != 1 || p.BodyHTML != "<p>Soft</p>" || p.Tags != "a, b" || p.Status != "active" {
+			t.Errorf("got product %+v", p)
+		}
+		if len(p.Options) != 1 || p.Options[0].ID != 10 || p.Options[0].ProductID != 1 {
+			t.Errorf("got options %+v", p.Options)
+		}
+		if len(p.Images) != 2 || p.Images[0].Src != "https://cdn.shopify.com/front.jpg" || p.Images[0].Width != 800 || p.Images[1].Position != 2 {
+			t.Errorf("got images %+v", p.Images)
+		}
+		if p.Image.ID != 20 || len(p.Images[1].VariantIds) != 1 || p.Images[1].VariantIds[0] != 12 {
+			t.Errorf("got main image %+v and image variants %v", p.Image, p.Images[1].VariantIds)
+		}
+		if len(p.Metafields) != 1 || p.Metafields[0].OwnerResource != "product" || p.Metafields[0].OwnerId != 1 {
+			t.Errorf("got product metafields %+v", p.Metafields)
+		}
+		if len(p.Variants) != 2 {
+			t.Fatalf("got %v variants, want 2", len(p.Variants))
+		}
+		v := p.Variants[0]
+		if v.ID != 11 || v.ProductID != 1 || v.Option1 != "S" || v.InventoryItemId != 111 || v.InventoryPolicy != "deny" {
+			t.Errorf("got variant %+v", v)
+		}
+		if v.Price.String() != "10.5" || v.Weight.String() != "0.2" || v.WeightUnit != "kg" || v.Grams != 200 {
+			t.Errorf("got price %v, weight %v %v, grams %v", v.Price, v.Weight, v.WeightUnit, v.Grams)
+		}
+		if !v.RequireShipping || v.InventoryManagement != "shopify" {
+			t.Errorf("got requires shipping %v, inventory management %q", v.RequireShipping, v.InventoryManagement)
+		}
+		if len(v.Metafields) != 1 || v.Metafields[0].OwnerResource != "variant" || v.Metafields[0].OwnerId != 11 {
+			t.Errorf("got variant metafields %+v", v.Metafields)
+		}
+		if p.Variants[1].Option1 != "M" || p.Variants[1].Price != nil || p.Variants[1].ImageID != 21 || p.Variants[1].InventoryManagement != "" {
+			t.Errorf("got variant %+v", p.Variants[1])
+		}
+	})
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "should fail on orphan variant",
+			input: `{"id":"gid://shopify/ProductVariant/11","__parentId":"gid://shopify/Product/1"}`,
+		},
+		{
+			name:  "should fail on invalid ID",
+			input: `{"id":"gid://shopify/Product/abc"}`,
+		},
+		{
+			name:  "should fail on unexpected object",
+			input: `{"id":"gid://shopify/Collection/1"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeBulkProducts(strings.NewReader(tt.input)); err == nil {
+				t.Fatal("got nil error")
+			}
+		})
+	}
+}
+
+func Test_bulkClient_getProducts(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		wantErr  bool
+	}{
+		{
+			name:     "should poll until completed",
+			statuses: []string{"CREATED", "RUNNING", "COMPLETED"},
+		},
+		{
+			name:     "should fail if operation failed",
+			statuses: []string{"RUNNING", "FAILED"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			polls := 0
+			var search string
+			mux.HandleFunc("/graphql.json", func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("X-Shopify-Access-Token"); got != "token" {
+					t.Errorf("got access token %q", got)
+				}
+				req := struct {
+					Query     string         `json:"query"`
+					Variables map[string]any `json:"variables"`
+				}{}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Error(err)
+					return
+				}
+				if strings.HasPrefix(req.Query, "mutation") {
+					search, _ = req.Variables["query"].(string)
+					fmt.Fprint(w, `{"data":{"bulkOperationRunQuery":{"bulkOperation":{"id":"gid://shopify/BulkOperation/1","status":"CREATED"},"userErrors":[]}}}`)
+					return
+				}
+				status := tt.statuses[polls]
+				polls++
+				fmt.Fprintf(w, `{"data":{"currentBulkOperation":{"id":"gid://shopify/BulkOperation/1","status":%q,"url":%q}}}`, status, server.URL+"/result.jsonl")
+			})
+			mux.HandleFunc("/result.jsonl", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, bulkResult)
+			})
+
+			c := &bulkClient{
+				httpClient:     server.Client(),
+				downloadClient: server.Client(),
+				endpoint:       server.URL + "/graphql.json",
+				token:          "token",
+			}
+			products, err := c.getProducts(context.Background(), "updated_at:>='2023-01-01T00:00:00Z'")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if polls != len(tt.statuses) {
+				t.Errorf("got %v polls, want %v", polls, len(tt.statuses))
+			}
+			if !strings.Contains(search, `products(query: "updated_at:>='2023-01-01T00:00:00Z'")`) {
+				t.Errorf("got query %q", search)
+			}
+			if !tt.wantErr && len(products) != 2 {
+				t.Errorf("got %v products, want 2", len(products))
+			}
+		})
+	}
+}
@@ -2,55 +2,328 @@
 package shopify
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/samherrmann/merchant/memdb"
 )
 
+const (
+	// DefaultConcurrency is the default number of concurrent requests.
+	DefaultConcurrency = 4
+	// httpTimeout is the timeout of a single request.
+	httpTimeout = 30 * time.Second
+)
+
+// RequiredScopes are the access scopes that the app in the store needs.
+var RequiredScopes = []string{"read_products", "write_products", "write_inventory", "read_locations"}
+
 var (
 	ErrNotExist = errors.New("does not exist")
+	ErrDrift    = errors.New("store has changed since the operations were planned")
 )
 
 type Client struct {
 	*goshopify.Client
+	// pool contains one set of services per concurrent worker.
+	pool     []services
+	reporter Reporter
+	// transport sends the HTTP requests to the store.
+	transport http.RoundTripper
+	// ctx aborts all requests once it is done.
+	ctx context.Context
+	// bulk fetches products through bulk operations. It is nil if products are
+	// fetched through the REST API.
+	bulk *bulkClient
+	// graphql sends GraphQL requests for resources that the REST API doesn't
+	// provide.
+	graphql *bulkClient
+	// variants looks up variants without downloading the store. It is nil if
+	// no cache is used.
+	variants VariantCache
+}
+
+// VariantCache looks up variants by SKU and barcode, typically in a local copy
+// of the store. It returns an error if the variant is not known.
+type VariantCache interface {
+	GetVariantBySKU(sku string) (*goshopify.Variant, error)
+	GetVariantByBarcode(barcode string) (*goshopify.Variant, error)
+}
+
+// Option configures a Client.
+type Option func(c *Client)
+
+// WithReporter sets the Reporter that receives the progress of long running
+// operations.
+func WithReporter(r Reporter) Option {
+	return func(c *Client) {
+		c.reporter = r
+	}
+}
+
+// WithTransport sets the http.RoundTripper through which requests are sent to
+// the store. It allows requests to be redirected to a fake store in tests, see
+// package shopifytest. http.DefaultTransport is used by default.
+func WithTransport(t http.RoundTripper) Option {
+	return func(c *Client) {
+		c.transport = t
+	}
+}
+
+// WithVariantCache sets the VariantCache that is consulted before the whole
+// store is searched for a variant.
+func WithVariantCache(vc VariantCache) Option {
+	return func(c *Client) {
+		c.variants = vc
+	}
 }
 
-func NewClient(c *Configuration) *Client {
-	return &Client{
-		Client: goshopify.NewClient(
+// WithContext sets a context that aborts all requests to the store once it is
+// done, which also aborts long running operations such as fetching all
+// products. goshopify doesn't accept a context per request, so this is the
+// only way to abort the requests that it sends. context.Background() is used
+// by default.
+func WithContext(ctx context.Context) Option {
+	return func(c *Client) {
+		c.ctx = ctx
+	}
+}
+
+func NewClient(c *Configuration, opts ...Option) *Client {
+	concurrency := c.Concurrency
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+	client := &Client{
+		reporter:  nopReporter{},
+		transport: http.DefaultTransport,
+		ctx:       context.Background(),
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	// All underlying clients share the same rate limiter because they share the
+	// same leaky bucket in the store.
+	httpClient := &http.Client{
+		Timeout:   httpTimeout,
+		Transport: &contextTransport{ctx: client.ctx, next: newRateLimiter(client.transport)},
+	}
+	clientOpts := []goshopify.Option{
+		goshopify.WithRetry(3),
+		goshopify.WithHTTPClient(httpClient),
+	}
+	// goshopify falls back to the oldest supported version only if no version
+	// option is given.
+	if c.APIVersion != "" {
+		clientOpts = append(clientOpts, goshopify.WithVersion(c.APIVersion))
+	}
+	newClient := func() *goshopify.Client {
+		return goshopify.NewClient(
 			goshopify.App{
 				ApiKey:   c.APIKey,
 				Password: c.Password,
 			},
 			c.Name,
-			"",
-			goshopify.WithRetry(3),
-		),
+			// The access token takes precedence over the API key and password if
+			// set.
+			c.AccessToken,
+			clientOpts...,
+		)
+	}
+	client.Client = newClient()
+	// A goshopify.Client is not safe for concurrent use, so every worker gets
+	// its own.
+	client.pool = []services{newServices(client.Client)}
+	for i := 1; i < concurrency; i++ {
+		client.pool = append(client.pool, newServices(newClient()))
+	}
+	client.graphql = newBulkClient(c, httpClient, client.transport)
+	if c.FetchBackend == FetchBackendGraphQL {
+		client.bulk = client.graphql
+	}
+	return client
+}
+
+// contextTransport aborts requests once ctx is done, in addition to their own
+// context.
+type contextTransport struct {
+	ctx  context.Context
+	next http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-t.ctx.Done():
+			cancel()
+		case <-stop:
+		}
+	}()
+	release := func() {
+		close(stop)
+		cancel()
+	}
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		release()
+		return nil, err
+	}
+	// The body is read after RoundTrip returns, so the request is only released
+	// once the body is closed.
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releaseBody calls release once the body is closed.
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// CheckAccess verifies the credentials by requesting the shop details. It
+// returns the shop together with the scopes of RequiredScopes that have not
+// been granted.
+func (c *Client) CheckAccess() (*goshopify.Shop, []string, error) {
+	shop, err := c.Shop.Get(nil)
+	if err != nil {
+		return nil, nil, err
 	}
+	scopes, err := c.AccessScopes.List(nil)
+	if err != nil {
+		return shop, nil, fmt.Errorf("failed to get access scopes: %w", err)
+	}
+	return shop, missingScopes(scopes), nil
 }
 
-func (c *Client) GetVariantBySKU(sku string) (*goshopify.Variant, error) {
+// GetVariantBySKU returns the variant with the given SKU. If a VariantCache
+// is set, the variant that it returns is fetched from the store and returned
+// if its SKU still matches. Otherwise all products of the store are searched.
+func (c *Client) GetVariantBySKU(ctx context.Context, sku string) (*goshopify.Variant, error) {
+	match := func(v *goshopify.Variant) bool {
+		return v.Sku == sku
+	}
+	if v := c.getCachedVariant(func(vc VariantCache) (*goshopify.Variant, error) {
+		return vc.GetVariantBySKU(sku)
+	}, match); v != nil {
+		return v, nil
+	}
 	return searchVariant(
-		c.Product,
-		c.Variant,
-		func(v *goshopify.Variant) bool {
-			return v.Sku == sku
-		},
+		ctx,
+		c.pool,
+		c.reporter,
+		match,
 	)
 }
 
-func (c *Client) GetVariantByBarcode(barcode string) (*goshopify.Variant, error) {
+// GetVariantByBarcode returns the variant with the given barcode. See
+// GetVariantBySKU for how a VariantCache is used.
+func (c *Client) GetVariantByBarcode(ctx context.Context, barcode string) (*goshopify.Variant, error) {
+	match := func(v *goshopify.Variant) bool {
+		return v.Barcode == barcode
+	}
+	if v := c.getCachedVariant(func(vc VariantCache) (*goshopify.Variant, error) {
+		return vc.GetVariantByBarcode(barcode)
+	}, match); v != nil {
+		return v, nil
+	}
 	return searchVariant(
-		c.Product,
-		c.Variant,
-		func(v *goshopify.Variant) bool {
-			return v.Barcode == barcode
-		},
+		ctx,
+		c.pool,
+		c.reporter,
+		match,
 	)
 }
 
-func (c *Client) GetProducts() ([]goshopify.Product, error) {
-	return getProducts(c.Product, c.Variant)
+// getCachedVariant looks up a variant in the VariantCache and fetches it from
+// the store. It returns nil if no cache is set, the cache doesn't know the
+// variant, or the variant in the store no longer matches.
+func (c *Client) getCachedVariant(
+	lookup func(vc VariantCache) (*goshopify.Variant, error),
+	match func(v *goshopify.Variant) bool,
+) *goshopify.Variant {
+	if c.variants == nil {
+		return nil
+	}
+	cached, err := lookup(c.variants)
+	if err != nil {
+		return nil
+	}
+	v, err := c.Variant.Get(cached.ID, nil)
+	if err != nil || !match(v) {
+		return nil
+	}
+	return v
+}
+
+// GetProducts returns all products of the store together with their
+// metafields.
+func (c *Client) GetProducts(ctx context.Context) ([]goshopify.Product, error) {
+	if c.bulk != nil {
+		return c.getBulkProducts(ctx, "")
+	}
+	return getProducts(ctx, c.pool, c.reporter, nil)
+}
+
+// GetProductsUpdatedSince returns the products of the store that have been
+// created or updated at or after t, together with their metafields.
+func (c *Client) GetProductsUpdatedSince(ctx context.Context, t time.Time) ([]goshopify.Product, error) {
+	if c.bulk != nil {
+		return c.getBulkProducts(ctx, fmt.Sprintf("updated_at:>='%s'", t.UTC().Format(time.RFC3339)))
+	}
+	return getProducts(ctx, c.pool, c.reporter, &ListOptions{UpdatedAtMin: t})
+}
+
+// getBulkProducts fetches the products that match the search query through a
+// bulk operation. Its progress is only reported once it is complete. The
+// published scopes of the products are fetched through the REST API because
+// GraphQL doesn't have them, so that the products equal the ones of the REST
+// API.
+func (c *Client) getBulkProducts(ctx context.Context, search string) ([]goshopify.Product, error) {
+	products, err := c.bulk.getProducts(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+	scopes, err := listProducts(c.Product, &ListOptions{Fields: "id,published_scope"})
+	if err != nil {
+		return nil, err
+	}
+	scopeByID := make(map[int64]string, len(scopes))
+	for _, p := range scopes {
+		scopeByID[p.ID] = p.PublishedScope
+	}
+	for i := range products {
+		products[i].PublishedScope = scopeByID[products[i].ID]
+	}
+	c.reporter.Progress(len(products), len(products))
+	return products, nil
+}
+
+// GetProductsByID returns the products with the given IDs together with their
+// metafields. IDs that don't exist in the store are ignored.
+func (c *Client) GetProductsByID(ctx context.Context, ids []int64) ([]goshopify.Product, error) {
+	return getProductsByID(ctx, c.pool, c.reporter, ids)
+}
+
+// GetProductIDs returns the IDs of all products in the store.
+func (c *Client) GetProductIDs() ([]int64, error) {
+	return getProductIDs(c.Product)
 }
 
 // GetVariantCount returns the total number of variants for all products.
@@ -58,7 +331,63 @@ func (c *Client) GetVariantCount() (int, error) {
 	return getVariantCount(c.Product)
 }
 
-// UpdateProducts updates the given products in the store.
-func (c *Client) UpdateProducts(products []goshopify.Product) error {
-	return updateProducts(c.Product, c.Variant, products)
+// UpdateProducts updates the given products in the store and deletes the
+// variants contained in deletions.
+func (c *Client) UpdateProducts(ctx context.Context, products []goshopify.Product, deletions []goshopify.Product) error {
+	return updateProducts(ctx, c.pool, c.reporter, products, deletions)
+}
+
+// ApplyOperations applies the given operations to the store as they are,
+// without comparing them against the current state of the store. Operations
+// that the journal reports as completed are skipped, and every applied
+// operation is recorded in the journal. The journal may be nil.
+func (c *Client) ApplyOperations(ctx context.Context, operations *memdb.Operations, journal Journal) error {
+	if journal == nil {
+		journal = nopJournal{}
+	}
+	return applyOperations(ctx, c.pool, operations, journal)
+}
+
+// IsStale reports whether products have been created, updated or deleted in
+// the store since the given products were fetched from it.
+func (c *Client) IsStale(products []goshopify.Product) (bool, error) {
+	return isStale(c.Product, products)
+}
+
+// CheckBaseline returns an error wrapping ErrDrift if any of the products in
+// the baseline of the given operations has changed in the store since the
+// operations were planned.
+func (c *Client) CheckBaseline(operations *memdb.Operations) error {
+	return checkBaseline(c.Product, operations.Baseline)
+}
+
+// UpdatedAt returns the time at which each of the products with the given IDs
+// was last updated. Products that don't exist are omitted.
+func (c *Client) UpdatedAt(ids []int64) (map[int64]time.Time, error) {
+	return listUpdatedAt(c.Product, ids)
+}
+
+// CheckUpdatedAt returns an error wrapping ErrDrift if any of the products in
+// updatedAt has been updated since the given time or no longer exists.
+func (c *Client) CheckUpdatedAt(updatedAt map[int64]time.Time) error {
+	return checkBaseline(c.Product, updatedAt)
+}
+
+// missingScopes returns the scopes of RequiredScopes that are not in granted.
+// A write scope implies the corresponding read scope.
+func missingScopes(granted []goshopify.AccessScope) []string {
+	set := map[string]bool{}
+	for _, s := range granted {
+		set[s.Handle] = true
+		if strings.HasPrefix(s.Handle, "write_") {
+			set["read_"+strings.TrimPrefix(s.Handle, "write_")] = true
+		}
+	}
+	missing := []string{}
+	for _, s := range RequiredScopes {
+		if !set[s] {
+			missing = append(missing, s)
+		}
+	}
+	return missing
 }
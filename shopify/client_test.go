@@ -0,0 +1,120 @@
+package shopify
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/samherrmann/merchant/shopify/shopifytest"
+)
+
+func Test_missingScopes(t *testing.T) {
+	tests := []struct {
+		name    string
+		granted []string
+		want    []string
+	}{
+		{name: "should return all scopes if none are granted", granted: nil, want: RequiredScopes},
+		{name: "should imply read scope from write scope", granted: []string{"write_products", "write_inventory", "read_locations"}, want: []string{}},
+		{name: "should return missing write scope", granted: []string{"read_products", "write_inventory", "read_locations"}, want: []string{"write_products"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			granted := []goshopify.AccessScope{}
+			for _, h := range tt.granted {
+				granted = append(granted, goshopify.AccessScope{Handle: h})
+			}
+			if got := missingScopes(granted); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// variantCache is a VariantCache that knows the variants of a fixed set of
+// SKUs.
+type variantCache map[string]*goshopify.Variant
+
+func (vc variantCache) GetVariantBySKU(sku string) (*goshopify.Variant, error) {
+	if v, ok := vc[sku]; ok {
+		return v, nil
+	}
+	return nil, ErrNotExist
+}
+
+func (vc variantCache) GetVariantByBarcode(barcode string) (*goshopify.Variant, error) {
+	return nil, ErrNotExist
+}
+
+func TestClient_GetVariantBySKU(t *testing.T) {
+	server := shopifytest.NewServer(shopifytest.WithAccessToken("token"), shopifytest.WithMaxLimit(2))
+	t.Cleanup(server.Close)
+	added := server.AddProducts(
+		Product{Title: "Shirt", Variants: []Variant{{Sku: "SHIRT"}}},
+		Product{Title: "Hat", Variants: []Variant{{Sku: "HAT"}}},
+		Product{Title: "Socks", Variants: []Variant{{Sku: "SOCKS"}}},
+	)
+	// The cache is stale: it has the hat under the SKU of the socks.
+	cache := variantCache{
+		"SHIRT": &added[0].Variants[0],
+		"SOCKS": &added[1].Variants[0],
+	}
+	client := NewClient(
+		&Configuration{Name: "foo", AccessToken: "token"},
+		WithTransport(server.Transport()),
+		WithVariantCache(cache),
+	)
+
+	tests := []struct {
+		name   string
+		sku    string
+		want   int64
+		search bool
+	}{
+		{name: "should fetch cached variant", sku: "SHIRT", want: added[0].Variants[0].ID, search: false},
+		{name: "should search store if cached variant is stale", sku: "SOCKS", want: added[2].Variants[0].ID, search: true},
+		{name: "should search store if variant is not cached", sku: "HAT", want: added[1].Variants[0].ID, search: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := server.Requests()
+			v, err := client.GetVariantBySKU(context.Background(), tt.sku)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.ID != tt.want {
+				t.Errorf("got variant %v, want %v", v.ID, tt.want)
+			}
+			// Fetching a single variant takes one request. Searching the store
+			// takes at least one per page of products.
+			if searched := server.Requests()-before > 1; searched != tt.search {
+				t.Errorf("got search %v, want %v", searched, tt.search)
+			}
+		})
+	}
+
+	if _, err := client.GetVariantBySKU(context.Background(), "SCARF"); !errors.Is(err, ErrNotExist) {
+		t.Fatalf("got %v, want %v", err, ErrNotExist)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	server := shopifytest.NewServer(shopifytest.WithAccessToken("token"))
+	t.Cleanup(server.Close)
+	server.AddProducts(Product{Title: "Shirt"})
+	ctx, cancel := context.WithCancel(context.Background())
+	client := NewClient(
+		&Configuration{Name: "foo", AccessToken: "token"},
+		WithTransport(server.Transport()),
+		WithContext(ctx),
+	)
+	if _, err := client.GetProducts(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := client.GetProducts(context.Background()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+}
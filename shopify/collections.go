@@ -0,0 +1,75 @@
+package shopify
+
+import (
+	"fmt"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+)
+
+type Collection = goshopify.Collection
+
+// GetCollections returns all custom and smart collections of the store.
+func (c *Client) GetCollections() ([]Collection, error) {
+	collections := []Collection{}
+	custom, err := listCollections[goshopify.CustomCollectionsResource](c.Client, "custom_collections.json")
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range custom {
+		for _, cc := range r.Collections {
+			collections = append(collections, Collection{
+				ID:             cc.ID,
+				Handle:         cc.Handle,
+				Title:          cc.Title,
+				UpdatedAt:      cc.UpdatedAt,
+				BodyHTML:       cc.BodyHTML,
+				SortOrder:      cc.SortOrder,
+				TemplateSuffix: cc.TemplateSuffix,
+				Image:          cc.Image,
+				PublishedAt:    cc.PublishedAt,
+				PublishedScope: cc.PublishedScope,
+			})
+		}
+	}
+	smart, err := listCollections[goshopify.SmartCollectionsResource](c.Client, "smart_collections.json")
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range smart {
+		for _, sc := range r.Collections {
+			collections = append(collections, Collection{
+				ID:             sc.ID,
+				Handle:         sc.Handle,
+				Title:          sc.Title,
+				UpdatedAt:      sc.UpdatedAt,
+				BodyHTML:       sc.BodyHTML,
+				SortOrder:      sc.SortOrder,
+				TemplateSuffix: sc.TemplateSuffix,
+				Image:          sc.Image,
+				PublishedAt:    sc.PublishedAt,
+				PublishedScope: sc.PublishedScope,
+			})
+		}
+	}
+	return collections, nil
+}
+
+// listCollections returns the pages of the collections at path, following the
+// pagination of the results.
+func listCollections[R any](client *goshopify.Client, path string) ([]R, error) {
+	pages := []R{}
+	var options any = &ListOptions{Limit: 250}
+	for {
+		resource := new(R)
+		pagination, err := client.ListWithPagination(path, resource, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get collections: %w", err)
+		}
+		pages = append(pages, *resource)
+		if pagination == nil || pagination.NextPageOptions == nil {
+			break
+		}
+		options = pagination.NextPageOptions
+	}
+	return pages, nil
+}
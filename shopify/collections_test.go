@@ -0,0 +1,26 @@
+package shopify
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClient_GetCollections(t *testing.T) {
+	client, server := newTestClient(t)
+	server.AddCustomCollections("Summer", "Winter", "Sale")
+	server.AddSmartCollections("New Arrivals")
+
+	collections, err := client.GetCollections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The custom collections span two pages.
+	got := []string{}
+	for _, c := range collections {
+		got = append(got, c.Handle)
+	}
+	want := []string{"summer", "winter", "sale", "new-arrivals"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got collections %v, want %v", got, want)
+	}
+}
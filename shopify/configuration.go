@@ -1,13 +1,83 @@
 package shopify
 
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// apiVersionRegex matches stable Admin API versions.
+var apiVersionRegex = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}$`)
+
+// unstableAPIVersion is the Admin API version of unreleased features.
+const unstableAPIVersion = "unstable"
+
+// Backends through which products are fetched from the store.
+const (
+	// FetchBackendREST fetches products through the REST Admin API, with one
+	// request per product and variant for the metafields.
+	FetchBackendREST = "rest"
+	// FetchBackendGraphQL fetches products through a bulk operation of the
+	// GraphQL Admin API.
+	FetchBackendGraphQL = "graphql"
+)
+
 type Configuration struct {
 	// Name is the name of the Shopify store as shown in
 	// <store-name>.myshopify.com.
 	Name string `json:"name"`
-	// APIKey is the API key for the Shopify store.
+	// APIKey is the API key of a private app in the Shopify store. It is used
+	// together with Password. Custom apps use AccessToken instead.
 	APIKey string `json:"apiKey"`
-	// Password is the password associated with the API key.
+	// Password is the password associated with the API key. Consider one of the
+	// alternatives described in [Configuration.ResolveSecrets] instead of
+	// storing the password in plain text.
 	Password string `json:"password"`
+	// PasswordCommand is a command that prints the password to standard output.
+	PasswordCommand []string `json:"passwordCommand,omitempty"`
+	// AccessToken is the Admin API access token of a custom app in the Shopify
+	// store. Consider one of the alternatives described in
+	// [Configuration.ResolveSecrets] instead of storing the access token in
+	// plain text.
+	AccessToken string `json:"accessToken,omitempty"`
+	// AccessTokenCommand is a command that prints the access token to standard
+	// output.
+	AccessTokenCommand []string `json:"accessTokenCommand,omitempty"`
+	// Keyring enables looking up the secrets in the keyring.
+	Keyring bool `json:"keyring,omitempty"`
+	// APIVersion is the Admin API version, e.g. "2026-07". The oldest supported
+	// stable version is used by the REST API if not set, and bulkAPIVersion by
+	// the GraphQL API, which must be at least 2024-04.
+	APIVersion string `json:"apiVersion,omitempty"`
+	// Concurrency is the maximum number of concurrent requests to the store.
+	// DefaultConcurrency is used if not set.
+	Concurrency int `json:"concurrency,omitempty"`
+	// FetchBackend is the backend through which products are fetched, either
+	// FetchBackendREST or FetchBackendGraphQL. FetchBackendREST is used if not
+	// set.
+	FetchBackend string `json:"fetchBackend,omitempty"`
+}
+
+// Validate returns an error if c is incomplete or invalid. The secrets are
+// expected to be resolved.
+func (c *Configuration) Validate() error {
+	errs := []error{}
+	if c.Name == "" {
+		errs = append(errs, errors.New("name is missing"))
+	}
+	if c.AccessToken == "" && (c.APIKey == "" || c.Password == "") {
+		errs = append(errs, errors.New("credentials are missing, either an access token or an API key and password are required"))
+	}
+	if c.AccessToken != "" && (c.APIKey != "" || c.Password != "") {
+		errs = append(errs, errors.New("an access token cannot be combined with an API key and password"))
+	}
+	if c.APIVersion != "" && c.APIVersion != unstableAPIVersion && !apiVersionRegex.MatchString(c.APIVersion) {
+		errs = append(errs, fmt.Errorf("API version %q is not of the form YYYY-MM", c.APIVersion))
+	}
+	if c.FetchBackend != "" && c.FetchBackend != FetchBackendREST && c.FetchBackend != FetchBackendGraphQL {
+		errs = append(errs, fmt.Errorf("fetch backend %q is not one of %q or %q", c.FetchBackend, FetchBackendREST, FetchBackendGraphQL))
+	}
+	return errors.Join(errs...)
 }
 
 type Configurations []Configuration
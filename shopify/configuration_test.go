@@ -0,0 +1,63 @@
+package shopify
+
+import (
+	"testing"
+)
+
+func TestConfiguration_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Configuration
+		wantErr bool
+	}{
+		{
+			name:   "should accept API key and password",
+			config: Configuration{Name: "foo", APIKey: "key", Password: "password"},
+		},
+		{
+			name:   "should accept access token with API version",
+			config: Configuration{Name: "foo", AccessToken: "token", APIVersion: "2023-04"},
+		},
+		{
+			name:   "should accept unstable API version",
+			config: Configuration{Name: "foo", AccessToken: "token", APIVersion: "unstable"},
+		},
+		{
+			name:    "should fail without name",
+			config:  Configuration{AccessToken: "token"},
+			wantErr: true,
+		},
+		{
+			name:    "should fail without credentials",
+			config:  Configuration{Name: "foo", APIKey: "key"},
+			wantErr: true,
+		},
+		{
+			name:    "should fail with access token and password",
+			config:  Configuration{Name: "foo", AccessToken: "token", APIKey: "key", Password: "password"},
+			wantErr: true,
+		},
+		{
+			name:   "should accept GraphQL fetch backend",
+			config: Configuration{Name: "foo", AccessToken: "token", FetchBackend: "graphql"},
+		},
+		{
+			name:    "should fail with unknown fetch backend",
+			config:  Configuration{Name: "foo", AccessToken: "token", FetchBackend: "soap"},
+			wantErr: true,
+		},
+		{
+			name:    "should fail with invalid API version",
+			config:  Configuration{Name: "foo", AccessToken: "token", APIVersion: "2023-4"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package shopify
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/samherrmann/merchant/memdb"
+)
+
+type Image = goshopify.Image
+
+// resourceImage is the name of the product image resource in step keys.
+const resourceImage = "image"
+
+// imageAlt is the ID and alt text of a product image as returned by the API.
+// goshopify.Image doesn't have the alt text of images.
+type imageAlt struct {
+	ID  int64  `json:"id"`
+	Alt string `json:"alt"`
+}
+
+// GetImageAlts returns the alt texts of the images of all products that have
+// one, by image ID.
+func (c *Client) GetImageAlts() (memdb.ImageAlts, error) {
+	alts := memdb.ImageAlts{}
+	var options any = &ListOptions{Limit: 250, Fields: "id,images"}
+	for {
+		resource := &struct {
+			Products []struct {
+				Images []imageAlt `json:"images"`
+			} `json:"products"`
+		}{}
+		pagination, err := c.ListWithPagination("products.json", resource, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get image alt texts: %w", err)
+		}
+		for _, p := range resource.Products {
+			for _, img := range p.Images {
+				if img.Alt != "" {
+					alts[img.ID] = img.Alt
+				}
+			}
+		}
+		if pagination == nil || pagination.NextPageOptions == nil {
+			break
+		}
+		options = pagination.NextPageOptions
+	}
+	return alts, nil
+}
+
+// createImage creates the image of c and returns its ID. Images with a local
+// source are uploaded.
+func createImage(raw RawClient, c memdb.ImageChange) (int64, error) {
+	img := imageBody(c)
+	if isURL(c.Src) {
+		img["src"] = c.Src
+	} else {
+		data, err := os.ReadFile(c.Src)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read image: %w", err)
+		}
+		img["attachment"] = base64.StdEncoding.EncodeToString(data)
+		img["filename"] = filepath.Base(c.Src)
+	}
+	resource := &struct {
+		Image imageAlt `json:"image"`
+	}{}
+	if err := raw.Post(imagesPath(c.ProductID), map[string]any{"image": img}, resource); err != nil {
+		return 0, err
+	}
+	return resource.Image.ID, nil
+}
+
+// updateImage updates the alt text, position and variants of the image of c.
+// The image is sent through a raw request because goshopify.Image doesn't have
+// the alt text.
+func updateImage(raw RawClient, c memdb.ImageChange) error {
+	img := imageBody(c)
+	img["id"] = c.ID
+	return raw.Put(imagePath(c.ProductID, c.ID), map[string]any{"image": img}, nil)
+}
+
+// deleteImage deletes the image of c.
+func deleteImage(raw RawClient, c memdb.ImageChange) error {
+	return raw.Delete(imagePath(c.ProductID, c.ID))
+}
+
+// imageBody returns the fields of c that are sent when the image is created or
+// updated.
+func imageBody(c memdb.ImageChange) map[string]any {
+	img := map[string]any{}
+	if c.Alt != nil {
+		img["alt"] = *c.Alt
+	}
+	if c.Position != 0 {
+		img["position"] = c.Position
+	}
+	if c.VariantIDs != nil {
+		img["variant_ids"] = c.VariantIDs
+	}
+	return img
+}
+
+func imagesPath(productID int64) string {
+	return fmt.Sprintf("products/%v/images.json", productID)
+}
+
+func imagePath(productID int64, id int64) string {
+	return fmt.Sprintf("products/%v/images/%v.json", productID, id)
+}
+
+func isURL(src string) bool {
+	return strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://")
+}
@@ -0,0 +1,67 @@
+package shopify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/samherrmann/merchant/memdb"
+)
+
+func TestClient_ApplyOperations_images(t *testing.T) {
+	client, server := newTestClient(t, Product{
+		Title:    "Shirt",
+		Options:  []ProductOption{{Name: "Size"}},
+		Variants: []Variant{{Option1: "S"}, {Option1: "M"}},
+		Images: []Image{
+			{Src: "https://example.com/front.jpg"},
+			{Src: "https://example.com/back.jpg"},
+		},
+	})
+	p := server.Products()[0]
+	file := filepath.Join(t.TempDir(), "side.png")
+	if err := os.WriteFile(file, []byte("png"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	alt := "Side"
+	frontAlt := "Front"
+	operations := &memdb.Operations{
+		NewImages: []memdb.ImageChange{{
+			ProductID:  p.ID,
+			Src:        file,
+			Alt:        &alt,
+			Position:   1,
+			VariantIDs: []int64{p.Variants[1].ID},
+		}},
+		ImageUpdates:  []memdb.ImageChange{{ID: p.Images[0].ID, ProductID: p.ID, Alt: &frontAlt}},
+		DeletedImages: []memdb.ImageChange{{ID: p.Images[1].ID, ProductID: p.ID}},
+	}
+	if err := client.ApplyOperations(context.Background(), operations, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got := server.Products()[0]
+	if len(got.Images) != 2 {
+		t.Fatalf("got images %+v, want 2", got.Images)
+	}
+	side := got.Images[0]
+	if !strings.Contains(side.Src, "side.png") || got.Images[1].ID != p.Images[0].ID {
+		t.Fatalf("got images %+v", got.Images)
+	}
+	if got.Variants[1].ImageID != side.ID || got.Variants[0].ImageID != 0 {
+		t.Errorf("got variant images %v and %v, want 0 and %v", got.Variants[0].ImageID, got.Variants[1].ImageID, side.ID)
+	}
+
+	alts, err := client.GetImageAlts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := memdb.ImageAlts{side.ID: "Side", p.Images[0].ID: "Front"}
+	if !reflect.DeepEqual(alts, want) {
+		t.Errorf("got alts %v, want %v", alts, want)
+	}
+}
@@ -0,0 +1,20 @@
+package shopify
+
+// Journal keeps track of the operations that have been applied to the store.
+type Journal interface {
+	// Completed returns the keys of the operations that have already been
+	// applied, mapped to the ID of the resource that they created or zero.
+	Completed() (map[string]int64, error)
+	// Record is called with the keys of operations that have been applied,
+	// mapped to the ID of the resource that they created, or zero if they
+	// didn't create a resource. Operations that complete close together are
+	// recorded in a single call.
+	Record(completed map[string]int64) error
+}
+
+// nopJournal is a Journal that doesn't keep track of anything.
+type nopJournal struct{}
+
+func (nopJournal) Completed() (map[string]int64, error) { return map[string]int64{}, nil }
+
+func (nopJournal) Record(completed map[string]int64) error { return nil }
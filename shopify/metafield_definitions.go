@@ -0,0 +1,109 @@
+package shopify
+
+import (
+	"context"
+	"fmt"
+)
+
+// metafieldDefinitionOwnerTypes maps the GraphQL owner types of the metafield
+// definitions that are fetched to the owner resources of the REST API.
+var metafieldDefinitionOwnerTypes = map[string]string{
+	"PRODUCT":        "product",
+	"PRODUCTVARIANT": "variant",
+	"COLLECTION":     "collection",
+}
+
+// metafieldDefinitionsQuery returns a page of the metafield definitions of an
+// owner type.
+// https://shopify.dev/docs/api/admin-graphql/2026-07/queries/metafieldDefinitions
+const metafieldDefinitionsQuery = `query($ownerType: MetafieldOwnerType!, $after: String) {
+  metafieldDefinitions(ownerType: $ownerType, first: 250, after: $after) {
+    nodes { id name namespace key description ownerType type { name } }
+    pageInfo { hasNextPage endCursor }
+  }
+}`
+
+// MetafieldDefinition defines the namespace, key and type of the metafields of
+// a resource. Metafield definitions are only available through the GraphQL
+// Admin API.
+type MetafieldDefinition struct {
+	ID int64 `json:"id"`
+	// OwnerResource is the resource that owns the metafields, e.g. "product"
+	// or "variant".
+	OwnerResource string `json:"ownerResource"`
+	Namespace     string `json:"namespace"`
+	Key           string `json:"key"`
+	Name          string `json:"name"`
+	Description   string `json:"description,omitempty"`
+	// Type is the name of the type of the metafields, e.g.
+	// "single_line_text_field".
+	Type string `json:"type"`
+}
+
+// GetMetafieldDefinitions returns the metafield definitions of products,
+// variants and collections.
+func (c *Client) GetMetafieldDefinitions(ctx context.Context) ([]MetafieldDefinition, error) {
+	defs := []MetafieldDefinition{}
+	for _, ownerType := range []string{"PRODUCT", "PRODUCTVARIANT", "COLLECTION"} {
+		after := ""
+		for {
+			page, err := c.getMetafieldDefinitions(ctx, ownerType, after)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get metafield definitions: %w", err)
+			}
+			for _, n := range page.Nodes {
+				id, err := legacyID(n.ID)
+				if err != nil {
+					return nil, err
+				}
+				defs = append(defs, MetafieldDefinition{
+					ID:            id,
+					OwnerResource: metafieldDefinitionOwnerTypes[n.OwnerType],
+					Namespace:     n.Namespace,
+					Key:           n.Key,
+					Name:          n.Name,
+					Description:   n.Description,
+					Type:          n.Type.Name,
+				})
+			}
+			if !page.PageInfo.HasNextPage {
+				break
+			}
+			after = page.PageInfo.EndCursor
+		}
+	}
+	return defs, nil
+}
+
+// metafieldDefinitionsPage is a page of the metafieldDefinitions query.
+type metafieldDefinitionsPage struct {
+	Nodes []struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		Namespace   string `json:"namespace"`
+		Key         string `json:"key"`
+		Description string `json:"description"`
+		OwnerType   string `json:"ownerType"`
+		Type        struct {
+			Name string `json:"name"`
+		} `json:"type"`
+	} `json:"nodes"`
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	} `json:"pageInfo"`
+}
+
+func (c *Client) getMetafieldDefinitions(ctx context.Context, ownerType string, after string) (*metafieldDefinitionsPage, error) {
+	vars := map[string]any{"ownerType": ownerType}
+	if after != "" {
+		vars["after"] = after
+	}
+	data := struct {
+		MetafieldDefinitions metafieldDefinitionsPage `json:"metafieldDefinitions"`
+	}{}
+	if err := c.graphql.do(ctx, metafieldDefinitionsQuery, vars, &data); err != nil {
+		return nil, err
+	}
+	return &data.MetafieldDefinitions, nil
+}
@@ -0,0 +1,39 @@
+package shopify
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/samherrmann/merchant/shopify/shopifytest"
+)
+
+func TestClient_GetMetafieldDefinitions(t *testing.T) {
+	client, server := newTestClient(t)
+	added := server.AddMetafieldDefinitions(
+		shopifytest.MetafieldDefinition{OwnerType: "PRODUCT", Namespace: "custom", Key: "fabric", Name: "Fabric", Type: "single_line_text_field"},
+		shopifytest.MetafieldDefinition{OwnerType: "PRODUCT", Namespace: "custom", Key: "care", Name: "Care", Type: "multi_line_text_field"},
+		shopifytest.MetafieldDefinition{OwnerType: "PRODUCT", Namespace: "custom", Key: "origin", Name: "Origin", Type: "single_line_text_field"},
+		shopifytest.MetafieldDefinition{OwnerType: "PRODUCTVARIANT", Namespace: "custom", Key: "fit", Name: "Fit", Type: "single_line_text_field"},
+	)
+
+	defs, err := client.GetMetafieldDefinitions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The product definitions span two pages.
+	if len(defs) != 4 {
+		t.Fatalf("got %v definitions, want 4: %+v", len(defs), defs)
+	}
+	want := MetafieldDefinition{
+		ID:            added[3].ID,
+		OwnerResource: "variant",
+		Namespace:     "custom",
+		Key:           "fit",
+		Name:          "Fit",
+		Type:          "single_line_text_field",
+	}
+	if !reflect.DeepEqual(defs[3], want) {
+		t.Fatalf("got definition %+v, want %+v", defs[3], want)
+	}
+}
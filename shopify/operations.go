@@ -1,8 +1,11 @@
 package shopify
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	goshopify "github.com/bold-commerce/go-shopify/v3"
 	"github.com/samherrmann/merchant/memdb"
@@ -11,24 +14,105 @@ import (
 type ProductService = goshopify.ProductService
 type VariantService = goshopify.VariantService
 type Product = goshopify.Product
+type ProductOption = goshopify.ProductOption
 type Variant = goshopify.Variant
+type Metafield = goshopify.Metafield
 type ListOptions = goshopify.ListOptions
+type CountOptions = goshopify.CountOptions
 
-// getProducts gets all products from the store and stores them in the cache
-// file.
-func getProducts(pService ProductService, vService VariantService) ([]Product, error) {
-	products, err := listProducts(pService, nil)
+// services is a set of services that is used by a single worker.
+type services struct {
+	Product        ProductService
+	Variant        VariantService
+	InventoryLevel InventoryLevelService
+	InventoryItem  InventoryItemService
+	// Raw sends requests that the services above can't express.
+	Raw RawClient
+}
+
+// RawClient sends requests with data as their body to the given path and
+// decodes the responses into resource.
+type RawClient interface {
+	Post(path string, data, resource any) error
+	Put(path string, data, resource any) error
+	Delete(path string) error
+}
+
+func newServices(c *goshopify.Client) services {
+	return services{
+		Product:        c.Product,
+		Variant:        c.Variant,
+		InventoryLevel: c.InventoryLevel,
+		InventoryItem:  c.InventoryItem,
+		Raw:            c,
+	}
+}
+
+// getProducts gets the products that match options from the store and attaches
+// their metafields. All products are returned if options is nil. The
+// metafields are fetched concurrently with one worker per set of services in
+// pool. The reporter receives the progress in number of products.
+func getProducts(ctx context.Context, pool []services, reporter Reporter, options *ListOptions) ([]Product, error) {
+	products, err := listProducts(pool[0].Product, options)
 	if err != nil {
 		return nil, err
 	}
-	count := len(products)
-	for i, p := range products {
-		fmt.Printf("Cloning metafields for product %v [%v/%v]\n", p.ID, i+1, count)
-		if err := attachMetafields(pService, vService, &p); err != nil {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	jobs := make(chan int)
+	var mu sync.Mutex
+	done := 0
+	wg := sync.WaitGroup{}
+	for _, s := range pool {
+		wg.Add(1)
+		go func(s services) {
+			defer wg.Done()
+			for i := range jobs {
+				if err := attachMetafields(s.Product, s.Variant, &products[i]); err != nil {
+					cancel(err)
+					return
+				}
+				mu.Lock()
+				done++
+				reporter.Progress(done, len(products))
+				mu.Unlock()
+			}
+		}(s)
+	}
+dispatch:
+	for i := range products {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// getProductsByID gets the products with the given IDs from the store and
+// attaches their metafields. IDs that don't exist in the store are ignored.
+func getProductsByID(ctx context.Context, pool []services, reporter Reporter, ids []int64) ([]Product, error) {
+	products := []Product{}
+	// 250 is the maximum number of IDs per request.
+	for start := 0; start < len(ids); start += 250 {
+		end := start + 250
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunk, err := getProducts(ctx, pool, reporter, &ListOptions{IDs: ids[start:end]})
+		if err != nil {
 			return nil, err
 		}
+		products = append(products, chunk...)
 	}
-	return products, err
+	return products, nil
 }
 
 func listProducts(service ProductService, options *ListOptions) ([]Product, error) {
@@ -59,13 +143,15 @@ func listProducts(service ProductService, options *ListOptions) ([]Product, erro
 }
 
 func updateProducts(
-	pService ProductService,
-	vService VariantService,
+	ctx context.Context,
+	pool []services,
+	reporter Reporter,
 	products []Product,
+	deletions []Product,
 ) error {
 	// Get latest inventory from live store so that we don't accidentally make
 	// updates based on an outdated cache.
-	inventory, err := getProducts(pService, vService)
+	inventory, err := getProducts(ctx, pool, reporter, nil)
 	if err != nil {
 		return err
 	}
@@ -73,32 +159,55 @@ func updateProducts(
 	if err != nil {
 		return err
 	}
-	operations, err := db.Operations(products)
+	operations, err := db.Operations(products, deletions)
 	if err != nil {
 		return err
 	}
-	errs := []error{}
-	for _, p := range operations.NewProducts {
-		if _, err := pService.Create(p); err != nil {
-			errs = append(errs, err)
-		}
+	return applyOperations(ctx, pool, operations, nopJournal{})
+}
+
+// createMetafield creates m on the product or variant that owns it and returns
+// the ID of the new metafield.
+func createMetafield(pService ProductService, vService VariantService, m Metafield) (int64, error) {
+	var created *Metafield
+	var err error
+	switch m.OwnerResource {
+	case memdb.MetafieldOwnerProduct:
+		created, err = pService.CreateMetafield(m.OwnerId, m)
+	case memdb.MetafieldOwnerVariant:
+		created, err = vService.CreateMetafield(m.OwnerId, m)
+	default:
+		err = fmt.Errorf("unknown metafield owner resource %q", m.OwnerResource)
 	}
-	for _, p := range operations.ProductUpdates {
-		if _, err := pService.Update(p); err != nil {
-			errs = append(errs, err)
-		}
+	if err != nil {
+		return 0, err
 	}
-	for _, v := range operations.NewVariants {
-		if _, err := vService.Create(v.ProductID, v); err != nil {
-			errs = append(errs, err)
-		}
+	return created.ID, nil
+}
+
+// updateMetafield updates m on the product or variant that owns it.
+func updateMetafield(pService ProductService, vService VariantService, m Metafield) error {
+	var err error
+	switch m.OwnerResource {
+	case memdb.MetafieldOwnerProduct:
+		_, err = pService.UpdateMetafield(m.OwnerId, m)
+	case memdb.MetafieldOwnerVariant:
+		_, err = vService.UpdateMetafield(m.OwnerId, m)
+	default:
+		err = fmt.Errorf("unknown metafield owner resource %q", m.OwnerResource)
 	}
-	for _, v := range operations.VariantUpdates {
-		if _, err := vService.Update(v); err != nil {
-			errs = append(errs, err)
-		}
+	return err
+}
+
+// deleteMetafield deletes m from the product or variant that owns it.
+func deleteMetafield(pService ProductService, vService VariantService, m Metafield) error {
+	switch m.OwnerResource {
+	case memdb.MetafieldOwnerProduct:
+		return pService.DeleteMetafield(m.OwnerId, m.ID)
+	case memdb.MetafieldOwnerVariant:
+		return vService.DeleteMetafield(m.OwnerId, m.ID)
 	}
-	return errors.Join(errs...)
+	return fmt.Errorf("unknown metafield owner resource %q", m.OwnerResource)
 }
 
 // attachMetafields fetches and attaches all metafields for the given product and its variants.
@@ -108,6 +217,7 @@ func attachMetafields(pService ProductService, vService VariantService, product
 		return fmt.Errorf("failed to get metafields for product %v: %w", product.ID, err)
 	}
 	product.Metafields = metafields
+	moveSEOMetafields(product)
 
 	for j, variant := range product.Variants {
 		metafields, err := vService.ListMetafields(variant.ID, nil)
@@ -119,8 +229,8 @@ func attachMetafields(pService ProductService, vService VariantService, product
 	return nil
 }
 
-func searchVariant(pService ProductService, vService VariantService, fn func(v *Variant) bool) (*Variant, error) {
-	inventory, err := getProducts(pService, vService)
+func searchVariant(ctx context.Context, pool []services, reporter Reporter, fn func(v *Variant) bool) (*Variant, error) {
+	inventory, err := getProducts(ctx, pool, reporter, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -134,6 +244,103 @@ func searchVariant(pService ProductService, vService VariantService, fn func(v *
 	return nil, ErrNotExist
 }
 
+// isStale reports whether products have been created, updated or deleted in
+// the store since the given products were fetched from it. Changes that do not
+// update a product's updated_at timestamp, such as metafield changes, are not
+// detected.
+func isStale(service ProductService, products []Product) (bool, error) {
+	count, err := service.Count(nil)
+	if err != nil {
+		return false, err
+	}
+	if count != len(products) {
+		return true, nil
+	}
+	var latest time.Time
+	for _, p := range products {
+		if p.UpdatedAt != nil && p.UpdatedAt.After(latest) {
+			latest = *p.UpdatedAt
+		}
+	}
+	if latest.IsZero() {
+		return count > 0, nil
+	}
+	// The updated_at_min filter is inclusive and timestamps have a resolution of
+	// one second.
+	count, err = service.Count(&CountOptions{UpdatedAtMin: latest.Add(time.Second)})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// checkBaseline returns an error if any of the products in baseline has been
+// updated or deleted since its recorded updated_at timestamp.
+func checkBaseline(service ProductService, baseline map[int64]time.Time) error {
+	ids := make([]int64, 0, len(baseline))
+	for id := range baseline {
+		ids = append(ids, id)
+	}
+	current, err := listUpdatedAt(service, ids)
+	if err != nil {
+		return err
+	}
+	errs := []error{}
+	for _, id := range ids {
+		updatedAt, exists := current[id]
+		if !exists {
+			errs = append(errs, fmt.Errorf("product %v: %w", id, ErrNotExist))
+			continue
+		}
+		if !updatedAt.Equal(baseline[id]) {
+			errs = append(errs, fmt.Errorf("product %v: updated at %v", id, updatedAt))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrDrift, errors.Join(errs...))
+	}
+	return nil
+}
+
+// listUpdatedAt returns the time at which each of the products with the given
+// IDs was last updated. Products that don't exist are omitted.
+func listUpdatedAt(service ProductService, ids []int64) (map[int64]time.Time, error) {
+	updatedAt := map[int64]time.Time{}
+	// 250 is the maximum number of IDs per request.
+	for start := 0; start < len(ids); start += 250 {
+		end := start + 250
+		if end > len(ids) {
+			end = len(ids)
+		}
+		products, err := listProducts(service, &ListOptions{
+			IDs:    ids[start:end],
+			Fields: "id,updated_at",
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range products {
+			if p.UpdatedAt != nil {
+				updatedAt[p.ID] = *p.UpdatedAt
+			}
+		}
+	}
+	return updatedAt, nil
+}
+
+// getProductIDs returns the IDs of all products in the store.
+func getProductIDs(service ProductService) ([]int64, error) {
+	products, err := listProducts(service, &ListOptions{Fields: "id"})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	return ids, nil
+}
+
 func getVariantCount(service ProductService) (int, error) {
 	options := &ListOptions{
 		Fields: "variants",
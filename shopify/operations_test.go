@@ -0,0 +1,93 @@
+package shopify
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samherrmann/merchant/shopify/shopifytest"
+	"github.com/shopspring/decimal"
+)
+
+// newTestClient returns a client of a fake store that contains the given
+// products.
+func newTestClient(t *testing.T, products ...Product) (*Client, *shopifytest.Server) {
+	t.Helper()
+	server := shopifytest.NewServer(shopifytest.WithAccessToken("token"), shopifytest.WithMaxLimit(2))
+	t.Cleanup(server.Close)
+	server.AddProducts(products...)
+	client := NewClient(
+		&Configuration{Name: "foo", AccessToken: "token", Concurrency: 2},
+		WithTransport(server.Transport()),
+	)
+	return client, server
+}
+
+func TestClient_GetProducts(t *testing.T) {
+	client, _ := newTestClient(t,
+		Product{Title: "Hat", Metafields: []Metafield{
+			{Namespace: "custom", Key: "fabric", Value: "wool", Type: "single_line_text_field"},
+		}},
+		Product{Title: "Shirt", Variants: []Variant{{Option1: "S", Metafields: []Metafield{
+			{Namespace: "custom", Key: "fit", Value: "slim", Type: "single_line_text_field"},
+		}}}},
+		Product{Title: "Socks"},
+	)
+	products, err := client.GetProducts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != 3 {
+		t.Fatalf("got %v products, want 3", len(products))
+	}
+	if m := products[0].Metafields; len(m) != 1 || m[0].Value != "wool" {
+		t.Fatalf("got product metafields %+v", m)
+	}
+	if m := products[1].Variants[0].Metafields; len(m) != 1 || m[0].Value != "slim" {
+		t.Fatalf("got variant metafields %+v", m)
+	}
+}
+
+func TestClient_UpdateProducts(t *testing.T) {
+	client, server := newTestClient(t,
+		Product{Title: "Shirt", Options: []ProductOption{{Name: "Size"}}, Variants: []Variant{
+			{Option1: "S", Sku: "SHIRT-S"},
+			{Option1: "M", Sku: "SHIRT-M"},
+		}},
+	)
+	price := decimal.NewFromInt(12)
+	products := []Product{
+		{Title: "Shirt", Vendor: "Acme", Variants: []Variant{
+			{Sku: "SHIRT-S", Price: &price},
+			{Sku: "SHIRT-L", Option1: "L"},
+		}},
+		{Title: "Hat", Variants: []Variant{{Sku: "HAT"}}},
+	}
+	deletions := []Product{
+		{Title: "Shirt", Variants: []Variant{{Sku: "SHIRT-M"}}},
+	}
+	if err := client.UpdateProducts(context.Background(), products, deletions); err != nil {
+		t.Fatal(err)
+	}
+
+	got := server.Products()
+	if len(got) != 2 {
+		t.Fatalf("got %v products, want 2", len(got))
+	}
+	shirt := got[0]
+	if shirt.Vendor != "Acme" {
+		t.Errorf("got vendor %q, want %q", shirt.Vendor, "Acme")
+	}
+	skus := []string{}
+	for _, v := range shirt.Variants {
+		skus = append(skus, v.Sku)
+	}
+	if len(skus) != 2 || skus[0] != "SHIRT-S" || skus[1] != "SHIRT-L" {
+		t.Fatalf("got variants %v, want [SHIRT-S SHIRT-L]", skus)
+	}
+	if !shirt.Variants[0].Price.Equal(price) {
+		t.Errorf("got price %v, want %v", shirt.Variants[0].Price, price)
+	}
+	if got[1].Title != "Hat" || got[1].Variants[0].Sku != "HAT" {
+		t.Errorf("got product %+v", got[1])
+	}
+}
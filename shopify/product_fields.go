@@ -0,0 +1,69 @@
+package shopify
+
+import (
+	"fmt"
+
+	"github.com/samherrmann/merchant/memdb"
+)
+
+// The metafields in which the store keeps the SEO title and description of a
+// product. The REST API updates them through the metafields_global_title_tag
+// and metafields_global_description_tag fields of the product, but doesn't
+// return these fields.
+const (
+	seoNamespace      = "global"
+	seoTitleKey       = "title_tag"
+	seoDescriptionKey = "description_tag"
+)
+
+// moveSEOMetafields moves the values of the SEO metafields of p to the SEO
+// fields of p, so that they are updated the same way as they are read.
+func moveSEOMetafields(p *Product) {
+	metafields := []Metafield{}
+	for _, m := range p.Metafields {
+		if m.Namespace != seoNamespace {
+			metafields = append(metafields, m)
+			continue
+		}
+		switch m.Key {
+		case seoTitleKey:
+			p.MetafieldsGlobalTitleTag = memdb.MetafieldValue(m.Value)
+		case seoDescriptionKey:
+			p.MetafieldsGlobalDescriptionTag = memdb.MetafieldValue(m.Value)
+		default:
+			metafields = append(metafields, m)
+		}
+	}
+	p.Metafields = metafields
+}
+
+// updateTags adds and removes the tags of u to and from the current tags of
+// the product. The tags are sent through a raw request because product
+// updates omit empty tags.
+func updateTags(service ProductService, raw RawClient, u memdb.TagUpdate) error {
+	p, err := service.Get(u.ProductID, nil)
+	if err != nil {
+		return err
+	}
+	product := map[string]any{
+		"id":   u.ProductID,
+		"tags": memdb.ApplyTags(p.Tags, u.Add, u.Remove),
+	}
+	return raw.Put(fmt.Sprintf("products/%v.json", u.ProductID), map[string]any{"product": product}, nil)
+}
+
+// resetProduct sets the fields of r through a raw request because product
+// updates omit empty values.
+func resetProduct(raw RawClient, r memdb.ProductReset) error {
+	product := map[string]any{"id": r.ID}
+	for name, value := range r.Fields {
+		product[name] = value
+	}
+	return raw.Put(fmt.Sprintf("products/%v.json", r.ID), map[string]any{"product": product}, nil)
+}
+
+// tagsKey returns the key of the step that updates the tags of the product with
+// the given ID.
+func tagsKey(productID int64) string {
+	return fmt.Sprintf("%v.tags.%v", memdb.ResourceProduct, productID)
+}
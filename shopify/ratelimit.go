@@ -0,0 +1,143 @@
+package shopify
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultBucketSize is the size of the leaky bucket of a standard store.
+	// It is used until the store reports its actual bucket size.
+	// https://shopify.dev/api/usage/rate-limits#rest-admin-api-rate-limits
+	defaultBucketSize = 40
+	// defaultLeakRate is the number of requests per second that leak out of the
+	// bucket of a standard store.
+	defaultLeakRate = 2
+	// callLimitHeader is the response header through which Shopify reports the
+	// bucket usage, e.g. "32/40".
+	callLimitHeader = "X-Shopify-Shop-Api-Call-Limit"
+)
+
+// newRateLimiter returns an http.RoundTripper that delays requests so that
+// Shopify's leaky bucket never overflows.
+func newRateLimiter(next http.RoundTripper) *rateLimiter {
+	return &rateLimiter{
+		next:     next,
+		size:     defaultBucketSize,
+		leakRate: defaultLeakRate,
+		now:      time.Now,
+	}
+}
+
+// rateLimiter throttles requests according to Shopify's leaky bucket algorithm.
+// The bucket level is taken from the X-Shopify-Shop-Api-Call-Limit header of
+// the most recent response and is extrapolated from there, taking requests in
+// flight into account.
+type rateLimiter struct {
+	next     http.RoundTripper
+	leakRate float64
+	now      func() time.Time
+
+	mu sync.Mutex
+	// used is the bucket level as last reported by the store.
+	used int
+	// size is the bucket size as last reported by the store.
+	size int
+	// reportedAt is the time at which used was reported.
+	reportedAt time.Time
+	// pending is the number of requests in flight.
+	pending int
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (l *rateLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := l.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	resp, err := l.next.RoundTrip(req)
+	l.done(resp)
+	return resp, err
+}
+
+// wait blocks until there is room in the bucket for another request and then
+// reserves it.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	for {
+		delay := l.reserve()
+		if delay == 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve reserves room for a request in the bucket and returns zero, or
+// returns the time to wait until there is room for it.
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// One slot is kept free as headroom for requests from other apps.
+	excess := l.level() + 1 - float64(l.size-1)
+	if excess <= 0 {
+		l.pending++
+		return 0
+	}
+	return time.Duration(excess / l.leakRate * float64(time.Second))
+}
+
+// level returns the estimated current bucket level. The caller must hold mu.
+func (l *rateLimiter) level() float64 {
+	level := float64(l.used)
+	if !l.reportedAt.IsZero() {
+		level -= l.now().Sub(l.reportedAt).Seconds() * l.leakRate
+	}
+	if level < 0 {
+		level = 0
+	}
+	return level + float64(l.pending)
+}
+
+// done releases the reservation of a request and updates the bucket level
+// from the response.
+func (l *rateLimiter) done(resp *http.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending--
+	if resp == nil {
+		return
+	}
+	used, size, ok := parseCallLimit(resp.Header.Get(callLimitHeader))
+	if !ok {
+		return
+	}
+	l.used = used
+	l.size = size
+	l.reportedAt = l.now()
+}
+
+// parseCallLimit parses the value of the X-Shopify-Shop-Api-Call-Limit header.
+func parseCallLimit(s string) (used int, size int, ok bool) {
+	usedStr, sizeStr, found := strings.Cut(s, "/")
+	if !found {
+		return 0, 0, false
+	}
+	used, err := strconv.Atoi(usedStr)
+	if err != nil {
+		return 0, 0, false
+	}
+	size, err = strconv.Atoi(sizeStr)
+	if err != nil || size < 2 {
+		return 0, 0, false
+	}
+	return used, size, true
+}
@@ -0,0 +1,77 @@
+package shopify
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_parseCallLimit(t *testing.T) {
+	tests := []struct {
+		s    string
+		used int
+		size int
+		ok   bool
+	}{
+		{"32/40", 32, 40, true},
+		{"1/80", 1, 80, true},
+		{"", 0, 0, false},
+		{"32", 0, 0, false},
+		{"a/40", 0, 0, false},
+		{"32/b", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			used, size, ok := parseCallLimit(tt.s)
+			if used != tt.used || size != tt.size || ok != tt.ok {
+				t.Fatalf("got (%v, %v, %v), want (%v, %v, %v)", used, size, ok, tt.used, tt.size, tt.ok)
+			}
+		})
+	}
+}
+
+func Test_rateLimiter_reserve(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	newLimiter := func() *rateLimiter {
+		l := newRateLimiter(http.DefaultTransport)
+		l.now = func() time.Time { return now }
+		return l
+	}
+	respond := func(l *rateLimiter, used int, size int) {
+		l.done(&http.Response{Header: http.Header{
+			callLimitHeader: []string{fmt.Sprintf("%v/%v", used, size)},
+		}})
+	}
+
+	t.Run("allows requests while the bucket has room", func(t *testing.T) {
+		l := newLimiter()
+		for i := 0; i < defaultBucketSize-1; i++ {
+			if d := l.reserve(); d != 0 {
+				t.Fatalf("request %v: got delay %v, want 0", i, d)
+			}
+		}
+		if d := l.reserve(); d == 0 {
+			t.Fatal("expected delay when bucket is full")
+		}
+	})
+
+	t.Run("delays requests when the store reports a full bucket", func(t *testing.T) {
+		l := newLimiter()
+		l.reserve()
+		respond(l, 39, 40)
+		if d := l.reserve(); d != time.Second/2 {
+			t.Fatalf("got delay %v, want %v", d, time.Second/2)
+		}
+	})
+
+	t.Run("takes leaked requests into account", func(t *testing.T) {
+		l := newLimiter()
+		l.reserve()
+		respond(l, 39, 40)
+		now = now.Add(time.Second)
+		if d := l.reserve(); d != 0 {
+			t.Fatalf("got delay %v, want 0", d)
+		}
+	})
+}
@@ -0,0 +1,12 @@
+package shopify
+
+// Reporter receives progress updates of long running operations.
+type Reporter interface {
+	// Progress is called each time an item out of total items is done.
+	Progress(done int, total int)
+}
+
+// nopReporter is a Reporter that discards all progress updates.
+type nopReporter struct{}
+
+func (nopReporter) Progress(done int, total int) {}
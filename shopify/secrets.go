@@ -0,0 +1,140 @@
+package shopify
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Environment variables that hold secrets. The variables with a store name
+// apply to that store only and take precedence over the ones without, which
+// only apply to the default store.
+const (
+	envPrefix      = "MERCHANT_STORE_"
+	envAPIKey      = "API_KEY"
+	envPassword    = "PASSWORD"
+	envAccessToken = "ACCESS_TOKEN"
+)
+
+// keyringService is the service attribute of the secrets in the keyring.
+const keyringService = "merchant"
+
+// runCommand runs a command and returns its standard output. Its standard
+// error is written to stderr. It is a variable so that it can be replaced in
+// tests.
+var runCommand = func(stderr io.Writer, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = stderr
+	return cmd.Output()
+}
+
+// HasInlineSecrets reports whether c contains secrets in plain text.
+func (c *Configuration) HasInlineSecrets() bool {
+	return c.Password != "" || c.AccessToken != ""
+}
+
+// ResolveSecrets sets the API key, password and access token of c from the
+// first of the following sources that provides them:
+//
+//  1. The MERCHANT_STORE_<NAME>_API_KEY, MERCHANT_STORE_<NAME>_PASSWORD and
+//     MERCHANT_STORE_<NAME>_ACCESS_TOKEN environment variables, where <NAME>
+//     is the upper-case store name with dashes replaced by underscores.
+//  2. The MERCHANT_STORE_API_KEY, MERCHANT_STORE_PASSWORD and
+//     MERCHANT_STORE_ACCESS_TOKEN environment variables if defaultStore is
+//     true, so that the secrets of one store are never sent to another, e.g.
+//     when syncing stores.
+//  3. The values in the configuration itself.
+//  4. The output of PasswordCommand and AccessTokenCommand.
+//  5. The keyring if Keyring is true, using secret-tool to look up the secrets
+//     with the attributes "service merchant store <name> secret <secret>". If
+//     no credentials have been found so far, the "accessToken" secret is looked
+//     up first. The "apiKey" and "password" secrets are looked up otherwise.
+func (c *Configuration) ResolveSecrets(defaultStore bool) error {
+	if v, ok := lookupSecretEnv(c.Name, envAPIKey, defaultStore); ok {
+		c.APIKey = v
+	}
+	if v, ok := lookupSecretEnv(c.Name, envPassword, defaultStore); ok {
+		c.Password = v
+	}
+	if v, ok := lookupSecretEnv(c.Name, envAccessToken, defaultStore); ok {
+		c.AccessToken = v
+	}
+	if c.Password == "" && len(c.PasswordCommand) > 0 {
+		out, err := runCommand(os.Stderr, c.PasswordCommand[0], c.PasswordCommand[1:]...)
+		if err != nil {
+			return fmt.Errorf("store %v: password command: %w", c.Name, err)
+		}
+		c.Password = trimNewline(out)
+	}
+	if c.AccessToken == "" && len(c.AccessTokenCommand) > 0 {
+		out, err := runCommand(os.Stderr, c.AccessTokenCommand[0], c.AccessTokenCommand[1:]...)
+		if err != nil {
+			return fmt.Errorf("store %v: access token command: %w", c.Name, err)
+		}
+		c.AccessToken = trimNewline(out)
+	}
+	if !c.Keyring || c.AccessToken != "" {
+		return nil
+	}
+	if c.APIKey == "" && c.Password == "" {
+		// secret-tool fails if the secret doesn't exist, in which case the API
+		// key and password are looked up instead. Its errors are discarded
+		// because a missing access token is expected.
+		if v, err := lookupKeyring(c.Name, "accessToken", io.Discard); err == nil && v != "" {
+			c.AccessToken = v
+			return nil
+		}
+	}
+	if c.APIKey == "" {
+		v, err := lookupKeyring(c.Name, "apiKey", os.Stderr)
+		if err != nil {
+			return err
+		}
+		c.APIKey = v
+	}
+	if c.Password == "" {
+		v, err := lookupKeyring(c.Name, "password", os.Stderr)
+		if err != nil {
+			return err
+		}
+		c.Password = v
+	}
+	return nil
+}
+
+// lookupSecretEnv returns the value of the environment variable for the given
+// secret of the given store. The variable without a store name is only looked
+// up if defaultStore is true.
+func lookupSecretEnv(store string, secret string, defaultStore bool) (string, bool) {
+	if store != "" {
+		name := strings.ToUpper(strings.ReplaceAll(store, "-", "_"))
+		if v, ok := os.LookupEnv(envPrefix + name + "_" + secret); ok {
+			return v, true
+		}
+	}
+	if !defaultStore {
+		return "", false
+	}
+	return os.LookupEnv(envPrefix + secret)
+}
+
+// lookupKeyring returns the given secret of the given store from a keyring
+// that implements the Secret Service API. The errors of secret-tool are
+// written to stderr.
+func lookupKeyring(store string, secret string, stderr io.Writer) (string, error) {
+	out, err := runCommand(stderr, "secret-tool", "lookup", "service", keyringService, "store", store, "secret", secret)
+	if err != nil {
+		return "", fmt.Errorf("store %v: keyring lookup of %v: %w", store, secret, err)
+	}
+	return trimNewline(out), nil
+}
+
+// trimNewline removes a trailing line break from b.
+func trimNewline(b []byte) string {
+	b = bytes.TrimSuffix(b, []byte("\n"))
+	b = bytes.TrimSuffix(b, []byte("\r"))
+	return string(b)
+}
@@ -0,0 +1,128 @@
+package shopify
+
+import (
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfiguration_ResolveSecrets(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Configuration
+		env      map[string]string
+		other    bool
+		want     Configuration
+		wantCall string
+		wantErr  bool
+	}{
+		{
+			name:   "should keep inline secrets",
+			config: Configuration{Name: "foo", APIKey: "key", Password: "inline"},
+			want:   Configuration{Name: "foo", APIKey: "key", Password: "inline"},
+		},
+		{
+			name:   "should prefer store environment variables",
+			config: Configuration{Name: "foo-bar", Password: "inline"},
+			env: map[string]string{
+				"MERCHANT_STORE_FOO_BAR_API_KEY":  "store-key",
+				"MERCHANT_STORE_FOO_BAR_PASSWORD": "store-env",
+				"MERCHANT_STORE_PASSWORD":         "env",
+			},
+			want: Configuration{Name: "foo-bar", APIKey: "store-key", Password: "store-env"},
+		},
+		{
+			name:   "should fall back to generic environment variables",
+			config: Configuration{Name: "foo", Password: "inline"},
+			env:    map[string]string{"MERCHANT_STORE_PASSWORD": "env"},
+			want:   Configuration{Name: "foo", Password: "env"},
+		},
+		{
+			name:   "should ignore generic environment variables for other stores",
+			config: Configuration{Name: "foo", Password: "inline"},
+			env:    map[string]string{"MERCHANT_STORE_PASSWORD": "env"},
+			other:  true,
+			want:   Configuration{Name: "foo", Password: "inline"},
+		},
+		{
+			name:     "should run password command",
+			config:   Configuration{Name: "foo", PasswordCommand: []string{"pass", "show", "foo"}},
+			want:     Configuration{Name: "foo", Password: "secret", PasswordCommand: []string{"pass", "show", "foo"}},
+			wantCall: "pass show foo",
+		},
+		{
+			name:     "should look up keyring",
+			config:   Configuration{Name: "foo", APIKey: "key", Keyring: true},
+			want:     Configuration{Name: "foo", APIKey: "key", Password: "secret", Keyring: true},
+			wantCall: "secret-tool lookup service merchant store foo secret password",
+		},
+		{
+			name:   "should read access token from environment",
+			config: Configuration{Name: "foo"},
+			env:    map[string]string{"MERCHANT_STORE_FOO_ACCESS_TOKEN": "token"},
+			want:   Configuration{Name: "foo", AccessToken: "token"},
+		},
+		{
+			name:     "should run access token command",
+			config:   Configuration{Name: "foo", AccessTokenCommand: []string{"pass", "foo"}},
+			want:     Configuration{Name: "foo", AccessToken: "secret", AccessTokenCommand: []string{"pass", "foo"}},
+			wantCall: "pass foo",
+		},
+		{
+			name:     "should look up access token in keyring first",
+			config:   Configuration{Name: "foo", Keyring: true},
+			want:     Configuration{Name: "foo", AccessToken: "secret", Keyring: true},
+			wantCall: "secret-tool lookup service merchant store foo secret accessToken",
+		},
+		{
+			name:     "should fail if command fails",
+			config:   Configuration{Name: "foo", PasswordCommand: []string{"fail"}},
+			wantCall: "fail",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setenv restores the variables after the test.
+			for _, k := range []string{"MERCHANT_STORE_API_KEY", "MERCHANT_STORE_PASSWORD", "MERCHANT_STORE_ACCESS_TOKEN"} {
+				t.Setenv(k, "")
+				os.Unsetenv(k)
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			calls := []string{}
+			original := runCommand
+			t.Cleanup(func() { runCommand = original })
+			runCommand = func(stderr io.Writer, name string, args ...string) ([]byte, error) {
+				calls = append(calls, strings.Join(append([]string{name}, args...), " "))
+				if len(args) > 0 && args[len(args)-1] == "accessToken" && stderr != io.Discard {
+					t.Errorf("errors of access token lookup are not discarded")
+				}
+				if name == "fail" {
+					return nil, errors.New("fake")
+				}
+				return []byte("secret\n"), nil
+			}
+
+			c := tt.config
+			err := c.ResolveSecrets(!tt.other)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantCall != "" && (len(calls) != 1 || calls[0] != tt.wantCall) {
+				t.Fatalf("got calls %q, want %q", calls, tt.wantCall)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(c, tt.want) {
+				t.Fatalf("got %+v, want %+v", c, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package shopifytest
+
+import (
+	"fmt"
+	"net/http"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+)
+
+// AddCustomCollections adds custom collections with the given titles to the
+// store and returns them.
+func (s *Server) AddCustomCollections(titles ...string) []goshopify.CustomCollection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	added := []goshopify.CustomCollection{}
+	for _, title := range titles {
+		c := goshopify.CustomCollection{
+			ID:             s.nextID(),
+			Title:          title,
+			Handle:         s.uniqueHandle(title),
+			UpdatedAt:      s.timestamp(),
+			SortOrder:      "manual",
+			PublishedScope: "web",
+			Published:      true,
+		}
+		s.customCollections = append(s.customCollections, c)
+		added = append(added, c)
+	}
+	return added
+}
+
+// AddSmartCollections adds smart collections with the given titles and no
+// rules to the store and returns them.
+func (s *Server) AddSmartCollections(titles ...string) []goshopify.SmartCollection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	added := []goshopify.SmartCollection{}
+	for _, title := range titles {
+		c := goshopify.SmartCollection{
+			ID:             s.nextID(),
+			Title:          title,
+			Handle:         s.uniqueHandle(title),
+			UpdatedAt:      s.timestamp(),
+			SortOrder:      "best-selling",
+			PublishedScope: "web",
+			Published:      true,
+			Rules:          []goshopify.Rule{},
+		}
+		s.smartCollections = append(s.smartCollections, c)
+		added = append(added, c)
+	}
+	return added
+}
+
+// listCollections implements GET custom_collections.json and GET
+// smart_collections.json with the limit parameter and cursor based pagination
+// through page_info. key is the name of the collections in the response body.
+func (s *Server) listCollections(r *http.Request, key string) (int, any, error) {
+	query, offset, limit, err := s.pageQuery(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	collections := []any{}
+	switch key {
+	case "custom_collections":
+		for _, c := range s.customCollections {
+			collections = append(collections, c)
+		}
+	case "smart_collections":
+		for _, c := range s.smartCollections {
+			collections = append(collections, c)
+		}
+	default:
+		return 0, nil, fmt.Errorf("unknown collections %q", key)
+	}
+	if offset > len(collections) {
+		offset = len(collections)
+	}
+	end := offset + limit
+	if end > len(collections) {
+		end = len(collections)
+	}
+	body := map[string]any{key: collections[offset:end]}
+	return http.StatusOK, pageResponse(r, query, limit, end, len(collections), body), nil
+}
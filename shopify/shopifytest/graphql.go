@@ -0,0 +1,100 @@
+package shopifytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MetafieldDefinition is a metafield definition of the store. Metafield
+// definitions are only available through the GraphQL Admin API.
+type MetafieldDefinition struct {
+	ID int64
+	// OwnerType is the GraphQL owner type, e.g. PRODUCT or PRODUCTVARIANT.
+	OwnerType   string
+	Namespace   string
+	Key         string
+	Name        string
+	Description string
+	// Type is the name of the type, e.g. single_line_text_field.
+	Type string
+}
+
+// AddMetafieldDefinitions adds the given metafield definitions to the store
+// and returns them with their IDs set.
+func (s *Server) AddMetafieldDefinitions(defs ...MetafieldDefinition) []MetafieldDefinition {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	added := []MetafieldDefinition{}
+	for _, d := range defs {
+		d.ID = s.nextID()
+		s.definitions = append(s.definitions, d)
+		added = append(added, d)
+	}
+	return added
+}
+
+// postGraphQL implements POST graphql.json. Only the metafieldDefinitions
+// query is supported. Its page size is the maximum limit of the store and its
+// cursors are offsets.
+func (s *Server) postGraphQL(r *http.Request) (int, any, error) {
+	req := struct {
+		Query     string         `json:"query"`
+		Variables map[string]any `json:"variables"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return 0, nil, badRequest("invalid body: %v", err)
+	}
+	if !strings.Contains(req.Query, "metafieldDefinitions") {
+		return http.StatusOK, map[string]any{
+			"errors": []map[string]any{{"message": "unsupported query"}},
+		}, nil
+	}
+	ownerType, _ := req.Variables["ownerType"].(string)
+	offset := 0
+	if after, ok := req.Variables["after"].(string); ok && after != "" {
+		n, err := strconv.Atoi(after)
+		if err != nil {
+			return 0, nil, badRequest("invalid cursor %q", after)
+		}
+		offset = n
+	}
+	defs := []MetafieldDefinition{}
+	for _, d := range s.definitions {
+		if d.OwnerType == ownerType {
+			defs = append(defs, d)
+		}
+	}
+	if offset > len(defs) {
+		offset = len(defs)
+	}
+	end := offset + s.maxLimit
+	if end > len(defs) {
+		end = len(defs)
+	}
+	nodes := []map[string]any{}
+	for _, d := range defs[offset:end] {
+		nodes = append(nodes, map[string]any{
+			"id":          fmt.Sprintf("gid://shopify/MetafieldDefinition/%v", d.ID),
+			"name":        d.Name,
+			"namespace":   d.Namespace,
+			"key":         d.Key,
+			"description": d.Description,
+			"ownerType":   d.OwnerType,
+			"type":        map[string]any{"name": d.Type},
+		})
+	}
+	return http.StatusOK, map[string]any{
+		"data": map[string]any{
+			"metafieldDefinitions": map[string]any{
+				"nodes": nodes,
+				"pageInfo": map[string]any{
+					"hasNextPage": end < len(defs),
+					"endCursor":   strconv.Itoa(end),
+				},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,234 @@
+package shopifytest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+)
+
+// productResponse is a product as returned by the API. goshopify.Image doesn't
+// have the alt text of images, so it is added here.
+type productResponse struct {
+	goshopify.Product
+	Images []imageResponse `json:"images,omitempty"`
+}
+
+type imageResponse struct {
+	goshopify.Image
+	Alt string `json:"alt"`
+}
+
+// imageRequest is the body of the image create and update requests.
+type imageRequest struct {
+	goshopify.Image
+	Alt string `json:"alt"`
+}
+
+// ImageAlts returns the alt texts of all images that have one by image ID.
+func (s *Server) ImageAlts() map[int64]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	alts := map[int64]string{}
+	for id, alt := range s.alts {
+		alts[id] = alt
+	}
+	return alts
+}
+
+// productResponse returns p with the alt texts of its images.
+func (s *Server) productResponse(p goshopify.Product) productResponse {
+	r := productResponse{Product: p}
+	for _, img := range p.Images {
+		r.Images = append(r.Images, s.imageResponse(img))
+	}
+	return r
+}
+
+func (s *Server) imageResponse(img goshopify.Image) imageResponse {
+	return imageResponse{Image: img, Alt: s.alts[img.ID]}
+}
+
+// routeImages dispatches requests for the images of a product, e.g.
+// products/<id>/images.json or products/<id>/images/<id>.json.
+func (s *Server) routeImages(r *http.Request, segments []string) (int, any, error) {
+	productID, err := parseID(segments[1])
+	if err != nil {
+		return 0, nil, err
+	}
+	p, ok := s.products[productID]
+	if !ok {
+		return 0, nil, notFound()
+	}
+	switch {
+	case len(segments) == 3 && r.Method == http.MethodGet:
+		images := []imageResponse{}
+		for _, img := range p.Images {
+			images = append(images, s.imageResponse(img))
+		}
+		return http.StatusOK, map[string]any{"images": images}, nil
+	case len(segments) == 3 && r.Method == http.MethodPost:
+		req := imageRequest{}
+		keys, err := decodeBody(r, "image", &req)
+		if err != nil {
+			return 0, nil, err
+		}
+		img, err := s.addImage(p, req.Image)
+		if err != nil {
+			return 0, nil, err
+		}
+		if _, ok := keys["alt"]; ok {
+			s.alts[img.ID] = req.Alt
+		}
+		p.UpdatedAt = s.timestamp()
+		return http.StatusOK, map[string]any{"image": s.imageResponse(*findImage(p, img.ID))}, nil
+	case len(segments) == 4:
+		id, err := parseID(segments[3])
+		if err != nil {
+			return 0, nil, err
+		}
+		img := findImage(p, id)
+		if img == nil {
+			return 0, nil, notFound()
+		}
+		switch r.Method {
+		case http.MethodPut:
+			req := imageRequest{}
+			keys, err := decodeBody(r, "image", &req)
+			if err != nil {
+				return 0, nil, err
+			}
+			if _, ok := keys["alt"]; ok {
+				s.alts[id] = req.Alt
+			}
+			if _, ok := keys["variant_ids"]; ok {
+				setImageVariants(p, id, req.VariantIds)
+			}
+			if req.Position != 0 {
+				moveImage(p, id, req.Position)
+			}
+			p.UpdatedAt = s.timestamp()
+			return http.StatusOK, map[string]any{"image": s.imageResponse(*findImage(p, id))}, nil
+		case http.MethodDelete:
+			setImageVariants(p, id, nil)
+			images := []goshopify.Image{}
+			for _, other := range p.Images {
+				if other.ID != id {
+					images = append(images, other)
+				}
+			}
+			p.Images = images
+			delete(s.alts, id)
+			renumberImages(p)
+			p.UpdatedAt = s.timestamp()
+			return http.StatusOK, nil, nil
+		}
+	}
+	return 0, nil, notFound()
+}
+
+// addImage adds img to the images of p at its position, or after the other
+// images if it has no position. Uploaded and linked images are both served
+// from the CDN of the store.
+func (s *Server) addImage(p *goshopify.Product, img goshopify.Image) (goshopify.Image, error) {
+	name := img.Filename
+	if img.Attachment == "" {
+		u, err := url.Parse(img.Src)
+		if err != nil || u.Scheme == "" {
+			return img, unprocessable("image", "src or attachment is required")
+		}
+		name = path.Base(u.Path)
+	}
+	if name == "" {
+		return img, unprocessable("filename", "can't be blank")
+	}
+	variants := img.VariantIds
+	img.ID = s.nextID()
+	img.ProductID = p.ID
+	img.Src = fmt.Sprintf("https://cdn.shopify.com/s/files/1/products/%v?v=%v", name, img.ID)
+	img.Attachment = ""
+	img.Filename = ""
+	img.VariantIds = nil
+	img.CreatedAt = s.timestamp()
+	img.UpdatedAt = img.CreatedAt
+	position := img.Position
+	img.Position = len(p.Images) + 1
+	p.Images = append(p.Images, img)
+	if position != 0 {
+		moveImage(p, img.ID, position)
+	}
+	renumberImages(p)
+	setImageVariants(p, img.ID, variants)
+	return img, nil
+}
+
+// setImageVariants makes the image with the given ID the image of the
+// variants with the given IDs, and removes it from all other variants.
+func setImageVariants(p *goshopify.Product, id int64, variantIDs []int64) {
+	for i := range p.Variants {
+		v := &p.Variants[i]
+		if v.ImageID == id {
+			v.ImageID = 0
+		}
+		if contains64(variantIDs, v.ID) {
+			v.ImageID = id
+		}
+	}
+	for i := range p.Images {
+		img := &p.Images[i]
+		img.VariantIds = nil
+		for _, v := range p.Variants {
+			if v.ImageID == img.ID {
+				img.VariantIds = append(img.VariantIds, v.ID)
+			}
+		}
+	}
+}
+
+// moveImage moves the image with the given ID to position.
+func moveImage(p *goshopify.Product, id int64, position int) {
+	for i := range p.Images {
+		if p.Images[i].ID == id {
+			p.Images[i].Position = position
+		} else if p.Images[i].Position >= position {
+			p.Images[i].Position++
+		}
+	}
+	renumberImages(p)
+}
+
+// renumberImages orders the images of p by position and numbers them from 1.
+// The first image is the main image of the product.
+func renumberImages(p *goshopify.Product) {
+	sort.SliceStable(p.Images, func(i, j int) bool {
+		return p.Images[i].Position < p.Images[j].Position
+	})
+	for i := range p.Images {
+		p.Images[i].Position = i + 1
+	}
+	p.Image = goshopify.Image{}
+	if len(p.Images) > 0 {
+		p.Image = p.Images[0]
+	}
+}
+
+func findImage(p *goshopify.Product, id int64) *goshopify.Image {
+	for i := range p.Images {
+		if p.Images[i].ID == id {
+			return &p.Images[i]
+		}
+	}
+	return nil
+}
+
+func contains64(s []int64, v int64) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,317 @@
+package shopifytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/shopspring/decimal"
+)
+
+// levelKey identifies the inventory level of an inventory item at a location.
+type levelKey struct {
+	itemID     int64
+	locationID int64
+}
+
+// AddLocations adds active locations with the given names to the store and
+// returns them with their IDs.
+func (s *Server) AddLocations(names ...string) []goshopify.Location {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	added := []goshopify.Location{}
+	for _, name := range names {
+		l := goshopify.Location{
+			ID:     s.nextID(),
+			Name:   name,
+			Active: true,
+		}
+		l.CreatedAt = *s.timestamp()
+		l.UpdatedAt = l.CreatedAt
+		l.AdminGraphqlAPIID = fmt.Sprintf("gid://shopify/Location/%v", l.ID)
+		s.locations = append(s.locations, l)
+		added = append(added, l)
+	}
+	return added
+}
+
+// SetInventoryLevel sets the available quantity of the inventory item at the
+// location, and stocks the item at the location if necessary.
+func (s *Server) SetInventoryLevel(itemID int64, locationID int64, available int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setLevel(itemID, locationID, available)
+}
+
+// InventoryLevels returns all inventory levels of the store ordered by
+// inventory item and location.
+func (s *Server) InventoryLevels() []goshopify.InventoryLevel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sortedLevels()
+}
+
+func (s *Server) setLevel(itemID int64, locationID int64, available int) goshopify.InventoryLevel {
+	k := levelKey{itemID: itemID, locationID: locationID}
+	l, ok := s.levels[k]
+	if !ok {
+		l = goshopify.InventoryLevel{
+			InventoryItemId: itemID,
+			LocationId:      locationID,
+			CreatedAt:       s.timestamp(),
+		}
+	}
+	l.Available = available
+	l.UpdatedAt = s.timestamp()
+	s.levels[k] = l
+	return l
+}
+
+func (s *Server) sortedLevels() []goshopify.InventoryLevel {
+	levels := make([]goshopify.InventoryLevel, 0, len(s.levels))
+	for _, l := range s.levels {
+		levels = append(levels, l)
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if levels[i].InventoryItemId != levels[j].InventoryItemId {
+			return levels[i].InventoryItemId < levels[j].InventoryItemId
+		}
+		return levels[i].LocationId < levels[j].LocationId
+	})
+	return levels
+}
+
+func (s *Server) listLocations() (int, any, error) {
+	return http.StatusOK, map[string]any{"locations": append([]goshopify.Location{}, s.locations...)}, nil
+}
+
+// listInventoryLevels implements GET inventory_levels.json with the
+// inventory_item_ids, location_ids, updated_at_min and limit parameters, and
+// cursor based pagination through page_info.
+func (s *Server) listInventoryLevels(r *http.Request) (int, any, error) {
+	query, offset, limit, err := s.pageQuery(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	levels, err := s.filterLevels(query)
+	if err != nil {
+		return 0, nil, err
+	}
+	if offset > len(levels) {
+		offset = len(levels)
+	}
+	end := offset + limit
+	if end > len(levels) {
+		end = len(levels)
+	}
+	body := map[string]any{"inventory_levels": levels[offset:end]}
+	return http.StatusOK, pageResponse(r, query, limit, end, len(levels), body), nil
+}
+
+// filterLevels returns the inventory levels that match the parameters of
+// query. Either inventory_item_ids or location_ids is required.
+func (s *Server) filterLevels(query url.Values) ([]goshopify.InventoryLevel, error) {
+	itemIDs, err := parseIDs(query, "inventory_item_ids")
+	if err != nil {
+		return nil, err
+	}
+	locationIDs, err := parseIDs(query, "location_ids")
+	if err != nil {
+		return nil, err
+	}
+	if len(itemIDs) == 0 && len(locationIDs) == 0 {
+		return nil, unprocessable("base", "inventory_item_ids or location_ids must be present")
+	}
+	var updatedAtMin time.Time
+	if v := query.Get("updated_at_min"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, badRequest("invalid updated_at_min %q", v)
+		}
+		updatedAtMin = t
+	}
+	levels := []goshopify.InventoryLevel{}
+	for _, l := range s.sortedLevels() {
+		if len(itemIDs) > 0 && !itemIDs[l.InventoryItemId] {
+			continue
+		}
+		if len(locationIDs) > 0 && !locationIDs[l.LocationId] {
+			continue
+		}
+		if l.UpdatedAt.Before(updatedAtMin) {
+			continue
+		}
+		levels = append(levels, l)
+	}
+	return levels, nil
+}
+
+// parseIDs parses the comma separated IDs of the query parameter with the
+// given name.
+func parseIDs(query url.Values, name string) (map[int64]bool, error) {
+	ids := map[int64]bool{}
+	v := query.Get(name)
+	if v == "" {
+		return ids, nil
+	}
+	for _, field := range strings.Split(v, ",") {
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, badRequest("invalid %v %q", name, v)
+		}
+		ids[id] = true
+	}
+	return ids, nil
+}
+
+// levelRequest is the body of the set and adjust requests.
+type levelRequest struct {
+	InventoryItemID     int64 `json:"inventory_item_id"`
+	LocationID          int64 `json:"location_id"`
+	Available           *int  `json:"available"`
+	AvailableAdjustment *int  `json:"available_adjustment"`
+}
+
+// postInventoryLevel implements POST inventory_levels/set.json and
+// inventory_levels/adjust.json.
+func (s *Server) postInventoryLevel(r *http.Request, action string) (int, any, error) {
+	req := levelRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return 0, nil, badRequest("invalid JSON: %v", err)
+	}
+	if !s.itemExists(req.InventoryItemID) || !s.locationExists(req.LocationID) {
+		return 0, nil, notFound()
+	}
+	k := levelKey{itemID: req.InventoryItemID, locationID: req.LocationID}
+	var level goshopify.InventoryLevel
+	switch action {
+	case "set.json":
+		if req.Available == nil {
+			return 0, nil, badRequest("required parameter missing or invalid: available")
+		}
+		level = s.setLevel(k.itemID, k.locationID, *req.Available)
+	case "adjust.json":
+		if req.AvailableAdjustment == nil {
+			return 0, nil, badRequest("required parameter missing or invalid: available_adjustment")
+		}
+		current, ok := s.levels[k]
+		if !ok {
+			return 0, nil, unprocessable("base", "Inventory item does not have inventory tracking enabled at this location")
+		}
+		level = s.setLevel(k.itemID, k.locationID, current.Available+*req.AvailableAdjustment)
+	default:
+		return 0, nil, notFound()
+	}
+	return http.StatusOK, map[string]any{"inventory_level": level}, nil
+}
+
+// itemExists reports whether a variant has the inventory item with the given
+// ID.
+func (s *Server) itemExists(id int64) bool {
+	_, ok := s.lookupItem(id)
+	return ok
+}
+
+func (s *Server) locationExists(id int64) bool {
+	for _, l := range s.locations {
+		if l.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// SetInventoryItemCost sets the cost of the inventory item with the given ID.
+func (s *Server) SetInventoryItemCost(itemID int64, cost decimal.Decimal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.costs[itemID] = cost
+}
+
+// InventoryItems returns the inventory items of all variants ordered by ID.
+func (s *Server) InventoryItems() []goshopify.InventoryItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := []goshopify.InventoryItem{}
+	for _, p := range s.products {
+		for _, v := range p.Variants {
+			items = append(items, s.inventoryItem(v))
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items
+}
+
+// inventoryItem returns the inventory item of v.
+func (s *Server) inventoryItem(v goshopify.Variant) goshopify.InventoryItem {
+	item := goshopify.InventoryItem{ID: v.InventoryItemId, SKU: v.Sku}
+	if cost, ok := s.costs[v.InventoryItemId]; ok {
+		item.Cost = &cost
+	}
+	return item
+}
+
+// lookupItem returns the variant with the inventory item with the given ID.
+func (s *Server) lookupItem(id int64) (goshopify.Variant, bool) {
+	for _, p := range s.products {
+		for _, v := range p.Variants {
+			if v.InventoryItemId == id {
+				return v, true
+			}
+		}
+	}
+	return goshopify.Variant{}, false
+}
+
+// listInventoryItems implements GET inventory_items.json with the required ids
+// parameter, of which at most 100 are accepted.
+func (s *Server) listInventoryItems(r *http.Request) (int, any, error) {
+	ids, err := parseIDs(r.URL.Query(), "ids")
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(ids) == 0 {
+		return 0, nil, badRequest("required parameter missing or invalid: ids")
+	}
+	if len(ids) > 100 {
+		return 0, nil, unprocessable("ids", "must contain at most 100 IDs")
+	}
+	items := []goshopify.InventoryItem{}
+	for _, p := range s.products {
+		for _, v := range p.Variants {
+			if ids[v.InventoryItemId] {
+				items = append(items, s.inventoryItem(v))
+			}
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return http.StatusOK, map[string]any{"inventory_items": items}, nil
+}
+
+// putInventoryItem implements PUT inventory_items/<id>.json. Only the cost can
+// be updated.
+func (s *Server) putInventoryItem(r *http.Request, id int64) (int, any, error) {
+	v, ok := s.lookupItem(id)
+	if !ok {
+		return 0, nil, notFound()
+	}
+	patch := goshopify.InventoryItem{}
+	keys, err := decodeBody(r, "inventory_item", &patch)
+	if err != nil {
+		return 0, nil, err
+	}
+	if _, ok := keys["cost"]; ok {
+		if patch.Cost == nil {
+			delete(s.costs, id)
+		} else {
+			s.costs[id] = *patch.Cost
+		}
+	}
+	return http.StatusOK, map[string]any{"inventory_item": s.inventoryItem(v)}, nil
+}
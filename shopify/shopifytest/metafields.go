@@ -0,0 +1,134 @@
+package shopifytest
+
+import (
+	"fmt"
+	"net/http"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+)
+
+// routeMetafields dispatches requests for the metafields of a product or
+// variant, e.g. products/<id>/metafields.json or
+// variants/<id>/metafields/<id>.json.
+func (s *Server) routeMetafields(r *http.Request, segments []string) (int, any, error) {
+	ownerID, err := parseID(segments[1])
+	if err != nil {
+		return 0, nil, err
+	}
+	owner := "product"
+	// Metafield changes don't update the timestamps of their owners.
+	var metafields *[]goshopify.Metafield
+	if segments[0] == "variants" {
+		owner = "variant"
+		p, i, ok := s.lookupVariant(ownerID)
+		if !ok {
+			return 0, nil, notFound()
+		}
+		metafields = &p.Variants[i].Metafields
+	} else {
+		p, ok := s.products[ownerID]
+		if !ok {
+			return 0, nil, notFound()
+		}
+		metafields = &p.Metafields
+	}
+
+	switch {
+	case len(segments) == 3 && segments[2] == "metafields.json":
+		switch r.Method {
+		case http.MethodGet:
+			return http.StatusOK, map[string]any{"metafields": append([]goshopify.Metafield{}, *metafields...)}, nil
+		case http.MethodPost:
+			m := goshopify.Metafield{}
+			if _, err := decodeBody(r, "metafield", &m); err != nil {
+				return 0, nil, err
+			}
+			created, err := s.addMetafield(metafields, owner, ownerID, m)
+			if err != nil {
+				return 0, nil, err
+			}
+			return http.StatusCreated, map[string]any{"metafield": created}, nil
+		}
+	case len(segments) == 4 && segments[2] == "metafields" && segments[3] == "count.json" && r.Method == http.MethodGet:
+		return http.StatusOK, map[string]any{"count": len(*metafields)}, nil
+	case len(segments) == 4 && segments[2] == "metafields":
+		id, err := parseID(segments[3])
+		if err != nil {
+			return 0, nil, err
+		}
+		i := findMetafield(*metafields, id)
+		if i < 0 {
+			return 0, nil, notFound()
+		}
+		switch r.Method {
+		case http.MethodGet:
+			return http.StatusOK, map[string]any{"metafield": (*metafields)[i]}, nil
+		case http.MethodPut:
+			m := &(*metafields)[i]
+			updated := *m
+			keys, err := decodeBody(r, "metafield", &goshopify.Metafield{})
+			if err != nil {
+				return 0, nil, err
+			}
+			if err := merge(&updated, keys, "id", "namespace", "key", "owner_id", "owner_resource"); err != nil {
+				return 0, nil, err
+			}
+			updated.UpdatedAt = s.timestamp()
+			*m = updated
+			return http.StatusOK, map[string]any{"metafield": updated}, nil
+		case http.MethodDelete:
+			*metafields = append((*metafields)[:i], (*metafields)[i+1:]...)
+			return http.StatusOK, nil, nil
+		}
+	}
+	return 0, nil, notFound()
+}
+
+// addMetafield appends m as a new metafield of the given owner to metafields
+// and returns it.
+func (s *Server) addMetafield(metafields *[]goshopify.Metafield, owner string, ownerID int64, m goshopify.Metafield) (goshopify.Metafield, error) {
+	if m.Namespace == "" || m.Key == "" {
+		return m, unprocessable("metafield", "namespace and key are required")
+	}
+	if m.Type == "" && m.ValueType == "" {
+		return m, unprocessable("type", "can't be blank")
+	}
+	if m.Value == nil {
+		return m, unprocessable("value", "can't be blank")
+	}
+	for _, other := range *metafields {
+		if other.Namespace == m.Namespace && other.Key == m.Key {
+			return m, unprocessable("key", "must be unique within this namespace on this resource")
+		}
+	}
+	m.ID = s.nextID()
+	m.OwnerId = ownerID
+	m.OwnerResource = owner
+	m.CreatedAt = s.timestamp()
+	m.UpdatedAt = m.CreatedAt
+	m.AdminGraphqlAPIID = fmt.Sprintf("gid://shopify/Metafield/%v", m.ID)
+	*metafields = append(*metafields, m)
+	return m, nil
+}
+
+// findMetafield returns the index of the metafield with the given ID, or -1 if
+// there is none.
+func findMetafield(metafields []goshopify.Metafield, id int64) int {
+	for i := range metafields {
+		if metafields[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// findMetafieldByKey returns the index of the metafield with the given
+// namespace and key, or -1 if there is none.
+func findMetafieldByKey(metafields []goshopify.Metafield, namespace string, key string) int {
+	for i := range metafields {
+		if metafields[i].Namespace == namespace && metafields[i].Key == key {
+			return i
+		}
+	}
+	return -1
+}
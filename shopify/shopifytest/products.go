@@ -0,0 +1,532 @@
+package shopifytest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// defaultOptionName and defaultOptionValue are the option of products that
+	// are created without variants.
+	defaultOptionName  = "Title"
+	defaultOptionValue = "Default Title"
+)
+
+// handleRegex matches the characters that are replaced by dashes in handles.
+var handleRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// sortedProducts returns the products ordered by ID.
+func (s *Server) sortedProducts() []*goshopify.Product {
+	products := make([]*goshopify.Product, 0, len(s.products))
+	for _, p := range s.products {
+		products = append(products, p)
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].ID < products[j].ID
+	})
+	return products
+}
+
+// listProducts implements GET products.json with the ids, updated_at_min,
+// fields and limit parameters, and cursor based pagination through page_info.
+func (s *Server) listProducts(r *http.Request) (int, any, error) {
+	query, offset, limit, err := s.pageQuery(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	products, err := s.filterProducts(query)
+	if err != nil {
+		return 0, nil, err
+	}
+	if offset > len(products) {
+		offset = len(products)
+	}
+	end := offset + limit
+	if end > len(products) {
+		end = len(products)
+	}
+	fields := []string{}
+	if v := query.Get("fields"); v != "" {
+		fields = strings.Split(v, ",")
+	}
+	page := []any{}
+	for _, p := range products[offset:end] {
+		v, err := selectFields(s.productResponse(copyProduct(p, false)), fields)
+		if err != nil {
+			return 0, nil, err
+		}
+		page = append(page, v)
+	}
+	return http.StatusOK, pageResponse(r, query, limit, end, len(products), map[string]any{"products": page}), nil
+}
+
+// pageQuery returns the query of the page that r requests, together with the
+// offset and limit of the page.
+func (s *Server) pageQuery(r *http.Request) (url.Values, int, int, error) {
+	query := r.URL.Query()
+	offset := 0
+	if pageInfo := query.Get("page_info"); pageInfo != "" {
+		// The page info encodes the query of the first page together with the
+		// offset of the page. Only the limit can change between pages.
+		b, err := base64.RawURLEncoding.DecodeString(pageInfo)
+		if err != nil {
+			return nil, 0, 0, badRequest("invalid page_info")
+		}
+		pageQuery, err := url.ParseQuery(string(b))
+		if err != nil {
+			return nil, 0, 0, badRequest("invalid page_info")
+		}
+		offset, _ = strconv.Atoi(pageQuery.Get("offset"))
+		pageQuery.Del("offset")
+		if limit := query.Get("limit"); limit != "" {
+			pageQuery.Set("limit", limit)
+		}
+		query = pageQuery
+	}
+	limit := defaultLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, 0, 0, badRequest("invalid limit %q", v)
+		}
+		limit = n
+	}
+	if limit > s.maxLimit {
+		limit = s.maxLimit
+	}
+	return query, offset, limit, nil
+}
+
+// pageResponse returns body together with a Link header to the next page if
+// the page ends before total.
+func pageResponse(r *http.Request, query url.Values, limit int, end int, total int, body any) any {
+	if end >= total {
+		return body
+	}
+	next := url.Values{}
+	for k, v := range query {
+		next[k] = v
+	}
+	next.Set("offset", strconv.Itoa(end))
+	link := url.URL{
+		Scheme: "https",
+		Host:   r.Host,
+		Path:   r.URL.Path,
+		RawQuery: url.Values{
+			"limit":     {strconv.Itoa(limit)},
+			"page_info": {base64.RawURLEncoding.EncodeToString([]byte(next.Encode()))},
+		}.Encode(),
+	}
+	return pagedResponse{
+		link: fmt.Sprintf("<%v>; rel=\"next\"", link.String()),
+		body: body,
+	}
+}
+
+// pagedResponse is a response body together with its Link header.
+type pagedResponse struct {
+	link string
+	body any
+}
+
+// filterProducts returns the products that match the ids and updated_at_min
+// parameters of query.
+func (s *Server) filterProducts(query url.Values) ([]*goshopify.Product, error) {
+	ids := map[int64]bool{}
+	if v := query.Get("ids"); v != "" {
+		for _, field := range strings.Split(v, ",") {
+			id, err := strconv.ParseInt(field, 10, 64)
+			if err != nil {
+				return nil, badRequest("invalid ids %q", v)
+			}
+			ids[id] = true
+		}
+	}
+	var updatedAtMin time.Time
+	if v := query.Get("updated_at_min"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, badRequest("invalid updated_at_min %q", v)
+		}
+		updatedAtMin = t
+	}
+	products := []*goshopify.Product{}
+	for _, p := range s.sortedProducts() {
+		if len(ids) > 0 && !ids[p.ID] {
+			continue
+		}
+		if p.UpdatedAt != nil && p.UpdatedAt.Before(updatedAtMin) {
+			continue
+		}
+		products = append(products, p)
+	}
+	return products, nil
+}
+
+// selectFields returns the JSON object of v reduced to the given fields. All
+// fields are returned if fields is empty.
+func selectFields(v any, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return v, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	all := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+	selected := map[string]json.RawMessage{}
+	for _, f := range fields {
+		if v, ok := all[strings.TrimSpace(f)]; ok {
+			selected[strings.TrimSpace(f)] = v
+		}
+	}
+	return selected, nil
+}
+
+func (s *Server) countProducts(r *http.Request) (int, any, error) {
+	products, err := s.filterProducts(r.URL.Query())
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]any{"count": len(products)}, nil
+}
+
+func (s *Server) getProduct(id int64) (int, any, error) {
+	p, ok := s.products[id]
+	if !ok {
+		return 0, nil, notFound()
+	}
+	return http.StatusOK, map[string]any{"product": s.productResponse(copyProduct(p, false))}, nil
+}
+
+func (s *Server) postProduct(r *http.Request) (int, any, error) {
+	p := goshopify.Product{}
+	if _, err := decodeBody(r, "product", &p); err != nil {
+		return 0, nil, err
+	}
+	created, err := s.createProduct(p)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusCreated, map[string]any{"product": s.productResponse(copyProduct(created, false))}, nil
+}
+
+// createProduct adds p to the store the way the API creates products.
+func (s *Server) createProduct(p goshopify.Product) (*goshopify.Product, error) {
+	if p.Title == "" {
+		return nil, unprocessable("title", "can't be blank")
+	}
+	p.ID = s.nextID()
+	p.CreatedAt = s.timestamp()
+	p.UpdatedAt = p.CreatedAt
+	p.AdminGraphqlAPIID = fmt.Sprintf("gid://shopify/Product/%v", p.ID)
+	if p.Status == "" {
+		p.Status = "active"
+	}
+	if p.Handle == "" {
+		p.Handle = s.uniqueHandle(p.Title)
+	}
+	if len(p.Options) == 0 {
+		p.Options = []goshopify.ProductOption{{Name: defaultOptionName}}
+	}
+	for i := range p.Options {
+		p.Options[i].ID = s.nextID()
+		p.Options[i].ProductID = p.ID
+		p.Options[i].Position = i + 1
+	}
+	if len(p.Variants) == 0 {
+		p.Variants = []goshopify.Variant{{Option1: defaultOptionValue}}
+	}
+	variants := p.Variants
+	p.Variants = nil
+	for _, v := range variants {
+		v, err := s.newVariant(&p, v)
+		if err != nil {
+			return nil, err
+		}
+		p.Variants = append(p.Variants, v)
+	}
+	metafields := p.Metafields
+	p.Metafields = nil
+	for _, m := range metafields {
+		if _, err := s.addMetafield(&p.Metafields, "product", p.ID, m); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.setSEOMetafields(&p); err != nil {
+		return nil, err
+	}
+	p.Tags = normalizeTags(p.Tags)
+	updateOptionValues(&p)
+	images := p.Images
+	p.Images = nil
+	for _, img := range images {
+		if _, err := s.addImage(&p, img); err != nil {
+			return nil, err
+		}
+	}
+	s.products[p.ID] = &p
+	return &p, nil
+}
+
+// setSEOMetafields moves the SEO fields of p into the global metafields of p,
+// which is where the API keeps them.
+func (s *Server) setSEOMetafields(p *goshopify.Product) error {
+	for key, value := range map[string]string{
+		"title_tag":       p.MetafieldsGlobalTitleTag,
+		"description_tag": p.MetafieldsGlobalDescriptionTag,
+	} {
+		if value == "" {
+			continue
+		}
+		if i := findMetafieldByKey(p.Metafields, "global", key); i >= 0 {
+			p.Metafields[i].Value = value
+			p.Metafields[i].UpdatedAt = s.timestamp()
+			continue
+		}
+		m := goshopify.Metafield{Namespace: "global", Key: key, Value: value, Type: "single_line_text_field"}
+		if _, err := s.addMetafield(&p.Metafields, "product", p.ID, m); err != nil {
+			return err
+		}
+	}
+	p.MetafieldsGlobalTitleTag = ""
+	p.MetafieldsGlobalDescriptionTag = ""
+	return nil
+}
+
+// clearSEOMetafields removes the SEO metafields of p of which the SEO fields
+// are empty strings in the request with the given keys.
+func clearSEOMetafields(p *goshopify.Product, keys map[string]json.RawMessage) {
+	for field, key := range map[string]string{
+		"metafields_global_title_tag":       "title_tag",
+		"metafields_global_description_tag": "description_tag",
+	} {
+		if string(keys[field]) != `""` {
+			continue
+		}
+		if i := findMetafieldByKey(p.Metafields, "global", key); i >= 0 {
+			p.Metafields = append(append([]goshopify.Metafield{}, p.Metafields[:i]...), p.Metafields[i+1:]...)
+		}
+	}
+}
+
+// normalizeTags returns the comma separated tags without surrounding
+// whitespace and duplicates, in alphabetical order, as the API does.
+func normalizeTags(s string) string {
+	tags := []string{}
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" && !contains(tags, t) {
+			tags = append(tags, t)
+		}
+	}
+	sort.Strings(tags)
+	return strings.Join(tags, ", ")
+}
+
+// uniqueHandle returns a handle for title that no other product uses.
+func (s *Server) uniqueHandle(title string) string {
+	base := strings.Trim(handleRegex.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	handle := base
+	for i := 1; s.handleExists(handle); i++ {
+		handle = fmt.Sprintf("%v-%v", base, i)
+	}
+	return handle
+}
+
+func (s *Server) handleExists(handle string) bool {
+	for _, p := range s.products {
+		if p.Handle == handle {
+			return true
+		}
+	}
+	return false
+}
+
+// putProduct implements PUT products/<id>.json. Only the fields in the request
+// are updated. If the request contains variants, then they replace the
+// variants of the product: variants with an ID are updated, variants without
+// an ID are created and the remaining variants are deleted. Metafields in the
+// request are added to the product.
+func (s *Server) putProduct(r *http.Request, id int64) (int, any, error) {
+	current, ok := s.products[id]
+	if !ok {
+		return 0, nil, notFound()
+	}
+	patch := goshopify.Product{}
+	keys, err := decodeBody(r, "product", &patch)
+	if err != nil {
+		return 0, nil, err
+	}
+	p := copyProduct(current, true)
+	if _, ok := keys["options"]; ok {
+		p.Options = nil
+	}
+	if err := merge(&p, keys, "id", "variants", "metafields"); err != nil {
+		return 0, nil, err
+	}
+	for i := range p.Options {
+		if i < len(current.Options) {
+			p.Options[i].ID = current.Options[i].ID
+		} else {
+			p.Options[i].ID = s.nextID()
+		}
+		p.Options[i].ProductID = p.ID
+		p.Options[i].Position = i + 1
+	}
+	if raw, ok := keys["variants"]; ok {
+		variants := []map[string]json.RawMessage{}
+		if err := json.Unmarshal(raw, &variants); err != nil {
+			return 0, nil, badRequest("invalid variants: %v", err)
+		}
+		p.Variants = nil
+		for i, v := range patch.Variants {
+			if v.ID == 0 {
+				created, err := s.newVariant(&p, v)
+				if err != nil {
+					return 0, nil, err
+				}
+				p.Variants = append(p.Variants, created)
+				continue
+			}
+			existing := findVariant(current.Variants, v.ID)
+			if existing == nil {
+				return 0, nil, unprocessable("variants", "variant %v does not exist", v.ID)
+			}
+			updated := *existing
+			if err := merge(&updated, variants[i], "id", "product_id", "metafields"); err != nil {
+				return 0, nil, err
+			}
+			updated.UpdatedAt = s.timestamp()
+			p.Variants = append(p.Variants, updated)
+		}
+	}
+	for _, m := range patch.Metafields {
+		if _, err := s.addMetafield(&p.Metafields, "product", p.ID, m); err != nil {
+			return 0, nil, err
+		}
+	}
+	clearSEOMetafields(&p, keys)
+	if err := s.setSEOMetafields(&p); err != nil {
+		return 0, nil, err
+	}
+	p.Tags = normalizeTags(p.Tags)
+	if err := checkVariants(&p); err != nil {
+		return 0, nil, err
+	}
+	updateOptionValues(&p)
+	p.UpdatedAt = s.timestamp()
+	s.products[id] = &p
+	return http.StatusOK, map[string]any{"product": s.productResponse(copyProduct(&p, false))}, nil
+}
+
+func (s *Server) deleteProduct(id int64) (int, any, error) {
+	if _, ok := s.products[id]; !ok {
+		return 0, nil, notFound()
+	}
+	for _, img := range s.products[id].Images {
+		delete(s.alts, img.ID)
+	}
+	delete(s.products, id)
+	return http.StatusOK, nil, nil
+}
+
+// copyProduct returns a deep copy of p. Metafields are only included if
+// withMetafields is true, because the API returns them separately.
+func copyProduct(p *goshopify.Product, withMetafields bool) goshopify.Product {
+	c := *p
+	// Pointers are copied too, so that decoding a request into the copy does
+	// not change p.
+	c.CreatedAt = copyTime(p.CreatedAt)
+	c.UpdatedAt = copyTime(p.UpdatedAt)
+	c.PublishedAt = copyTime(p.PublishedAt)
+	c.Options = nil
+	for _, o := range p.Options {
+		o.Values = append([]string(nil), o.Values...)
+		c.Options = append(c.Options, o)
+	}
+	c.Variants = nil
+	for _, v := range p.Variants {
+		v.Price = copyDecimal(v.Price)
+		v.CompareAtPrice = copyDecimal(v.CompareAtPrice)
+		v.Weight = copyDecimal(v.Weight)
+		v.CreatedAt = copyTime(v.CreatedAt)
+		v.UpdatedAt = copyTime(v.UpdatedAt)
+		v.Metafields = copyMetafields(v.Metafields, withMetafields)
+		c.Variants = append(c.Variants, v)
+	}
+	c.Metafields = copyMetafields(p.Metafields, withMetafields)
+	c.Images = nil
+	for _, img := range p.Images {
+		img.VariantIds = append([]int64(nil), img.VariantIds...)
+		c.Images = append(c.Images, img)
+	}
+	return c
+}
+
+func copyDecimal(d *decimal.Decimal) *decimal.Decimal {
+	if d == nil {
+		return nil
+	}
+	c := *d
+	return &c
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+func copyMetafields(metafields []goshopify.Metafield, include bool) []goshopify.Metafield {
+	if !include {
+		return nil
+	}
+	return append([]goshopify.Metafield(nil), metafields...)
+}
+
+// updateOptionValues sets the values of the options of p to the distinct
+// option values of its variants.
+func updateOptionValues(p *goshopify.Product) {
+	for i := range p.Options {
+		values := []string{}
+		seen := map[string]bool{}
+		for _, v := range p.Variants {
+			value := optionValue(&v, i+1)
+			if value == "" || seen[value] {
+				continue
+			}
+			seen[value] = true
+			values = append(values, value)
+		}
+		p.Options[i].Values = values
+	}
+}
+
+// optionValue returns the value of the option at the given position.
+func optionValue(v *goshopify.Variant, position int) string {
+	switch position {
+	case 1:
+		return v.Option1
+	case 2:
+		return v.Option2
+	case 3:
+		return v.Option3
+	}
+	return ""
+}
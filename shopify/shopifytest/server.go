@@ -0,0 +1,496 @@
+// Package shopifytest provides a fake Shopify store for tests.
+//
+// The fake store implements the parts of the REST Admin API that merchant
+// uses: products, variants, product images, product and variant metafields,
+// custom and smart collections, locations, inventory levels, inventory items,
+// counts, pagination through Link headers and the X-Shopify-Shop-Api-Call-Limit
+// header of the leaky bucket rate limit. Of the GraphQL Admin API, only the
+// metafieldDefinitions query is implemented. Clients reach it through
+// [Server.Transport], which redirects requests for any <store>.myshopify.com
+// host to the fake store:
+//
+//	server := shopifytest.NewServer()
+//	defer server.Close()
+//	client := shopify.NewClient(cfg, shopify.WithTransport(server.Transport()))
+package shopifytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// defaultMaxLimit is the maximum number of resources per page.
+	defaultMaxLimit = 250
+	// defaultLimit is the number of resources per page if the request does not
+	// specify a limit.
+	defaultLimit = 50
+	// defaultBucketSize and defaultLeakRate are the leaky bucket parameters of a
+	// standard store.
+	defaultBucketSize = 40
+	defaultLeakRate   = 2
+	// callLimitHeader is the response header through which Shopify reports the
+	// bucket usage.
+	callLimitHeader = "X-Shopify-Shop-Api-Call-Limit"
+)
+
+// DefaultScopes are the access scopes that the fake store grants by default.
+var DefaultScopes = []string{"read_products", "write_products", "write_inventory", "read_locations"}
+
+// Option configures a Server.
+type Option func(s *Server)
+
+// WithAccessToken requires requests to authenticate with the given access
+// token, either in the X-Shopify-Access-Token header or as the password of
+// basic authentication. Requests are not authenticated by default.
+func WithAccessToken(token string) Option {
+	return func(s *Server) {
+		s.accessToken = token
+	}
+}
+
+// WithMaxLimit sets the maximum number of resources per page, so that
+// pagination can be tested with few products.
+func WithMaxLimit(n int) Option {
+	return func(s *Server) {
+		s.maxLimit = n
+	}
+}
+
+// WithBucket sets the size and leak rate in requests per second of the leaky
+// bucket. Requests that overflow the bucket are rejected with status 429.
+func WithBucket(size int, leakRate float64) Option {
+	return func(s *Server) {
+		s.bucketSize = size
+		s.leakRate = leakRate
+	}
+}
+
+// WithScopes sets the access scopes that the fake store grants.
+func WithScopes(scopes ...string) Option {
+	return func(s *Server) {
+		s.scopes = scopes
+	}
+}
+
+// Server is a fake Shopify store served by an httptest.Server. It is safe for
+// concurrent use.
+type Server struct {
+	*httptest.Server
+
+	accessToken string
+	maxLimit    int
+	bucketSize  int
+	leakRate    float64
+	scopes      []string
+	now         func() time.Time
+
+	mu        sync.Mutex
+	products  map[int64]*goshopify.Product
+	locations []goshopify.Location
+	levels    map[levelKey]goshopify.InventoryLevel
+	costs     map[int64]decimal.Decimal
+	alts      map[int64]string
+	// customCollections and smartCollections are ordered by ID.
+	customCollections []goshopify.CustomCollection
+	smartCollections  []goshopify.SmartCollection
+	definitions       []MetafieldDefinition
+	lastID            int64
+	requests          int
+	// level is the bucket level at leakedAt.
+	level    float64
+	leakedAt time.Time
+}
+
+// NewServer starts and returns a new fake store without any products or
+// locations. The caller should call Close when finished, to shut it down.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		maxLimit:   defaultMaxLimit,
+		bucketSize: defaultBucketSize,
+		leakRate:   defaultLeakRate,
+		scopes:     DefaultScopes,
+		now:        time.Now,
+		products:   map[int64]*goshopify.Product{},
+		levels:     map[levelKey]goshopify.InventoryLevel{},
+		costs:      map[int64]decimal.Decimal{},
+		alts:       map[int64]string{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Transport returns an http.RoundTripper that sends all requests to the fake
+// store, regardless of their host.
+func (s *Server) Transport() http.RoundTripper {
+	u, _ := url.Parse(s.URL)
+	return &transport{url: u, next: s.Client().Transport}
+}
+
+// AddProducts adds products to the store as if they were created through the
+// API, and returns them with their IDs.
+func (s *Server) AddProducts(products ...goshopify.Product) []goshopify.Product {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	added := []goshopify.Product{}
+	for _, p := range products {
+		p, err := s.createProduct(p)
+		if err != nil {
+			panic(err)
+		}
+		added = append(added, copyProduct(p, true))
+	}
+	return added
+}
+
+// Products returns all products of the store ordered by ID, together with the
+// metafields of the products and their variants.
+func (s *Server) Products() []goshopify.Product {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	products := []goshopify.Product{}
+	for _, p := range s.sortedProducts() {
+		products = append(products, copyProduct(p, true))
+	}
+	return products
+}
+
+// Advance moves the clock of the store forward by d, so that changes made
+// after the call get a later update time than those made before it.
+func (s *Server) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now
+	s.now = func() time.Time { return now().Add(d) }
+}
+
+// Requests returns the number of requests that the store has received.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// transport redirects requests to a fixed scheme and host.
+type transport struct {
+	url  *url.URL
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.URL.Scheme = t.url.Scheme
+	r.URL.Host = t.url.Host
+	// The original host is kept so that the Link headers refer to it.
+	r.Host = req.URL.Host
+	return t.next.RoundTrip(r)
+}
+
+// apiError is an error response of the Admin API.
+type apiError struct {
+	status int
+	// errors is a string or a map of field names to messages.
+	errors any
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%v: %v", e.status, e.errors)
+}
+
+func notFound() *apiError {
+	return &apiError{status: http.StatusNotFound, errors: "Not Found"}
+}
+
+func unprocessable(field string, format string, a ...any) *apiError {
+	return &apiError{
+		status: http.StatusUnprocessableEntity,
+		errors: map[string][]string{field: {fmt.Sprintf(format, a...)}},
+	}
+}
+
+func badRequest(format string, a ...any) *apiError {
+	return &apiError{status: http.StatusBadRequest, errors: fmt.Sprintf(format, a...)}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+
+	if !s.authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{
+			"errors": "[API] Invalid API key or access token (unrecognized login or wrong password)",
+		})
+		return
+	}
+	if !s.reserve(w) {
+		w.Header().Set("Retry-After", "1.0")
+		writeJSON(w, http.StatusTooManyRequests, map[string]any{
+			"errors": "Exceeded 2 calls per second for api client. Reduce request rates to resume uninterrupted service.",
+		})
+		return
+	}
+	status, body, err := s.route(r)
+	if err != nil {
+		if e, ok := err.(*apiError); ok {
+			writeJSON(w, e.status, map[string]any{"errors": e.errors})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"errors": err.Error()})
+		return
+	}
+	if paged, ok := body.(pagedResponse); ok {
+		w.Header().Set("Link", paged.link)
+		body = paged.body
+	}
+	if body == nil {
+		body = map[string]any{}
+	}
+	writeJSON(w, status, body)
+}
+
+// authorized reports whether r carries the access token, if one is required.
+func (s *Server) authorized(r *http.Request) bool {
+	if s.accessToken == "" {
+		return true
+	}
+	if r.Header.Get("X-Shopify-Access-Token") == s.accessToken {
+		return true
+	}
+	_, password, ok := r.BasicAuth()
+	return ok && password == s.accessToken
+}
+
+// reserve adds a request to the leaky bucket and sets the call limit header. It
+// returns false if the bucket is full.
+func (s *Server) reserve(w http.ResponseWriter) bool {
+	now := s.now()
+	if !s.leakedAt.IsZero() {
+		s.level = math.Max(0, s.level-now.Sub(s.leakedAt).Seconds()*s.leakRate)
+	}
+	s.leakedAt = now
+	if s.level+1 > float64(s.bucketSize) {
+		return false
+	}
+	s.level++
+	w.Header().Set(callLimitHeader, fmt.Sprintf("%v/%v", int(math.Ceil(s.level)), s.bucketSize))
+	return true
+}
+
+// route dispatches r to the handler of its path and returns the status and
+// body of the response.
+func (s *Server) route(r *http.Request) (int, any, error) {
+	path, ok := resourcePath(r.URL.Path)
+	if !ok {
+		return 0, nil, notFound()
+	}
+	segments := strings.Split(path, "/")
+	method := r.Method
+	switch {
+	case path == "shop.json" && method == http.MethodGet:
+		return http.StatusOK, s.shop(r), nil
+	case path == "oauth/access_scopes.json" && method == http.MethodGet:
+		return http.StatusOK, s.accessScopes(), nil
+	case path == "products.json" && method == http.MethodGet:
+		return s.listProducts(r)
+	case path == "products.json" && method == http.MethodPost:
+		return s.postProduct(r)
+	case path == "products/count.json" && method == http.MethodGet:
+		return s.countProducts(r)
+	case segments[0] == "products" && len(segments) == 2:
+		id, err := parseID(segments[1])
+		if err != nil {
+			return 0, nil, err
+		}
+		switch method {
+		case http.MethodGet:
+			return s.getProduct(id)
+		case http.MethodPut:
+			return s.putProduct(r, id)
+		case http.MethodDelete:
+			return s.deleteProduct(id)
+		}
+	case segments[0] == "products" && len(segments) == 3 && segments[2] == "variants.json":
+		id, err := parseID(segments[1])
+		if err != nil {
+			return 0, nil, err
+		}
+		switch method {
+		case http.MethodGet:
+			return s.listVariants(id)
+		case http.MethodPost:
+			return s.postVariant(r, id)
+		}
+	case segments[0] == "products" && len(segments) == 4 && segments[2] == "variants" && method == http.MethodDelete:
+		productID, err := parseID(segments[1])
+		if err != nil {
+			return 0, nil, err
+		}
+		variantID, err := parseID(segments[3])
+		if err != nil {
+			return 0, nil, err
+		}
+		return s.deleteVariant(productID, variantID)
+	case segments[0] == "variants" && len(segments) == 2:
+		id, err := parseID(segments[1])
+		if err != nil {
+			return 0, nil, err
+		}
+		switch method {
+		case http.MethodGet:
+			return s.getVariant(id)
+		case http.MethodPut:
+			return s.putVariant(r, id)
+		}
+	case segments[0] == "products" && len(segments) >= 3 && strings.HasPrefix(segments[2], "images"):
+		return s.routeImages(r, segments)
+	case (segments[0] == "products" || segments[0] == "variants") && len(segments) >= 3 && strings.HasPrefix(segments[2], "metafields"):
+		return s.routeMetafields(r, segments)
+	case path == "custom_collections.json" && method == http.MethodGet:
+		return s.listCollections(r, "custom_collections")
+	case path == "smart_collections.json" && method == http.MethodGet:
+		return s.listCollections(r, "smart_collections")
+	case path == "graphql.json" && method == http.MethodPost:
+		return s.postGraphQL(r)
+	case path == "locations.json" && method == http.MethodGet:
+		return s.listLocations()
+	case path == "inventory_levels.json" && method == http.MethodGet:
+		return s.listInventoryLevels(r)
+	case segments[0] == "inventory_levels" && len(segments) == 2 && method == http.MethodPost:
+		return s.postInventoryLevel(r, segments[1])
+	case path == "inventory_items.json" && method == http.MethodGet:
+		return s.listInventoryItems(r)
+	case segments[0] == "inventory_items" && len(segments) == 2 && method == http.MethodPut:
+		id, err := parseID(segments[1])
+		if err != nil {
+			return 0, nil, err
+		}
+		return s.putInventoryItem(r, id)
+	}
+	return 0, nil, notFound()
+}
+
+// resourcePath returns the path of a request relative to the versioned or
+// unversioned Admin API prefix, e.g. "products.json" for
+// "/admin/api/2023-04/products.json".
+func resourcePath(p string) (string, bool) {
+	rest, ok := strings.CutPrefix(p, "/admin/")
+	if !ok {
+		return "", false
+	}
+	if after, ok := strings.CutPrefix(rest, "api/"); ok {
+		_, rest, ok = strings.Cut(after, "/")
+		if !ok {
+			return "", false
+		}
+	}
+	return rest, true
+}
+
+// parseID parses a path segment such as "123" or "123.json".
+func parseID(segment string) (int64, error) {
+	id, err := strconv.ParseInt(strings.TrimSuffix(segment, ".json"), 10, 64)
+	if err != nil {
+		return 0, notFound()
+	}
+	return id, nil
+}
+
+func (s *Server) shop(r *http.Request) map[string]any {
+	name, _, _ := strings.Cut(r.Host, ".")
+	return map[string]any{"shop": goshopify.Shop{
+		Name:            name,
+		MyshopifyDomain: r.Host,
+	}}
+}
+
+func (s *Server) accessScopes() map[string]any {
+	scopes := []goshopify.AccessScope{}
+	for _, handle := range s.scopes {
+		scopes = append(scopes, goshopify.AccessScope{Handle: handle})
+	}
+	return map[string]any{"access_scopes": scopes}
+}
+
+// nextID returns a new unique ID for any resource.
+func (s *Server) nextID() int64 {
+	s.lastID++
+	return s.lastID
+}
+
+// timestamp returns the current time at the resolution of the API.
+func (s *Server) timestamp() *time.Time {
+	t := s.now().UTC().Truncate(time.Second)
+	return &t
+}
+
+// decodeBody decodes the resource with the given name from the body of r,
+// e.g. the product of {"product": {...}}. The keys of the resource are
+// returned so that fields that are set to zero values can be told apart from
+// fields that are not set.
+func decodeBody(r *http.Request, name string, v any) (map[string]json.RawMessage, error) {
+	body := map[string]json.RawMessage{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, badRequest("invalid JSON: %v", err)
+	}
+	raw, ok := body[name]
+	if !ok {
+		return nil, badRequest("required parameter missing or invalid: %v", name)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return nil, badRequest("invalid %v: %v", name, err)
+	}
+	keys := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return nil, badRequest("invalid %v: %v", name, err)
+	}
+	return keys, nil
+}
+
+// merge sets the fields of the struct that dst points to from the JSON object
+// fields, ignoring the keys in skip. Fields that are not in fields keep their
+// value.
+func merge(dst any, fields map[string]json.RawMessage, skip ...string) error {
+	for key, raw := range fields {
+		if contains(skip, key) {
+			continue
+		}
+		b, err := json.Marshal(map[string]json.RawMessage{key: raw})
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, dst); err != nil {
+			return badRequest("invalid %v: %v", key, err)
+		}
+	}
+	return nil
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,175 @@
+package shopifytest
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/shopspring/decimal"
+)
+
+// newClient returns a goshopify.Client for the fake store.
+func newClient(s *Server, token string) *goshopify.Client {
+	return goshopify.NewClient(
+		goshopify.App{},
+		"foo",
+		token,
+		goshopify.WithHTTPClient(&http.Client{Transport: s.Transport()}),
+		goshopify.WithVersion("2023-04"),
+	)
+}
+
+func TestServer_products(t *testing.T) {
+	s := NewServer(WithMaxLimit(2))
+	defer s.Close()
+	added := s.AddProducts(
+		goshopify.Product{Title: "Hat"},
+		goshopify.Product{
+			Title:   "Shirt",
+			Options: []goshopify.ProductOption{{Name: "Size"}},
+			Variants: []goshopify.Variant{
+				{Option1: "S", Sku: "SHIRT-S"},
+				{Option1: "M", Sku: "SHIRT-M"},
+			},
+		},
+		goshopify.Product{Title: "Socks"},
+	)
+	client := newClient(s, "")
+
+	t.Run("paginates products", func(t *testing.T) {
+		options := &goshopify.ListOptions{Limit: 250}
+		products := []goshopify.Product{}
+		pages := 0
+		for {
+			page, pagination, err := client.Product.ListWithPagination(options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pages++
+			products = append(products, page...)
+			if pagination.NextPageOptions == nil {
+				break
+			}
+			options = pagination.NextPageOptions
+		}
+		if pages != 2 || len(products) != 3 {
+			t.Fatalf("got %v products in %v pages, want 3 in 2", len(products), pages)
+		}
+		if products[1].Handle != "shirt" || products[1].Options[0].Values[1] != "M" {
+			t.Fatalf("got product %+v", products[1])
+		}
+	})
+
+	t.Run("creates products with default variant", func(t *testing.T) {
+		if v := added[0].Variants; len(v) != 1 || v[0].Title != "Default Title" {
+			t.Fatalf("got variants %+v", v)
+		}
+	})
+
+	t.Run("rejects duplicate variants", func(t *testing.T) {
+		_, err := client.Variant.Create(added[1].ID, goshopify.Variant{Option1: "S"})
+		responseErr := goshopify.ResponseError{}
+		if !errors.As(err, &responseErr) || responseErr.Status != http.StatusUnprocessableEntity {
+			t.Fatalf("got error %v, want status 422", err)
+		}
+	})
+
+	t.Run("updates only given fields", func(t *testing.T) {
+		price := decimal.NewFromInt(5)
+		id := added[1].Variants[0].ID
+		if _, err := client.Variant.Update(goshopify.Variant{ID: id, Price: &price}); err != nil {
+			t.Fatal(err)
+		}
+		v, err := client.Variant.Get(id, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Sku != "SHIRT-S" || !v.Price.Equal(price) {
+			t.Fatalf("got variant %+v", v)
+		}
+	})
+
+	t.Run("manages metafields", func(t *testing.T) {
+		id := added[1].Variants[1].ID
+		m, err := client.Variant.CreateMetafield(id, goshopify.Metafield{
+			Namespace: "custom",
+			Key:       "fit",
+			Value:     "slim",
+			Type:      "single_line_text_field",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Variant.UpdateMetafield(id, goshopify.Metafield{ID: m.ID, Value: "loose"}); err != nil {
+			t.Fatal(err)
+		}
+		metafields, err := client.Variant.ListMetafields(id, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(metafields) != 1 || metafields[0].Value != "loose" || metafields[0].OwnerResource != "variant" {
+			t.Fatalf("got metafields %+v", metafields)
+		}
+		if err := client.Variant.DeleteMetafield(id, m.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got := s.Products()[1].Variants[1].Metafields; len(got) != 0 {
+			t.Fatalf("got metafields %+v after delete", got)
+		}
+	})
+
+	t.Run("counts products", func(t *testing.T) {
+		count, err := client.Product.Count(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 3 {
+			t.Fatalf("got count %v, want 3", count)
+		}
+	})
+}
+
+func TestServer_authentication(t *testing.T) {
+	s := NewServer(WithAccessToken("secret"))
+	defer s.Close()
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "should accept access token", token: "secret"},
+		{name: "should reject wrong access token", token: "wrong", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newClient(s, tt.token).Shop.Get(nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServer_rateLimit(t *testing.T) {
+	s := NewServer(WithBucket(2, 0))
+	defer s.Close()
+	client := &http.Client{Transport: s.Transport()}
+
+	want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}
+	wantHeader := []string{"1/2", "2/2", ""}
+	for i := range want {
+		resp, err := client.Get("https://foo.myshopify.com/admin/api/2023-04/products/count.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want[i] {
+			t.Fatalf("request %v: got status %v, want %v", i, resp.StatusCode, want[i])
+		}
+		if got := resp.Header.Get(callLimitHeader); got != wantHeader[i] {
+			t.Fatalf("request %v: got call limit %q, want %q", i, got, wantHeader[i])
+		}
+	}
+}
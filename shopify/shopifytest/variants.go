@@ -0,0 +1,205 @@
+package shopifytest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/shopspring/decimal"
+)
+
+// newVariant returns v as a new variant of p. The variant is not appended to
+// p. Metafields of v are created together with the variant.
+func (s *Server) newVariant(p *goshopify.Product, v goshopify.Variant) (goshopify.Variant, error) {
+	v.ID = s.nextID()
+	v.ProductID = p.ID
+	v.InventoryItemId = s.nextID()
+	v.CreatedAt = s.timestamp()
+	v.UpdatedAt = v.CreatedAt
+	v.AdminGraphqlAPIID = fmt.Sprintf("gid://shopify/ProductVariant/%v", v.ID)
+	if v.Option1 == "" {
+		v.Option1 = defaultOptionValue
+	}
+	if v.InventoryPolicy == "" {
+		v.InventoryPolicy = "deny"
+	}
+	if v.FulfillmentService == "" {
+		v.FulfillmentService = "manual"
+	}
+	// New variants are taxable and require shipping by default. goshopify
+	// omits false values, so they can only be cleared by an update.
+	v.Taxable = true
+	v.RequireShipping = true
+	// The API always returns a price and weight.
+	if v.Price == nil {
+		v.Price = copyDecimal(&decimal.Zero)
+	}
+	if v.Weight == nil {
+		v.Weight = copyDecimal(&decimal.Zero)
+	}
+	if v.WeightUnit == "" {
+		v.WeightUnit = "kg"
+	}
+	for _, other := range p.Variants {
+		if sameOptions(&other, &v) {
+			return v, unprocessable("base", "The variant '%v' already exists.", variantTitle(&v))
+		}
+	}
+	metafields := v.Metafields
+	v.Metafields = nil
+	for _, m := range metafields {
+		if _, err := s.addMetafield(&v.Metafields, "variant", v.ID, m); err != nil {
+			return v, err
+		}
+	}
+	v.Title = variantTitle(&v)
+	v.Position = len(p.Variants) + 1
+	return v, nil
+}
+
+// checkVariants returns an error if two variants of p have the same options.
+// It also renumbers the positions and titles of the variants.
+func checkVariants(p *goshopify.Product) error {
+	for i := range p.Variants {
+		v := &p.Variants[i]
+		for j := 0; j < i; j++ {
+			if sameOptions(&p.Variants[j], v) {
+				return unprocessable("base", "The variant '%v' already exists.", variantTitle(v))
+			}
+		}
+		v.Position = i + 1
+		v.Title = variantTitle(v)
+	}
+	return nil
+}
+
+func sameOptions(a *goshopify.Variant, b *goshopify.Variant) bool {
+	return a.Option1 == b.Option1 && a.Option2 == b.Option2 && a.Option3 == b.Option3
+}
+
+// variantTitle returns the title of v, which consists of its option values.
+func variantTitle(v *goshopify.Variant) string {
+	values := []string{}
+	for _, o := range []string{v.Option1, v.Option2, v.Option3} {
+		if o != "" {
+			values = append(values, o)
+		}
+	}
+	return strings.Join(values, " / ")
+}
+
+func findVariant(variants []goshopify.Variant, id int64) *goshopify.Variant {
+	for i := range variants {
+		if variants[i].ID == id {
+			return &variants[i]
+		}
+	}
+	return nil
+}
+
+// lookupVariant returns the product and index of the variant with the given
+// ID.
+func (s *Server) lookupVariant(id int64) (*goshopify.Product, int, bool) {
+	for _, p := range s.products {
+		for i := range p.Variants {
+			if p.Variants[i].ID == id {
+				return p, i, true
+			}
+		}
+	}
+	return nil, 0, false
+}
+
+func (s *Server) listVariants(productID int64) (int, any, error) {
+	p, ok := s.products[productID]
+	if !ok {
+		return 0, nil, notFound()
+	}
+	return http.StatusOK, map[string]any{"variants": copyProduct(p, false).Variants}, nil
+}
+
+func (s *Server) getVariant(id int64) (int, any, error) {
+	p, i, ok := s.lookupVariant(id)
+	if !ok {
+		return 0, nil, notFound()
+	}
+	return http.StatusOK, map[string]any{"variant": copyProduct(p, false).Variants[i]}, nil
+}
+
+func (s *Server) postVariant(r *http.Request, productID int64) (int, any, error) {
+	p, ok := s.products[productID]
+	if !ok {
+		return 0, nil, notFound()
+	}
+	v := goshopify.Variant{}
+	if _, err := decodeBody(r, "variant", &v); err != nil {
+		return 0, nil, err
+	}
+	created, err := s.newVariant(p, v)
+	if err != nil {
+		return 0, nil, err
+	}
+	p.Variants = append(p.Variants, created)
+	updateOptionValues(p)
+	p.UpdatedAt = s.timestamp()
+	created.Metafields = nil
+	return http.StatusCreated, map[string]any{"variant": created}, nil
+}
+
+// putVariant implements PUT variants/<id>.json. Only the fields in the request
+// are updated. Metafields in the request are added to the variant.
+func (s *Server) putVariant(r *http.Request, id int64) (int, any, error) {
+	p, i, ok := s.lookupVariant(id)
+	if !ok {
+		return 0, nil, notFound()
+	}
+	patch := goshopify.Variant{}
+	keys, err := decodeBody(r, "variant", &patch)
+	if err != nil {
+		return 0, nil, err
+	}
+	updated := copyProduct(p, true)
+	v := &updated.Variants[i]
+	if err := merge(v, keys, "id", "product_id", "metafields"); err != nil {
+		return 0, nil, err
+	}
+	for _, m := range patch.Metafields {
+		if _, err := s.addMetafield(&v.Metafields, "variant", v.ID, m); err != nil {
+			return 0, nil, err
+		}
+	}
+	if err := checkVariants(&updated); err != nil {
+		return 0, nil, err
+	}
+	v.UpdatedAt = s.timestamp()
+	updateOptionValues(&updated)
+	updated.UpdatedAt = v.UpdatedAt
+	s.products[p.ID] = &updated
+	result := *v
+	result.Metafields = nil
+	return http.StatusOK, map[string]any{"variant": result}, nil
+}
+
+func (s *Server) deleteVariant(productID int64, id int64) (int, any, error) {
+	p, ok := s.products[productID]
+	if !ok {
+		return 0, nil, notFound()
+	}
+	variants := []goshopify.Variant{}
+	for _, v := range p.Variants {
+		if v.ID != id {
+			variants = append(variants, v)
+		}
+	}
+	if len(variants) == len(p.Variants) {
+		return 0, nil, notFound()
+	}
+	p.Variants = variants
+	if err := checkVariants(p); err != nil {
+		return 0, nil, err
+	}
+	updateOptionValues(p)
+	p.UpdatedAt = s.timestamp()
+	return http.StatusOK, nil, nil
+}
@@ -0,0 +1,350 @@
+package shopify
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	goshopify "github.com/bold-commerce/go-shopify/v3"
+	"github.com/samherrmann/merchant/memdb"
+	"github.com/shopspring/decimal"
+)
+
+type InventoryLevelService = goshopify.InventoryLevelService
+type InventoryLevel = goshopify.InventoryLevel
+type InventoryItemService = goshopify.InventoryItemService
+type InventoryItem = goshopify.InventoryItem
+type Location = goshopify.Location
+
+const (
+	// resourceInventory is the name of the inventory level resource in step
+	// keys.
+	resourceInventory = "inventory"
+	// resourceInventoryItem is the name of the inventory item resource in step
+	// keys.
+	resourceInventoryItem = "inventory_item"
+)
+
+// GetLocations returns all locations of the store.
+func (c *Client) GetLocations() ([]Location, error) {
+	locations, err := c.Location.List(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get locations: %w", err)
+	}
+	return locations, nil
+}
+
+// GetInventoryLevels returns the inventory levels of all inventory items at the
+// given locations. If t is not the zero time, then only the levels that have
+// been updated at or after t are returned.
+func (c *Client) GetInventoryLevels(locationIDs []int64, t time.Time) ([]InventoryLevel, error) {
+	levels := []InventoryLevel{}
+	// 50 is the maximum number of location IDs per request.
+	for start := 0; start < len(locationIDs); start += 50 {
+		end := start + 50
+		if end > len(locationIDs) {
+			end = len(locationIDs)
+		}
+		chunk, err := listInventoryLevels(c.Client, &goshopify.InventoryLevelListOptions{
+			LocationIds:  locationIDs[start:end],
+			UpdatedAtMin: t,
+		})
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, chunk...)
+	}
+	return levels, nil
+}
+
+// GetInventoryItems returns the inventory items with the given IDs.
+func (c *Client) GetInventoryItems(ids []int64) ([]InventoryItem, error) {
+	items := []InventoryItem{}
+	// 100 is the maximum number of IDs per request.
+	for start := 0; start < len(ids); start += 100 {
+		end := start + 100
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunk, err := c.InventoryItem.List(&goshopify.ListOptions{IDs: ids[start:end], Limit: 250})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get inventory items: %w", err)
+		}
+		items = append(items, chunk...)
+	}
+	return items, nil
+}
+
+// GetStock returns all locations of the store together with the inventory
+// levels at these locations and the inventory items with the given IDs.
+func (c *Client) GetStock(itemIDs []int64) (*memdb.Stock, error) {
+	locations, err := c.GetLocations()
+	if err != nil {
+		return nil, err
+	}
+	levels, err := c.GetInventoryLevels(LocationIDs(locations), time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	items, err := c.GetInventoryItems(itemIDs)
+	if err != nil {
+		return nil, err
+	}
+	return &memdb.Stock{Locations: locations, Levels: levels, Items: items}, nil
+}
+
+// LocationIDs returns the IDs of the given locations.
+func LocationIDs(locations []Location) []int64 {
+	ids := []int64{}
+	for _, l := range locations {
+		ids = append(ids, l.ID)
+	}
+	return ids
+}
+
+// InventoryItemIDs returns the inventory item IDs of the variants of the given
+// products.
+func InventoryItemIDs(products []Product) []int64 {
+	ids := []int64{}
+	for _, p := range products {
+		for _, v := range p.Variants {
+			if v.InventoryItemId != 0 {
+				ids = append(ids, v.InventoryItemId)
+			}
+		}
+	}
+	return ids
+}
+
+// listInventoryLevels returns all inventory levels that match options, following
+// the pagination of the results.
+func listInventoryLevels(client *goshopify.Client, options *goshopify.InventoryLevelListOptions) ([]InventoryLevel, error) {
+	// 250 is the maximum limit.
+	options.Limit = 250
+	levels := []InventoryLevel{}
+	var pageOptions any = options
+	for {
+		resource := &goshopify.InventoryLevelsResource{}
+		pagination, err := client.ListWithPagination("inventory_levels.json", resource, pageOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get packet of inventory levels: %w", err)
+		}
+		levels = append(levels, resource.InventoryLevels...)
+		if pagination == nil || pagination.NextPageOptions == nil {
+			break
+		}
+		// The filters of the first page are encoded in the page info.
+		pageOptions = pagination.NextPageOptions
+	}
+	return levels, nil
+}
+
+// updateInventory sets the available quantity of u, or adjusts it. The store
+// has no conditional set, so before a quantity is set, the current quantity is
+// compared with the quantity that u was planned against. An error wrapping
+// ErrDrift is returned if they differ, e.g. because of sales since the plan.
+func updateInventory(service InventoryLevelService, u memdb.InventoryUpdate) error {
+	if u.Available != nil {
+		if err := checkInventoryLevel(service, u); err != nil {
+			return err
+		}
+		_, err := service.Set(InventoryLevel{
+			InventoryItemId: u.InventoryItemID,
+			LocationId:      u.LocationID,
+			Available:       *u.Available,
+		})
+		return err
+	}
+	_, err := service.Adjust(goshopify.InventoryLevelAdjustOptions{
+		InventoryItemId: u.InventoryItemID,
+		LocationId:      u.LocationID,
+		Adjust:          u.Adjustment,
+	})
+	return err
+}
+
+// checkInventoryLevel returns an error wrapping ErrDrift if the available
+// quantity of the inventory item of u at its location differs from u.Before.
+func checkInventoryLevel(service InventoryLevelService, u memdb.InventoryUpdate) error {
+	levels, err := service.List(&goshopify.InventoryLevelListOptions{
+		InventoryItemIds: []int64{u.InventoryItemID},
+		LocationIds:      []int64{u.LocationID},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get inventory level: %w", err)
+	}
+	if len(levels) == 0 {
+		return compareInventoryLevel(u, nil)
+	}
+	return compareInventoryLevel(u, &levels[0])
+}
+
+// compareInventoryLevel returns an error wrapping ErrDrift if the available
+// quantity of level differs from u.Before. level is nil if the inventory item
+// of u is not stocked at its location.
+func compareInventoryLevel(u memdb.InventoryUpdate, level *InventoryLevel) error {
+	switch {
+	case level == nil && u.Before == nil:
+		return nil
+	case level == nil:
+		return fmt.Errorf("%w: %v at %v is no longer stocked", ErrDrift, u.Title, u.Location)
+	case u.Before == nil:
+		return fmt.Errorf("%w: %v at %v is stocked with %v", ErrDrift, u.Title, u.Location, level.Available)
+	case level.Available != *u.Before:
+		return fmt.Errorf("%w: %v at %v changed from %v to %v", ErrDrift, u.Title, u.Location, *u.Before, level.Available)
+	}
+	return nil
+}
+
+// CheckInventory returns an error wrapping ErrDrift if the available quantity
+// of any inventory update of operations that sets the quantity, or the cost of
+// any inventory item update, differs from the value that the update was
+// planned against. Adjustments are not checked because they keep the changes
+// in the store.
+func (c *Client) CheckInventory(operations *memdb.Operations) error {
+	levelErrs, err := c.checkInventoryLevels(operations.InventoryUpdates)
+	if err != nil {
+		return err
+	}
+	costErrs, err := c.checkInventoryCosts(operations.InventoryItemUpdates)
+	if err != nil {
+		return err
+	}
+	return errors.Join(append(levelErrs, costErrs...)...)
+}
+
+// checkInventoryLevels compares the updates that set the available quantity
+// with the current inventory levels and returns an error for every update
+// that has drifted.
+func (c *Client) checkInventoryLevels(updates []memdb.InventoryUpdate) ([]error, error) {
+	type levelKey struct{ itemID, locationID int64 }
+	items := map[int64]bool{}
+	locations := map[int64]bool{}
+	itemIDs := []int64{}
+	locationIDs := []int64{}
+	for _, u := range updates {
+		if u.Available == nil {
+			continue
+		}
+		if !items[u.InventoryItemID] {
+			items[u.InventoryItemID] = true
+			itemIDs = append(itemIDs, u.InventoryItemID)
+		}
+		if !locations[u.LocationID] {
+			locations[u.LocationID] = true
+			locationIDs = append(locationIDs, u.LocationID)
+		}
+	}
+	levels := map[levelKey]InventoryLevel{}
+	// 50 is the maximum number of inventory item and location IDs per request.
+	for start := 0; start < len(itemIDs); start += 50 {
+		end := start + 50
+		if end > len(itemIDs) {
+			end = len(itemIDs)
+		}
+		for locStart := 0; locStart < len(locationIDs); locStart += 50 {
+			locEnd := locStart + 50
+			if locEnd > len(locationIDs) {
+				locEnd = len(locationIDs)
+			}
+			chunk, err := listInventoryLevels(c.Client, &goshopify.InventoryLevelListOptions{
+				InventoryItemIds: itemIDs[start:end],
+				LocationIds:      locationIDs[locStart:locEnd],
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, l := range chunk {
+				levels[levelKey{l.InventoryItemId, l.LocationId}] = l
+			}
+		}
+	}
+	errs := []error{}
+	for _, u := range updates {
+		if u.Available == nil {
+			continue
+		}
+		var level *InventoryLevel
+		if l, ok := levels[levelKey{u.InventoryItemID, u.LocationID}]; ok {
+			level = &l
+		}
+		if err := compareInventoryLevel(u, level); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs, nil
+}
+
+// checkInventoryCosts compares the updates with the current costs of their
+// inventory items and returns an error for every update that has drifted.
+func (c *Client) checkInventoryCosts(updates []memdb.InventoryItemUpdate) ([]error, error) {
+	if len(updates) == 0 {
+		return nil, nil
+	}
+	ids := []int64{}
+	for _, u := range updates {
+		ids = append(ids, u.ID)
+	}
+	items, err := c.GetInventoryItems(ids)
+	if err != nil {
+		return nil, err
+	}
+	costs := map[int64]*decimal.Decimal{}
+	for _, item := range items {
+		costs[item.ID] = item.Cost
+	}
+	errs := []error{}
+	for _, u := range updates {
+		cost := costs[u.ID]
+		if !equalCosts(cost, u.Before) {
+			errs = append(errs, fmt.Errorf("%w: cost of %v changed from %v to %v", ErrDrift, u.Title, formatCost(u.Before), formatCost(cost)))
+		}
+	}
+	return errs, nil
+}
+
+// equalCosts reports whether a and b are both nil or the same amount.
+func equalCosts(a *decimal.Decimal, b *decimal.Decimal) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// formatCost returns the string representation of cost, or "none" if cost is
+// nil.
+func formatCost(cost *decimal.Decimal) string {
+	if cost == nil {
+		return "none"
+	}
+	return cost.String()
+}
+
+// inventoryKey returns the key of the step that updates the inventory level of
+// u.
+func inventoryKey(u memdb.InventoryUpdate) string {
+	return fmt.Sprintf("%v.update.%v.%v", resourceInventory, u.InventoryItemID, u.LocationID)
+}
+
+// updateInventoryItem sets the cost of the inventory item of u.
+func updateInventoryItem(service InventoryItemService, u memdb.InventoryItemUpdate) error {
+	cost := u.Cost
+	_, err := service.Update(InventoryItem{ID: u.ID, Cost: &cost})
+	return err
+}
+
+// resetVariant sets the fields of r through a raw request because variant
+// updates omit false and null values.
+func resetVariant(raw RawClient, r memdb.VariantReset) error {
+	variant := map[string]any{"id": r.ID}
+	for name, value := range r.Fields {
+		variant[name] = value
+	}
+	return raw.Put(fmt.Sprintf("variants/%v.json", r.ID), map[string]any{"variant": variant}, nil)
+}
+
+// resetKey returns the key of the step that resets fields of the resource with
+// the given ID, e.g. a product or a variant.
+func resetKey(resource string, id int64) string {
+	return fmt.Sprintf("%v.reset.%v", resource, id)
+}
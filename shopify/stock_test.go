@@ -0,0 +1,176 @@
+package shopify
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/samherrmann/merchant/memdb"
+	"github.com/shopspring/decimal"
+)
+
+func TestClient_GetStock(t *testing.T) {
+	client, server := newTestClient(t,
+		Product{Title: "Shirt", Options: []ProductOption{{Name: "Size"}}, Variants: []Variant{
+			{Option1: "S"},
+			{Option1: "M"},
+		}},
+	)
+	locations := server.AddLocations("Warehouse", "Shop")
+	variants := server.Products()[0].Variants
+	server.SetInventoryLevel(variants[0].InventoryItemId, locations[0].ID, 3)
+	server.SetInventoryLevel(variants[1].InventoryItemId, locations[0].ID, 4)
+	server.SetInventoryLevel(variants[1].InventoryItemId, locations[1].ID, 5)
+	cost := decimal.NewFromFloat(2.5)
+	server.SetInventoryItemCost(variants[1].InventoryItemId, cost)
+
+	stock, err := client.GetStock([]int64{variants[0].InventoryItemId, variants[1].InventoryItemId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item, ok := stock.Item(variants[1].InventoryItemId); !ok || !item.Cost.Equal(cost) {
+		t.Fatalf("got inventory items %+v", stock.Items)
+	}
+	if len(stock.Locations) != 2 || stock.Locations[1].Name != "Shop" {
+		t.Fatalf("got locations %+v", stock.Locations)
+	}
+	// The levels span two pages.
+	got := []int{}
+	for _, l := range stock.Levels {
+		got = append(got, l.Available)
+	}
+	if want := []int{3, 4, 5}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got available quantities %v, want %v", got, want)
+	}
+}
+
+func TestClient_ApplyOperations_inventory(t *testing.T) {
+	client, server := newTestClient(t, Product{Title: "Hat"})
+	locations := server.AddLocations("Warehouse", "Shop")
+	item := server.Products()[0].Variants[0].InventoryItemId
+	server.SetInventoryLevel(item, locations[0].ID, 3)
+
+	available := 8
+	operations := &memdb.Operations{
+		InventoryUpdates: []memdb.InventoryUpdate{
+			{InventoryItemID: item, LocationID: locations[0].ID, Adjustment: -2},
+			{InventoryItemID: item, LocationID: locations[1].ID, Available: &available},
+		},
+		InventoryItemUpdates: []memdb.InventoryItemUpdate{
+			{ID: item, Cost: decimal.NewFromInt(6)},
+		},
+	}
+	if err := client.ApplyOperations(context.Background(), operations, nil); err != nil {
+		t.Fatal(err)
+	}
+	got := []int{}
+	for _, l := range server.InventoryLevels() {
+		got = append(got, l.Available)
+	}
+	if want := []int{1, 8}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got available quantities %v, want %v", got, want)
+	}
+	if cost := server.InventoryItems()[0].Cost; cost == nil || !cost.Equal(decimal.NewFromInt(6)) {
+		t.Fatalf("got cost %v, want 6", cost)
+	}
+}
+
+func TestClient_ApplyOperations_inventoryDrift(t *testing.T) {
+	client, server := newTestClient(t, Product{Title: "Hat"})
+	locations := server.AddLocations("Warehouse")
+	item := server.Products()[0].Variants[0].InventoryItemId
+	server.SetInventoryLevel(item, locations[0].ID, 2)
+
+	before := 3
+	available := 8
+	operations := &memdb.Operations{
+		InventoryUpdates: []memdb.InventoryUpdate{
+			{InventoryItemID: item, LocationID: locations[0].ID, Before: &before, Available: &available},
+		},
+	}
+	err := client.ApplyOperations(context.Background(), operations, nil)
+	if !errors.Is(err, ErrDrift) {
+		t.Fatalf("got error %v, want %v", err, ErrDrift)
+	}
+	if got := server.InventoryLevels()[0].Available; got != 2 {
+		t.Fatalf("got available quantity %v, want 2", got)
+	}
+}
+
+func TestClient_CheckInventory(t *testing.T) {
+	client, server := newTestClient(t, Product{Title: "Hat"}, Product{Title: "Cap"})
+	locations := server.AddLocations("Warehouse")
+	hatItem := server.Products()[0].Variants[0].InventoryItemId
+	capItem := server.Products()[1].Variants[0].InventoryItemId
+	server.SetInventoryLevel(hatItem, locations[0].ID, 3)
+	server.SetInventoryLevel(capItem, locations[0].ID, 2)
+	server.SetInventoryItemCost(hatItem, decimal.NewFromInt(5))
+
+	three := 3
+	eight := 8
+	five := decimal.NewFromInt(5)
+	upToDate := &memdb.Operations{
+		InventoryUpdates: []memdb.InventoryUpdate{
+			{InventoryItemID: hatItem, LocationID: locations[0].ID, Before: &three, Available: &eight},
+			// Adjustments are not checked.
+			{InventoryItemID: capItem, LocationID: locations[0].ID, Before: &three, Adjustment: 1},
+		},
+		InventoryItemUpdates: []memdb.InventoryItemUpdate{
+			{ID: hatItem, Before: &five, Cost: decimal.NewFromInt(6)},
+		},
+	}
+	if err := client.CheckInventory(upToDate); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, operations := range map[string]*memdb.Operations{
+		"quantity": {InventoryUpdates: []memdb.InventoryUpdate{
+			{InventoryItemID: capItem, LocationID: locations[0].ID, Before: &three, Available: &eight},
+		}},
+		"cost": {InventoryItemUpdates: []memdb.InventoryItemUpdate{
+			{ID: capItem, Before: &five, Cost: decimal.NewFromInt(6)},
+		}},
+	} {
+		if err := client.CheckInventory(operations); !errors.Is(err, ErrDrift) {
+			t.Errorf("got error %v for changed %v, want %v", err, name, ErrDrift)
+		}
+	}
+}
+
+func TestClient_ApplyOperations_variantResets(t *testing.T) {
+	compareAt := decimal.NewFromInt(20)
+	client, server := newTestClient(t, Product{Title: "Hat", Variants: []Variant{{CompareAtPrice: &compareAt}}})
+	v := server.Products()[0].Variants[0]
+
+	operations := &memdb.Operations{
+		VariantResets: []memdb.VariantReset{{
+			ID:        v.ID,
+			ProductID: v.ProductID,
+			Fields:    map[string]any{"compare_at_price": nil, "taxable": false},
+		}},
+	}
+	if err := client.ApplyOperations(context.Background(), operations, nil); err != nil {
+		t.Fatal(err)
+	}
+	got := server.Products()[0].Variants[0]
+	if got.CompareAtPrice != nil || got.Taxable || !got.RequireShipping {
+		t.Fatalf("got variant %+v", got)
+	}
+}
+
+func TestClient_ApplyOperations_productResets(t *testing.T) {
+	client, server := newTestClient(t, Product{Title: "Hat", BodyHTML: "<p>Hat</p>", Vendor: "Acme"})
+	p := server.Products()[0]
+
+	operations := &memdb.Operations{
+		ProductResets: []memdb.ProductReset{{ID: p.ID, Fields: map[string]any{"body_html": ""}}},
+	}
+	if err := client.ApplyOperations(context.Background(), operations, nil); err != nil {
+		t.Fatal(err)
+	}
+	got := server.Products()[0]
+	if got.BodyHTML != "" || got.Vendor != "Acme" {
+		t.Fatalf("got product %+v", got)
+	}
+}